@@ -0,0 +1,168 @@
+package artifacts
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+
+	"bbox/pkg/flags"
+	"bbox/pkg/types"
+	"bbox/teamcity"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var artifactsCmdName = "artifacts"
+
+// buildSelector addresses the build whose artifacts are listed or downloaded, by ID or as the latest matching build of a build type.
+type buildSelector struct {
+	BuildID     int
+	BuildTypeID string
+	// Branch is the branch of the build, builds of all branches are selected if empty
+	Branch string
+	// LastSuccessful only selects successful builds, which is the default unless LastFinished is set
+	LastSuccessful bool
+	// LastFinished also selects failed builds
+	LastFinished bool
+	Tag          string
+	Pinned       bool
+}
+
+var (
+	selector buildSelector
+	// artifactFlags holds the artifact filter flags of all subcommands and the download flags of the download command
+	artifactFlags = &flags.ArtifactFlags{}
+)
+
+var Cmd = &cobra.Command{
+	Use:   artifactsCmdName,
+	Short: "List and download the artifacts of existing TeamCity builds",
+	Run: func(cmd *cobra.Command, args []string) {
+	},
+}
+
+func init() {
+	Cmd.PersistentFlags().IntVar(&selector.BuildID, "build-id", 0, "The ID of the build")
+	Cmd.PersistentFlags().StringVarP(&selector.BuildTypeID, "build-type-id", "i", "", "Select the latest finished build of this Build Type")
+	Cmd.PersistentFlags().StringVarP(&selector.Branch, "branch", "b", "", "Select a build of this branch, builds of all branches are selected if empty")
+	Cmd.PersistentFlags().BoolVar(&selector.LastSuccessful, "last-successful", false, "Select the last successful build, the default")
+	Cmd.PersistentFlags().BoolVar(&selector.LastFinished, "last-finished", false, "Select the last finished build, whatever its status")
+	Cmd.PersistentFlags().StringVar(&selector.Tag, "tag", "", "Select a build with this tag")
+	Cmd.PersistentFlags().BoolVar(&selector.Pinned, "pinned", false, "Select a pinned build")
+	artifactFlags.AddFilterFlags(Cmd.PersistentFlags())
+	// the other selection flags only apply to the latest build of a build type
+	for _, name := range []string{"build-type-id", "branch", "last-successful", "last-finished", "tag", "pinned"} {
+		Cmd.MarkFlagsMutuallyExclusive("build-id", name)
+	}
+	Cmd.MarkFlagsMutuallyExclusive("last-successful", "last-finished")
+}
+
+// newClient creates the TeamCity client from the global flags, exiting with code 2 on errors.
+func newClient(cmd *cobra.Command) *teamcity.Client {
+	teamcityUsername, _ := cmd.Root().PersistentFlags().GetString("teamcity-username")
+	teamcityPassword, _ := cmd.Root().PersistentFlags().GetString("teamcity-password")
+	teamcityURL, _ := cmd.Root().PersistentFlags().GetString("teamcity-url")
+
+	url, err := url.Parse(teamcityURL)
+	if err != nil {
+		log.Errorf("error parsing TeamCity URL: %s", err)
+		os.Exit(2)
+	}
+
+	client, err := teamcity.NewTeamCityClient(url, teamcityUsername, teamcityPassword)
+	if err != nil {
+		log.Errorf("error initializing TeamCity Client: %s", err)
+		os.Exit(2)
+	}
+
+	return client
+}
+
+// mustResolveBuild resolves the selected build, exiting with code 2 on errors.
+func mustResolveBuild(client *teamcity.Client) types.Build {
+	build, err := resolveBuild(client, selector)
+	if err != nil {
+		log.Errorf("error finding the build: %s", err)
+		os.Exit(2)
+	}
+
+	log.WithFields(log.Fields{
+		"buildID": build.ID,
+		"status":  build.Status,
+		"webURL":  build.WebURL,
+	}).Infof("found build %s #%s", build.BuildTypeID, build.Number)
+
+	return build
+}
+
+// resolveBuild returns the build addressed by its ID, which must have finished, or the latest finished build of the build type matching the selector.
+func resolveBuild(client *teamcity.Client, selector buildSelector) (types.Build, error) {
+	if selector.BuildID != 0 {
+		status, err := client.Build.GetBuildStatus(selector.BuildID)
+		if err != nil {
+			return types.Build{}, fmt.Errorf("error getting build %d: %w", selector.BuildID, err)
+		}
+
+		// the artifacts of a running build are incomplete
+		if status.State != types.BuildStateFinished {
+			return types.Build{}, fmt.Errorf("build %d is %s, its artifacts are only available once it finished", selector.BuildID, status.State)
+		}
+
+		return types.Build{
+			ID:          status.ID,
+			BuildTypeID: status.BuildTypeID,
+			Status:      status.Status,
+			State:       status.State,
+			BranchName:  status.BranchName,
+			WebURL:      status.WebURL,
+			BuildType:   status.BuildType,
+		}, nil
+	}
+
+	if selector.BuildTypeID == "" {
+		return types.Build{}, errors.New("one of --build-id or --build-type-id is required")
+	}
+
+	locator := types.BuildLocator{
+		BuildTypeID: selector.BuildTypeID,
+		Branch:      selector.Branch,
+		State:       string(types.BuildStateFinished),
+		Count:       1,
+	}
+
+	if selector.LastSuccessful || !selector.LastFinished {
+		locator.Status = string(types.BuildStatusSuccess)
+	}
+
+	if selector.Tag != "" {
+		locator.Tags = []string{selector.Tag}
+	}
+
+	if selector.Pinned {
+		locator.Pinned = &selector.Pinned
+	}
+
+	builds, err := client.Build.ListBuilds(locator)
+	if err != nil {
+		return types.Build{}, fmt.Errorf("error listing builds: %w", err)
+	}
+
+	if len(builds) == 0 {
+		return types.Build{}, fmt.Errorf("no finished build of %s matches the selection", selector.BuildTypeID)
+	}
+
+	return builds[0], nil
+}
+
+// artifactFilter parses the include and exclude flags, exiting with code 1 on errors.
+func artifactFilter() teamcity.ArtifactFilter {
+	filter, err := artifactFlags.Filter()
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+
+	return filter
+}
@@ -0,0 +1,101 @@
+package artifacts
+
+import (
+	"testing"
+
+	"bbox/pkg/types"
+	"bbox/pkg/utils/testutils"
+	"bbox/teamcity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveBuildByID(t *testing.T) {
+	mockBuildService := new(testutils.MockBuildService)
+	client := &teamcity.Client{Build: mockBuildService}
+
+	mockBuildService.On("GetBuildStatus", 42).Return(types.BuildStatusResponse{ID: 42, BuildTypeID: "Backend_Build", Status: types.BuildStatusSuccess, State: types.BuildStateFinished}, nil)
+
+	build, err := resolveBuild(client, buildSelector{BuildID: 42})
+	require.NoError(t, err)
+
+	assert.Equal(t, 42, build.ID)
+	assert.Equal(t, "Backend_Build", build.BuildTypeID)
+	mockBuildService.AssertNotCalled(t, "ListBuilds")
+}
+
+func TestResolveBuildByIDNotFinished(t *testing.T) {
+	mockBuildService := new(testutils.MockBuildService)
+	client := &teamcity.Client{Build: mockBuildService}
+
+	mockBuildService.On("GetBuildStatus", 42).Return(types.BuildStatusResponse{ID: 42, BuildTypeID: "Backend_Build", State: types.BuildStateRunning}, nil)
+
+	_, err := resolveBuild(client, buildSelector{BuildID: 42})
+	assert.ErrorContains(t, err, "build 42 is running, its artifacts are only available once it finished")
+}
+
+func TestResolveBuildLatest(t *testing.T) {
+	pinned := true
+
+	testCases := []struct {
+		name            string
+		selector        buildSelector
+		expectedLocator types.BuildLocator
+	}{
+		{
+			name:     "last successful",
+			selector: buildSelector{BuildTypeID: "Backend_Build", Branch: "main"},
+			expectedLocator: types.BuildLocator{
+				BuildTypeID: "Backend_Build", Branch: "main", State: "finished", Status: "SUCCESS", Count: 1,
+			},
+		},
+		{
+			name:     "explicitly last successful",
+			selector: buildSelector{BuildTypeID: "Backend_Build", LastSuccessful: true},
+			expectedLocator: types.BuildLocator{
+				BuildTypeID: "Backend_Build", State: "finished", Status: "SUCCESS", Count: 1,
+			},
+		},
+		{
+			name:     "last finished with a tag",
+			selector: buildSelector{BuildTypeID: "Backend_Build", LastFinished: true, Tag: "release"},
+			expectedLocator: types.BuildLocator{
+				BuildTypeID: "Backend_Build", State: "finished", Tags: []string{"release"}, Count: 1,
+			},
+		},
+		{
+			name:     "pinned",
+			selector: buildSelector{BuildTypeID: "Backend_Build", Pinned: true},
+			expectedLocator: types.BuildLocator{
+				BuildTypeID: "Backend_Build", State: "finished", Status: "SUCCESS", Pinned: &pinned, Count: 1,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockBuildService := new(testutils.MockBuildService)
+			client := &teamcity.Client{Build: mockBuildService}
+
+			mockBuildService.On("ListBuilds", tc.expectedLocator).Return([]types.Build{{ID: 7, BuildTypeID: "Backend_Build"}}, nil)
+
+			build, err := resolveBuild(client, tc.selector)
+			require.NoError(t, err)
+			assert.Equal(t, 7, build.ID)
+		})
+	}
+}
+
+func TestResolveBuildNotFound(t *testing.T) {
+	mockBuildService := new(testutils.MockBuildService)
+	client := &teamcity.Client{Build: mockBuildService}
+
+	mockBuildService.On("ListBuilds", types.BuildLocator{BuildTypeID: "Backend_Build", State: "finished", Status: "SUCCESS", Count: 1}).Return([]types.Build{}, nil)
+
+	_, err := resolveBuild(client, buildSelector{BuildTypeID: "Backend_Build"})
+	assert.ErrorContains(t, err, "no finished build of Backend_Build matches the selection")
+
+	_, err = resolveBuild(client, buildSelector{})
+	assert.ErrorContains(t, err, "one of --build-id or --build-type-id is required")
+}
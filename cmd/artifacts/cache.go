@@ -0,0 +1,97 @@
+package artifacts
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"bbox/pkg/cache"
+	"bbox/pkg/utils"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cacheCmdName = "cache"
+	cacheOptions = cache.ArtifactCacheOptions{Enabled: true, MaxSize: "10GiB"}
+	pruneAll     bool
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   cacheCmdName,
+	Short: "Inspect and prune the local artifact cache",
+	Run: func(cmd *cobra.Command, args []string) {
+	},
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show the number of builds and files in the artifact cache, and its size",
+	Run: func(cmd *cobra.Command, args []string) {
+		artifactCache := mustOpenCache()
+
+		stats, err := artifactCache.Stats()
+		if err != nil {
+			log.Errorf("error reading the artifact cache: %s", err)
+			os.Exit(2)
+		}
+
+		writeCacheStats(os.Stdout, stats)
+	},
+}
+
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Evict the least recently used artifacts until the cache fits its size",
+	Example: `  # shrink the cache to 2 GiB
+  bbox artifacts cache prune --artifacts-cache-size 2GiB`,
+	Run: func(cmd *cobra.Command, args []string) {
+		artifactCache := mustOpenCache()
+
+		maxSize := artifactCache.MaxSize()
+		if pruneAll {
+			maxSize = 0
+		}
+
+		result, err := artifactCache.Prune(maxSize)
+		if err != nil {
+			log.Errorf("error pruning the artifact cache: %s", err)
+			os.Exit(2)
+		}
+
+		log.Infof("evicted %d files from the artifact cache, freeing %s", result.Removed, utils.FormatBytes(result.Freed))
+	},
+}
+
+func init() {
+	cacheCmd.PersistentFlags().StringVar(&cacheOptions.Dir, "artifacts-cache-dir", "", "Directory of the artifact cache, bbox/artifacts in the user cache directory if empty")
+	cacheCmd.PersistentFlags().StringVar(&cacheOptions.MaxSize, "artifacts-cache-size", cacheOptions.MaxSize, "Size the artifact cache is pruned to, least recently used artifacts first, e.g. 10GiB or 500MB")
+	cachePruneCmd.Flags().BoolVar(&pruneAll, "all", false, "Evict all artifacts")
+	cacheCmd.AddCommand(cacheStatsCmd)
+	cacheCmd.AddCommand(cachePruneCmd)
+	Cmd.AddCommand(cacheCmd)
+}
+
+// mustOpenCache opens the artifact cache, exiting with code 1 on errors.
+func mustOpenCache() *cache.ArtifactCache {
+	artifactCache, err := cacheOptions.Open()
+	if err != nil {
+		log.Errorf("error opening the artifact cache: %s", err)
+		os.Exit(1)
+	}
+
+	return artifactCache
+}
+
+func writeCacheStats(w io.Writer, stats cache.ArtifactCacheStats) {
+	usage := int64(0)
+	if stats.MaxSize > 0 {
+		usage = stats.Size * 100 / stats.MaxSize
+	}
+
+	fmt.Fprintf(w, "Directory: %s\n", stats.Dir)
+	fmt.Fprintf(w, "Builds:    %d\n", stats.Builds)
+	fmt.Fprintf(w, "Files:     %d (%d distinct)\n", stats.Files, stats.Objects)
+	fmt.Fprintf(w, "Size:      %s of %s (%d%%)\n", utils.FormatBytes(stats.Size), utils.FormatBytes(stats.MaxSize), usage)
+}
@@ -0,0 +1,57 @@
+package artifacts
+
+import (
+	"os"
+
+	"bbox/teamcity"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	downloadCmdName = "download"
+	artifactsPath   = "./"
+)
+
+var downloadCmd = &cobra.Command{
+	Use:   downloadCmdName,
+	Short: "Download the artifacts of a build",
+	Example: `  # download the jars of the last pinned build of main
+  bbox artifacts download --build-type-id Backend_Build --branch main --pinned --artifact-include 'dist/**/*.jar => lib/'`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client := newClient(cmd)
+
+		options, err := artifactFlags.Options()
+		if err != nil {
+			log.Error(err)
+			os.Exit(1)
+		}
+
+		// nothing to download is an error when downloading artifacts is the whole point of the command
+		options.RequireMatch = true
+
+		build := mustResolveBuild(client)
+
+		if !client.Artifacts.BuildHasArtifact(build.ID) {
+			log.Errorf("build %d has no artifacts", build.ID)
+			os.Exit(2)
+		}
+
+		log.Infof("downloading artifacts of build %d to %s", build.ID, artifactsPath)
+
+		err = teamcity.DownloadBuildArtifacts(client, build.ID, build.BuildTypeID, artifactsPath, options)
+		if err != nil {
+			log.Errorf("error downloading artifacts of build %d: %s", build.ID, err)
+			os.Exit(2)
+		}
+
+		log.Infof("downloaded artifacts of build %d to %s", build.ID, artifactsPath)
+	},
+}
+
+func init() {
+	downloadCmd.Flags().StringVar(&artifactsPath, "artifacts-path", artifactsPath, "Path to download Artifacts to")
+	artifactFlags.AddDownloadFlags(downloadCmd.Flags())
+	Cmd.AddCommand(downloadCmd)
+}
@@ -0,0 +1,163 @@
+package artifacts
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"bbox/pkg/types"
+	"bbox/pkg/utils"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	outputTree = "tree"
+	outputJSON = "json"
+)
+
+var (
+	listCmdName = "list"
+	listOutput  = outputTree
+)
+
+var listCmd = &cobra.Command{
+	Use:   listCmdName,
+	Short: "List the artifacts of a build as a tree with their sizes",
+	Example: `  # the artifacts of the last successful build of main
+  bbox artifacts list --build-type-id Backend_Build --branch main`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client := newClient(cmd)
+		filter := artifactFilter()
+		build := mustResolveBuild(client)
+
+		files, err := client.Artifacts.ListArtifacts(build.ID)
+		if err != nil {
+			log.Errorf("error listing artifacts of build %d: %s", build.ID, err)
+			os.Exit(2)
+		}
+
+		selected := make([]types.ArtifactFile, 0, len(files))
+		for _, file := range files {
+			if _, ok := filter.Destination(file.FullName); ok {
+				selected = append(selected, file)
+			}
+		}
+
+		err = renderArtifacts(os.Stdout, selected, listOutput)
+		if err != nil {
+			log.Errorf("error rendering artifacts: %s", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	listCmd.Flags().StringVarP(&listOutput, "output", "o", listOutput, "Output format: tree or json")
+	Cmd.AddCommand(listCmd)
+}
+
+// listedArtifact is an artifact file in the json output of the list command.
+type listedArtifact struct {
+	Path             string `json:"path"`
+	Size             int64  `json:"size"`
+	ModificationTime string `json:"modificationTime,omitempty"`
+}
+
+func renderArtifacts(w io.Writer, files []types.ArtifactFile, format string) error {
+	switch format {
+	case outputTree:
+		return artifactsTree(w, files)
+	case outputJSON:
+		listed := make([]listedArtifact, 0, len(files))
+		for _, file := range files {
+			listed = append(listed, listedArtifact{Path: file.FullName, Size: file.Size, ModificationTime: file.ModificationTime})
+		}
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(listed)
+	default:
+		return fmt.Errorf("unknown output format %q, expected one of: %s, %s", format, outputTree, outputJSON)
+	}
+}
+
+// artifactNode is a file or directory of the artifacts tree, directories are sized by the files they contain.
+type artifactNode struct {
+	name     string
+	size     int64
+	children map[string]*artifactNode
+}
+
+func (n *artifactNode) isDir() bool {
+	return n.children != nil
+}
+
+// artifactsTree writes the files as a tree of directories, sorted by name with the total size of every directory.
+func artifactsTree(w io.Writer, files []types.ArtifactFile) error {
+	root := &artifactNode{children: map[string]*artifactNode{}}
+
+	for _, file := range files {
+		node := root
+		node.size += file.Size
+
+		segments := strings.Split(file.FullName, "/")
+		for i, segment := range segments {
+			child, ok := node.children[segment]
+			if !ok {
+				child = &artifactNode{name: segment}
+				if i < len(segments)-1 {
+					child.children = map[string]*artifactNode{}
+				}
+
+				node.children[segment] = child
+			}
+
+			child.size += file.Size
+			node = child
+		}
+	}
+
+	var b strings.Builder
+	writeArtifactNodes(&b, root, "")
+	fmt.Fprintf(&b, "\n%d files, %s\n", len(files), utils.FormatBytes(root.size))
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+func writeArtifactNodes(b *strings.Builder, node *artifactNode, prefix string) {
+	names := make([]string, 0, len(node.children))
+	for name := range node.children {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for i, name := range names {
+		child := node.children[name]
+		last := i == len(names)-1
+
+		branch, indent := "├── ", "│   "
+		if last {
+			branch, indent = "└── ", "    "
+		}
+
+		label := child.name
+		if child.isDir() {
+			label += "/"
+		}
+
+		fmt.Fprintf(b, "%s%s%s (%s)\n", prefix, branch, label, utils.FormatBytes(child.size))
+
+		if child.isDir() {
+			writeArtifactNodes(b, child, prefix+indent)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package artifacts
+
+import (
+	"bytes"
+	"testing"
+
+	"bbox/pkg/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderArtifactsTree(t *testing.T) {
+	t.Parallel()
+
+	files := []types.ArtifactFile{
+		{FullName: "report.txt", Size: 12},
+		{FullName: "dist/lib/core.jar", Size: 512 << 10},
+		{FullName: "dist/app.jar", Size: 1 << 20},
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, renderArtifacts(&out, files, outputTree))
+
+	assert.Equal(t, `├── dist/ (1.5 MiB)
+│   ├── app.jar (1.0 MiB)
+│   └── lib/ (512.0 KiB)
+│       └── core.jar (512.0 KiB)
+└── report.txt (12 B)
+
+3 files, 1.5 MiB
+`, out.String())
+}
+
+func TestRenderArtifactsJSON(t *testing.T) {
+	t.Parallel()
+
+	files := []types.ArtifactFile{{FullName: "dist/app.jar", Size: 3, ModificationTime: "20240501T083000+0000"}}
+
+	var out bytes.Buffer
+	require.NoError(t, renderArtifacts(&out, files, outputJSON))
+
+	assert.JSONEq(t, `[{"path": "dist/app.jar", "size": 3, "modificationTime": "20240501T083000+0000"}]`, out.String())
+}
+
+func TestRenderArtifactsUnknownFormat(t *testing.T) {
+	t.Parallel()
+
+	err := renderArtifacts(&bytes.Buffer{}, nil, "xml")
+	assert.ErrorContains(t, err, `unknown output format "xml"`)
+}
@@ -0,0 +1,14 @@
+package builds
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var buildsCmdName = "builds"
+
+var Cmd = &cobra.Command{
+	Use:   buildsCmdName,
+	Short: "Search and inspect TeamCity builds",
+	Run: func(cmd *cobra.Command, args []string) {
+	},
+}
@@ -0,0 +1,234 @@
+package builds
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"bbox/pkg/types"
+	"bbox/teamcity"
+
+	"github.com/olekukonko/tablewriter"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputCSV   = "csv"
+)
+
+var (
+	listCmdName = "list"
+	locator     = types.BuildLocator{Count: 100}
+	since       string
+	until       string
+	running     bool
+	canceled    bool
+	output      = outputTable
+)
+
+var listCmd = &cobra.Command{
+	Use:   listCmdName,
+	Short: "List TeamCity builds matching the given filters",
+	Example: `  # what ran on release/x in the last 24 hours
+  bbox builds list --branch release/x --since 24h`,
+	Run: func(cmd *cobra.Command, args []string) {
+		teamcityUsername, _ := cmd.Root().PersistentFlags().GetString("teamcity-username")
+		teamcityPassword, _ := cmd.Root().PersistentFlags().GetString("teamcity-password")
+		teamcityURL, _ := cmd.Root().PersistentFlags().GetString("teamcity-url")
+
+		url, err := url.Parse(teamcityURL)
+		if err != nil {
+			log.Errorf("error parsing TeamCity URL: %s", err)
+			os.Exit(2)
+		}
+
+		client, err := teamcity.NewTeamCityClient(url, teamcityUsername, teamcityPassword)
+		if err != nil {
+			log.Errorf("error initializing TeamCity Client: %s", err)
+			os.Exit(2)
+		}
+
+		now := time.Now()
+
+		locator.SinceDate, err = parseTime(since, now)
+		if err != nil {
+			log.Errorf("invalid --since: %s", err)
+			os.Exit(1)
+		}
+
+		locator.UntilDate, err = parseTime(until, now)
+		if err != nil {
+			log.Errorf("invalid --until: %s", err)
+			os.Exit(1)
+		}
+
+		if cmd.Flags().Changed("running") {
+			locator.Running = &running
+		}
+
+		if cmd.Flags().Changed("canceled") {
+			locator.Canceled = &canceled
+		}
+
+		log.WithField("locator", fmt.Sprintf("%+v", locator)).Debug("listing builds")
+
+		builds, err := client.Build.ListBuilds(locator)
+		if err != nil {
+			log.Errorf("error listing builds: %s", err)
+			os.Exit(2)
+		}
+
+		err = renderBuilds(os.Stdout, builds, output)
+		if err != nil {
+			log.Errorf("error rendering builds: %s", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	listCmd.Flags().StringVarP(&locator.BuildTypeID, "build-type-id", "i", "", "Only list builds of this Build Type")
+	listCmd.Flags().StringVar(&locator.ProjectID, "project", "", "Only list builds of this project and its sub-projects")
+	listCmd.Flags().StringVarP(&locator.Branch, "branch", "b", "", "Only list builds of this branch, all branches are listed if empty")
+	listCmd.Flags().StringVar(&locator.Status, "status", "", "Only list builds with this status (SUCCESS, FAILURE, ERROR, UNKNOWN)")
+	listCmd.Flags().StringVar(&locator.State, "state", "", "Only list builds in this state (queued, running, finished, any)")
+	listCmd.Flags().StringVar(&locator.User, "user", "", "Only list builds triggered by this username")
+	listCmd.Flags().StringSliceVar(&locator.Tags, "tag", nil, "Only list builds with this tag. Repeatable")
+	listCmd.Flags().StringVar(&since, "since", "", "Only list builds started after this date, as a duration ago (e.g. 24h) or a date (2006-01-02 or RFC3339)")
+	listCmd.Flags().StringVar(&until, "until", "", "Only list builds started before this date, as a duration ago (e.g. 1h) or a date (2006-01-02 or RFC3339)")
+	listCmd.Flags().BoolVar(&running, "running", false, "Only list running builds, or only builds that are not running if set to false")
+	listCmd.Flags().BoolVar(&canceled, "canceled", false, "Only list canceled builds, or only builds that were not canceled if set to false")
+	listCmd.Flags().IntVarP(&locator.Count, "count", "n", locator.Count, "Maximum number of builds to list, 0 lists all matching builds")
+	listCmd.Flags().StringVarP(&output, "output", "o", output, "Output format: table, json or csv")
+	Cmd.AddCommand(listCmd)
+}
+
+// parseTime parses a duration ago from now, a date or an RFC3339 timestamp. An empty value is the zero time.
+func parseTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if duration, err := time.ParseDuration(value); err == nil {
+		return now.Add(-duration), nil
+	}
+
+	if date, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return date, nil
+	}
+
+	timestamp, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a duration, a date or an RFC3339 timestamp", value)
+	}
+
+	return timestamp, nil
+}
+
+func renderBuilds(w io.Writer, builds []types.Build, format string) error {
+	switch format {
+	case outputTable:
+		buildsTable(w, builds)
+		return nil
+	case outputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(builds)
+	case outputCSV:
+		writer := csv.NewWriter(w)
+
+		err := writer.Write(buildsHeader)
+		if err != nil {
+			return err
+		}
+
+		for _, build := range builds {
+			err = writer.Write(buildRow(build))
+			if err != nil {
+				return err
+			}
+		}
+
+		writer.Flush()
+
+		return writer.Error()
+	default:
+		return fmt.Errorf("unknown output format %q, expected one of: %s, %s, %s", format, outputTable, outputJSON, outputCSV)
+	}
+}
+
+var buildsHeader = []string{"ID", "Build Type", "Number", "Branch", "Status", "State", "Triggered By", "Start Date", "Finish Date", "Web URL"}
+
+func buildRow(build types.Build) []string {
+	triggeredBy := build.Triggered.User.Username
+	if triggeredBy == "" {
+		triggeredBy = build.Triggered.Type
+	}
+
+	return []string{
+		strconv.Itoa(build.ID),
+		build.BuildType.FullName(),
+		build.Number,
+		build.BranchName,
+		string(build.Result()),
+		string(build.State),
+		triggeredBy,
+		formatTeamCityTime(build.StartDate),
+		formatTeamCityTime(build.FinishDate),
+		build.WebURL,
+	}
+}
+
+func buildsTable(w io.Writer, builds []types.Build) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(buildsHeader)
+	table.SetBorders(tablewriter.Border{Left: false, Top: true, Right: false, Bottom: true})
+	table.SetAutoWrapText(false)
+
+	headerColors := make([]tablewriter.Colors, len(buildsHeader))
+	for i := range headerColors {
+		headerColors[i] = tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiCyanColor}
+	}
+	table.SetHeaderColor(headerColors...)
+
+	for _, build := range builds {
+		statusColor := tablewriter.FgHiRedColor
+		switch result := build.Result(); {
+		case result.IsSuccessful():
+			statusColor = tablewriter.FgHiGreenColor
+		case result.WasCanceled():
+			statusColor = tablewriter.FgHiYellowColor
+		}
+
+		rowColors := make([]tablewriter.Colors, len(buildsHeader))
+		rowColors[4] = tablewriter.Colors{tablewriter.Bold, statusColor}
+
+		table.Rich(buildRow(build), rowColors)
+	}
+
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.Render()
+}
+
+// formatTeamCityTime formats a TeamCity date in the local time zone, keeping it as is if it can not be parsed.
+func formatTeamCityTime(value string) string {
+	if value == "" {
+		return ""
+	}
+
+	t, err := time.Parse(types.TeamCityTimeLayout, value)
+	if err != nil {
+		return value
+	}
+
+	return t.Local().Format("2006-01-02 15:04:05")
+}
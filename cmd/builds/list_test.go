@@ -0,0 +1,73 @@
+package builds
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"bbox/pkg/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTime(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name          string
+		value         string
+		expected      time.Time
+		expectedError bool
+	}{
+		{name: "empty", value: "", expected: time.Time{}},
+		{name: "duration", value: "24h", expected: now.Add(-24 * time.Hour)},
+		{name: "date", value: "2024-05-01", expected: time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)},
+		{name: "rfc3339", value: "2024-05-01T08:30:00Z", expected: time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC)},
+		{name: "invalid", value: "yesterday", expectedError: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			parsed, err := parseTime(tc.value, now)
+
+			if tc.expectedError {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.True(t, tc.expected.Equal(parsed), "expected %s, got %s", tc.expected, parsed)
+		})
+	}
+}
+
+func TestRenderBuildsCSV(t *testing.T) {
+	t.Parallel()
+
+	builds := []types.Build{
+		{
+			ID:         42,
+			Number:     "1.0.42",
+			Status:     "SUCCESS",
+			State:      "finished",
+			BranchName: "release/x",
+			WebURL:     "https://teamcity/build/42",
+			BuildType:  types.BuildType{Name: "Build", ProjectName: "Backend"},
+		},
+	}
+	builds[0].Triggered.Type = "vcs"
+
+	var b bytes.Buffer
+	require.NoError(t, renderBuilds(&b, builds, outputCSV))
+
+	assert.Equal(t, "ID,Build Type,Number,Branch,Status,State,Triggered By,Start Date,Finish Date,Web URL\n"+
+		"42,Backend / Build,1.0.42,release/x,SUCCESS,finished,vcs,,,https://teamcity/build/42\n", b.String())
+
+	assert.Error(t, renderBuilds(&b, builds, "xml"))
+}
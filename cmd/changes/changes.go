@@ -0,0 +1,65 @@
+package changes
+
+import (
+	"net/url"
+	"os"
+
+	"bbox/teamcity"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	changesCmdName  = "changes"
+	buildID         int
+	sinceBuildID    int
+	output          = outputMarkdown
+	groupByVcsRoots bool
+)
+
+var Cmd = &cobra.Command{
+	Use:   changesCmdName,
+	Short: "List the VCS changes included in a TeamCity Build",
+	Long: `List the VCS changes (commits) included in a TeamCity Build, e.g. for release notes.
+With --since-build, the changes of all builds of the same Build Type and branch after that build are listed too, i.e. everything that changed between the two builds.`,
+	Example: `  # release notes between the previous and the current release build
+  bbox changes --build-id 12345 --since-build 12000 --group-by-vcs-root > RELEASE_NOTES.md`,
+	Run: func(cmd *cobra.Command, args []string) {
+		teamcityUsername, _ := cmd.Root().PersistentFlags().GetString("teamcity-username")
+		teamcityPassword, _ := cmd.Root().PersistentFlags().GetString("teamcity-password")
+		teamcityURL, _ := cmd.Root().PersistentFlags().GetString("teamcity-url")
+
+		url, err := url.Parse(teamcityURL)
+		if err != nil {
+			log.Errorf("error parsing TeamCity URL: %s", err)
+			os.Exit(2)
+		}
+
+		client, err := teamcity.NewTeamCityClient(url, teamcityUsername, teamcityPassword)
+		if err != nil {
+			log.Errorf("error initializing TeamCity Client: %s", err)
+			os.Exit(2)
+		}
+
+		changes, err := teamcity.BuildChanges(client, buildID, sinceBuildID)
+		if err != nil {
+			log.Errorf("error getting changes: %s", err)
+			os.Exit(2)
+		}
+
+		err = renderChanges(os.Stdout, changes, output, groupByVcsRoots)
+		if err != nil {
+			log.Errorf("error rendering changes: %s", err)
+			os.Exit(2)
+		}
+	},
+}
+
+func init() {
+	Cmd.Flags().IntVar(&buildID, "build-id", 0, "The ID of the build whose changes to list")
+	Cmd.Flags().IntVar(&sinceBuildID, "since-build", 0, "Also list the changes of the builds of the same Build Type and branch after this build ID")
+	Cmd.Flags().StringVarP(&output, "output", "o", output, "Output format: markdown or json")
+	Cmd.Flags().BoolVar(&groupByVcsRoots, "group-by-vcs-root", false, "Group the changes by the VCS root they were detected in")
+	_ = Cmd.MarkFlagRequired("build-id")
+}
@@ -0,0 +1,138 @@
+package changes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"bbox/pkg/types"
+)
+
+const (
+	outputMarkdown = "markdown"
+	outputJSON     = "json"
+
+	// shortVersionLength is the length commit SHAs are shortened to, like `git log --oneline`
+	shortVersionLength = 7
+)
+
+// vcsRootChanges are the changes detected in one VCS root.
+type vcsRootChanges struct {
+	VcsRoot string         `json:"vcsRoot"`
+	Changes []types.Change `json:"changes"`
+}
+
+// groupByVcsRoot groups the changes by the VCS root they were detected in, in the order the VCS roots first appear.
+func groupByVcsRoot(changes []types.Change) []vcsRootChanges {
+	groups := []vcsRootChanges{}
+	index := map[string]int{}
+
+	for _, change := range changes {
+		name := change.VcsRootInstance.Name
+		if name == "" {
+			name = change.VcsRootInstance.VcsRootID
+		}
+
+		i, ok := index[name]
+		if !ok {
+			i = len(groups)
+			index[name] = i
+			groups = append(groups, vcsRootChanges{VcsRoot: name})
+		}
+
+		groups[i].Changes = append(groups[i].Changes, change)
+	}
+
+	return groups
+}
+
+func renderChanges(w io.Writer, changes []types.Change, format string, groupByVcsRoots bool) error {
+	switch format {
+	case outputMarkdown:
+		return changesMarkdown(w, changes, groupByVcsRoots)
+	case outputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		if groupByVcsRoots {
+			return encoder.Encode(groupByVcsRoot(changes))
+		}
+
+		return encoder.Encode(changes)
+	default:
+		return fmt.Errorf("unknown output format %q, expected one of: %s, %s", format, outputMarkdown, outputJSON)
+	}
+}
+
+// changesMarkdown renders the changes as a Markdown list for release notes, under a heading per VCS root if groupByVcsRoots is set.
+func changesMarkdown(w io.Writer, changes []types.Change, groupByVcsRoots bool) error {
+	var b strings.Builder
+
+	if len(changes) == 0 {
+		b.WriteString("No changes.\n")
+	} else if groupByVcsRoots {
+		for i, group := range groupByVcsRoot(changes) {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+
+			fmt.Fprintf(&b, "## %s\n\n", group.VcsRoot)
+			writeChangesList(&b, group.Changes)
+		}
+	} else {
+		writeChangesList(&b, changes)
+	}
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+func writeChangesList(b *strings.Builder, changes []types.Change) {
+	for _, change := range changes {
+		version := "`" + shortVersion(change.Version) + "`"
+		if change.WebURL != "" {
+			version = "[" + version + "](" + change.WebURL + ")"
+		}
+
+		subject, body, _ := strings.Cut(strings.TrimSpace(change.Comment), "\n")
+
+		fmt.Fprintf(b, "- %s %s (%s, %s)\n", version, strings.TrimSpace(subject), change.Username, formatChangeDate(change.Date))
+
+		for _, line := range strings.Split(strings.TrimSpace(body), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				fmt.Fprintf(b, "  %s\n", line)
+			}
+		}
+
+		for _, file := range change.Files.File {
+			path := file.RelativeFile
+			if path == "" {
+				path = file.File
+			}
+
+			fmt.Fprintf(b, "  - %s `%s`\n", file.ChangeType, path)
+		}
+	}
+}
+
+// shortVersion shortens commit SHAs, keeping short revisions, e.g. of Perforce or Subversion, as they are.
+func shortVersion(version string) string {
+	if len(version) < 2*shortVersionLength {
+		return version
+	}
+
+	return version[:shortVersionLength]
+}
+
+// formatChangeDate formats a TeamCity date keeping its time zone, which is the one of the committer, keeping it as is if it can not be parsed.
+func formatChangeDate(value string) string {
+	t, err := time.Parse(types.TeamCityTimeLayout, value)
+	if err != nil {
+		return value
+	}
+
+	return t.Format("2006-01-02 15:04 -0700")
+}
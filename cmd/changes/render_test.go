@@ -0,0 +1,93 @@
+package changes
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"bbox/pkg/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testChange(id int, version, vcsRoot, comment string, files ...types.ChangeFile) types.Change {
+	change := types.Change{
+		ID:       id,
+		Version:  version,
+		Username: "jdoe",
+		Date:     "20240501T083000+0200",
+		Comment:  comment,
+	}
+	change.VcsRootInstance.Name = vcsRoot
+	change.Files.File = files
+
+	return change
+}
+
+var testChanges = []types.Change{
+	testChange(3, "0a1b2c3d4e5f60718293a4b5c6d7e8f901234567", "backend", "Fix the retry loop\n\nIt never stopped.\n", types.ChangeFile{RelativeFile: "teamcity/build.go", ChangeType: "edited"}),
+	testChange(2, "1234", "docs", "Document the gate command"),
+	testChange(1, "fedcba9876543210fedcba9876543210fedcba98", "backend", "Add the gate command", types.ChangeFile{File: "cmd/gate/gate.go", ChangeType: "added"}),
+}
+
+func TestChangesMarkdown(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	require.NoError(t, renderChanges(&buf, testChanges, outputMarkdown, false))
+
+	assert.Equal(t, "- `0a1b2c3` Fix the retry loop (jdoe, 2024-05-01 08:30 +0200)\n"+
+		"  It never stopped.\n"+
+		"  - edited `teamcity/build.go`\n"+
+		"- `1234` Document the gate command (jdoe, 2024-05-01 08:30 +0200)\n"+
+		"- `fedcba9` Add the gate command (jdoe, 2024-05-01 08:30 +0200)\n"+
+		"  - added `cmd/gate/gate.go`\n", buf.String())
+}
+
+func TestChangesMarkdownGroupedByVcsRoot(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	require.NoError(t, renderChanges(&buf, testChanges, outputMarkdown, true))
+
+	assert.Equal(t, "## backend\n\n"+
+		"- `0a1b2c3` Fix the retry loop (jdoe, 2024-05-01 08:30 +0200)\n"+
+		"  It never stopped.\n"+
+		"  - edited `teamcity/build.go`\n"+
+		"- `fedcba9` Add the gate command (jdoe, 2024-05-01 08:30 +0200)\n"+
+		"  - added `cmd/gate/gate.go`\n"+
+		"\n## docs\n\n"+
+		"- `1234` Document the gate command (jdoe, 2024-05-01 08:30 +0200)\n", buf.String())
+}
+
+func TestChangesMarkdownEmpty(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	require.NoError(t, renderChanges(&buf, nil, outputMarkdown, true))
+
+	assert.Equal(t, "No changes.\n", buf.String())
+}
+
+func TestChangesJSONGroupedByVcsRoot(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	require.NoError(t, renderChanges(&buf, testChanges, outputJSON, true))
+
+	var groups []vcsRootChanges
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &groups))
+
+	require.Len(t, groups, 2)
+	assert.Equal(t, "backend", groups[0].VcsRoot)
+	assert.Len(t, groups[0].Changes, 2)
+	assert.Equal(t, "docs", groups[1].VcsRoot)
+}
+
+func TestRenderChangesUnknownFormat(t *testing.T) {
+	t.Parallel()
+
+	err := renderChanges(&bytes.Buffer{}, testChanges, "html", false)
+	assert.ErrorContains(t, err, `unknown output format "html"`)
+}
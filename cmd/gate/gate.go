@@ -0,0 +1,190 @@
+package gate
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"bbox/pkg/interrupt"
+	"bbox/pkg/report"
+	"bbox/pkg/types"
+	"bbox/teamcity"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	gateCmdName = "gate"
+	revision    string
+	projectID   string
+	waitTimeout = 30 * time.Minute
+)
+
+var Cmd = &cobra.Command{
+	Use:   gateCmdName,
+	Short: "Wait until all TeamCity builds of a commit are green",
+	Long: `Wait until all TeamCity builds of a commit are green.
+Every build of the revision, across all Build Types, is waited for concurrently. The latest build of each Build Type counts, and the command fails if any of them did not succeed.`,
+	Example: `  # gate a merge on the builds of the current commit
+  bbox gate --revision "$(git rev-parse HEAD)" --project Backend`,
+	Run: func(cmd *cobra.Command, args []string) {
+		teamcityUsername, _ := cmd.Root().PersistentFlags().GetString("teamcity-username")
+		teamcityPassword, _ := cmd.Root().PersistentFlags().GetString("teamcity-password")
+		teamcityURL, _ := cmd.Root().PersistentFlags().GetString("teamcity-url")
+
+		url, err := url.Parse(teamcityURL)
+		if err != nil {
+			log.Errorf("error parsing TeamCity URL: %s", err)
+			os.Exit(2)
+		}
+
+		client, err := teamcity.NewTeamCityClient(url, teamcityUsername, teamcityPassword)
+		if err != nil {
+			log.Errorf("error initializing TeamCity Client: %s", err)
+			os.Exit(2)
+		}
+
+		ctx, stop := interrupt.NotifyContext()
+		defer stop()
+
+		results, err := gate(ctx, client, revision, projectID, waitTimeout)
+		if err != nil {
+			log.Errorf("gate failed: %s", err)
+			os.Exit(2)
+		}
+
+		report.ResultsTable(os.Stdout, results)
+
+		if ctx.Err() != nil {
+			log.Warn("interrupted, the builds keep running in TeamCity")
+			os.Exit(interrupt.ExitCode)
+		}
+
+		failed := 0
+		for _, result := range results {
+			if !result.BuildStatus.IsSuccessful() {
+				failed++
+			}
+		}
+
+		if failed > 0 {
+			log.Errorf("%d of %d builds of revision %s did not succeed", failed, len(results), revision)
+			os.Exit(2)
+		}
+
+		log.Infof("all %d builds of revision %s succeeded", len(results), revision)
+	},
+}
+
+func init() {
+	Cmd.Flags().StringVarP(&revision, "revision", "r", "", "The VCS revision (commit SHA) whose builds gate the change")
+	Cmd.Flags().StringVar(&projectID, "project", "", "Only gate on builds of this project and its sub-projects")
+	Cmd.Flags().DurationVarP(&waitTimeout, "wait-timeout", "t", waitTimeout, "Timeout for waiting for all builds to finish")
+	_ = Cmd.MarkFlagRequired("revision")
+}
+
+// gate waits concurrently for the latest build of every Build Type building the revision, and returns their results sorted by name.
+func gate(ctx context.Context, c *teamcity.Client, revision, projectID string, timeout time.Duration) ([]types.BuildResult, error) {
+	builds, err := revisionBuilds(c, revision, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("waiting for %d builds of revision %s", len(builds), revision)
+
+	results := make([]types.BuildResult, len(builds))
+
+	var wg sync.WaitGroup
+
+	for i, build := range builds {
+		results[i] = types.BuildResult{
+			BuildName:   build.BuildType.FullName(),
+			WebURL:      build.WebURL,
+			BranchName:  build.BranchName,
+			BuildStatus: build.Result(),
+			Duration:    build.Duration(),
+		}
+
+		if build.State.IsTerminal() {
+			continue
+		}
+
+		wg.Add(1)
+
+		go func(result *types.BuildResult, build types.Build) {
+			defer wg.Done()
+
+			status, err := c.Build.WaitForBuild(result.BuildName, build.ID, timeout, teamcity.WithContext(ctx))
+			if err != nil {
+				log.Errorf("error waiting for build %s: %s", result.BuildName, err)
+				result.BuildStatus = types.BuildStatusUnknown
+				result.Error = fmt.Errorf("error waiting for build: %w", err)
+
+				return
+			}
+
+			result.BuildStatus = status.Result()
+			result.Duration = status.Duration()
+
+			log.WithField("buildStatus", result.BuildStatus).Infof("build %s finished", result.BuildName)
+		}(&results[i], build)
+	}
+
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].BuildName < results[j].BuildName
+	})
+
+	return results, nil
+}
+
+// revisionBuilds returns the latest build of every Build Type building the revision, in any state.
+func revisionBuilds(c *teamcity.Client, revision, projectID string) ([]types.Build, error) {
+	changesLocator := teamcity.NewLocator().Add("version", revision)
+	if projectID != "" {
+		changesLocator.AddLocator("project", teamcity.IDLocator(projectID))
+	}
+
+	changes, err := c.Change.GetChanges(changesLocator)
+	if err != nil {
+		return nil, fmt.Errorf("error getting changes of revision %s: %w", revision, err)
+	}
+
+	if len(changes) == 0 {
+		return nil, fmt.Errorf("revision %s is not known to TeamCity", revision)
+	}
+
+	builds, err := c.Build.ListBuilds(types.BuildLocator{
+		ProjectID: projectID,
+		Revision:  revision,
+		State:     "any",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing builds of revision %s: %w", revision, err)
+	}
+
+	// builds are listed newest first, so the first build of a Build Type is its latest one
+	latest := []types.Build{}
+	seen := map[string]bool{}
+
+	for _, build := range builds {
+		if seen[build.BuildTypeID] {
+			continue
+		}
+
+		seen[build.BuildTypeID] = true
+		latest = append(latest, build)
+	}
+
+	if len(latest) == 0 {
+		return nil, fmt.Errorf("no builds of revision %s were found", revision)
+	}
+
+	return latest, nil
+}
@@ -0,0 +1,69 @@
+package gate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"bbox/pkg/types"
+	"bbox/pkg/utils/testutils"
+	"bbox/teamcity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGate(t *testing.T) {
+	mockBuild := new(testutils.MockBuildService)
+	mockChange := new(testutils.MockChangeService)
+	client := &teamcity.Client{
+		Build:  mockBuild,
+		Change: mockChange,
+	}
+
+	api := types.BuildType{ID: "Backend_Api", Name: "Api", ProjectName: "Backend"}
+	worker := types.BuildType{ID: "Backend_Worker", Name: "Worker", ProjectName: "Backend"}
+	web := types.BuildType{ID: "Backend_Web", Name: "Web", ProjectName: "Backend"}
+
+	mockChange.On("GetChanges", teamcity.NewLocator().Add("version", "0a1b2c3").AddLocator("project", teamcity.IDLocator("Backend"))).
+		Return([]types.Change{{ID: 1, Version: "0a1b2c3"}}, nil)
+	mockBuild.On("ListBuilds", types.BuildLocator{ProjectID: "Backend", Revision: "0a1b2c3", State: "any"}).Return([]types.Build{
+		{ID: 3, BuildTypeID: api.ID, BuildType: api, State: types.BuildStateRunning},
+		{ID: 4, BuildTypeID: worker.ID, BuildType: worker, State: types.BuildStateFinished, Status: types.BuildStatusFailure},
+		{ID: 5, BuildTypeID: web.ID, BuildType: web, State: types.BuildStateQueued},
+		// an older build of a Build Type that was rebuilt does not count
+		{ID: 1, BuildTypeID: api.ID, BuildType: api, State: types.BuildStateFinished, Status: types.BuildStatusFailure},
+	}, nil)
+	mockBuild.On("WaitForBuild", "Backend / Api", 3, time.Minute).Return(types.BuildStatusResponse{ID: 3, State: types.BuildStateFinished, Status: types.BuildStatusSuccess}, nil)
+	mockBuild.On("GetBuildStatus", 3).Return(types.BuildStatusResponse{}, nil)
+	mockBuild.On("WaitForBuild", "Backend / Web", 5, time.Minute).Return(types.BuildStatusResponse{}, errors.New("timeout"))
+	mockBuild.On("GetBuildStatus", 5).Return(types.BuildStatusResponse{}, nil)
+
+	results, err := gate(context.Background(), client, "0a1b2c3", "Backend", time.Minute)
+	require.NoError(t, err)
+
+	require.Len(t, results, 3)
+	assert.Equal(t, "Backend / Api", results[0].BuildName)
+	assert.Equal(t, types.BuildStatusSuccess, results[0].BuildStatus)
+	assert.Equal(t, "Backend / Web", results[1].BuildName)
+	assert.Equal(t, types.BuildStatusUnknown, results[1].BuildStatus)
+	assert.Error(t, results[1].Error)
+	assert.Equal(t, "Backend / Worker", results[2].BuildName)
+	assert.Equal(t, types.BuildStatusFailure, results[2].BuildStatus)
+
+	mockBuild.AssertExpectations(t)
+	mockChange.AssertExpectations(t)
+}
+
+func TestGateUnknownRevision(t *testing.T) {
+	mockChange := new(testutils.MockChangeService)
+	client := &teamcity.Client{Change: mockChange}
+
+	mockChange.On("GetChanges", mock.Anything).Return([]types.Change{}, nil)
+
+	_, err := gate(context.Background(), client, "0a1b2c3", "", time.Minute)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is not known to TeamCity")
+}
@@ -0,0 +1,78 @@
+package graph
+
+import (
+	"net/url"
+	"os"
+
+	"bbox/pkg/types"
+	"bbox/teamcity"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	graphCmdName = "graph"
+	buildID      int
+	buildTypeID  string
+	output       = outputDOT
+)
+
+var Cmd = &cobra.Command{
+	Use:   graphCmdName,
+	Short: "Export the dependency graph of a TeamCity Build or Build Type",
+	Long: `Export the snapshot and artifact dependency graph of a TeamCity Build or Build Type as Graphviz DOT, Mermaid or JSON.
+The graph of a build shows the builds its dependencies resolved to, colored by their status. Snapshot dependencies are solid edges, artifact dependencies dashed ones.`,
+	Example: `  # render the build chain of a build as SVG
+  bbox graph --build-id 12345 | dot -Tsvg > chain.svg
+
+  # the dependencies of a Build Type as a Mermaid flowchart
+  bbox graph --build-type-id Backend_Release --output mermaid`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if buildID == 0 && buildTypeID == "" {
+			log.Error("one of --build-id or --build-type-id is required")
+			os.Exit(2)
+		}
+
+		teamcityUsername, _ := cmd.Root().PersistentFlags().GetString("teamcity-username")
+		teamcityPassword, _ := cmd.Root().PersistentFlags().GetString("teamcity-password")
+		teamcityURL, _ := cmd.Root().PersistentFlags().GetString("teamcity-url")
+
+		url, err := url.Parse(teamcityURL)
+		if err != nil {
+			log.Errorf("error parsing TeamCity URL: %s", err)
+			os.Exit(2)
+		}
+
+		client, err := teamcity.NewTeamCityClient(url, teamcityUsername, teamcityPassword)
+		if err != nil {
+			log.Errorf("error initializing TeamCity Client: %s", err)
+			os.Exit(2)
+		}
+
+		var graph types.DependencyGraph
+		if buildID != 0 {
+			graph, err = teamcity.BuildGraph(client, buildID)
+		} else {
+			graph, err = teamcity.BuildTypeGraph(client, buildTypeID)
+		}
+
+		if err != nil {
+			log.Errorf("error getting the dependency graph: %s", err)
+			os.Exit(2)
+		}
+
+		err = renderGraph(os.Stdout, graph, output)
+		if err != nil {
+			log.Errorf("error rendering the dependency graph: %s", err)
+			os.Exit(2)
+		}
+	},
+}
+
+func init() {
+	Cmd.Flags().IntVar(&buildID, "build-id", 0, "The ID of the build whose dependency graph to export")
+	Cmd.Flags().StringVarP(&buildTypeID, "build-type-id", "i", "", "The ID of the Build Type whose dependency graph to export")
+	Cmd.Flags().StringVarP(&output, "output", "o", output, "Output format: dot, mermaid or json")
+	Cmd.MarkFlagsMutuallyExclusive("build-id", "build-type-id")
+}
@@ -0,0 +1,176 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"bbox/pkg/types"
+)
+
+const (
+	outputDOT     = "dot"
+	outputMermaid = "mermaid"
+	outputJSON    = "json"
+)
+
+// nodeClasses are the classes of build nodes by status, in the order they are declared, with their fill colors.
+var nodeClasses = []struct {
+	name  string
+	color string
+}{
+	{"success", "#c8e6c9"},
+	{"failed", "#ffcdd2"},
+	{"canceled", "#fff9c4"},
+	{"running", "#bbdefb"},
+	{"queued", "#eeeeee"},
+}
+
+func renderGraph(w io.Writer, graph types.DependencyGraph, format string) error {
+	switch format {
+	case outputDOT:
+		return graphDOT(w, graph)
+	case outputMermaid:
+		return graphMermaid(w, graph)
+	case outputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(graph)
+	default:
+		return fmt.Errorf("unknown output format %q, expected one of: %s, %s, %s", format, outputDOT, outputMermaid, outputJSON)
+	}
+}
+
+// graphDOT renders the graph in the Graphviz DOT language, e.g. for `dot -Tsvg`.
+// Snapshot dependencies are solid edges, artifact dependencies dashed ones.
+func graphDOT(w io.Writer, graph types.DependencyGraph) error {
+	var b strings.Builder
+
+	b.WriteString("digraph dependencies {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=\"rounded,filled\", fillcolor=\"#ffffff\"];\n")
+
+	ids := nodeIDs(graph)
+
+	for _, node := range graph.Nodes {
+		attributes := []string{"label=" + dotQuote(node.Label)}
+
+		if class := nodeClass(node); class != "" {
+			attributes = append(attributes, "fillcolor="+dotQuote(classColor(class)))
+		}
+
+		if node.WebURL != "" {
+			attributes = append(attributes, "URL="+dotQuote(node.WebURL))
+		}
+
+		fmt.Fprintf(&b, "  %s [%s];\n", ids[node.ID], strings.Join(attributes, ", "))
+	}
+
+	for _, edge := range graph.Edges {
+		if edge.Type == types.DependencyArtifact {
+			fmt.Fprintf(&b, "  %s -> %s [style=dashed, label=%q];\n", ids[edge.From], ids[edge.To], edge.Type)
+			continue
+		}
+
+		fmt.Fprintf(&b, "  %s -> %s;\n", ids[edge.From], ids[edge.To])
+	}
+
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+// graphMermaid renders the graph as a Mermaid flowchart, e.g. to embed in Markdown.
+// Snapshot dependencies are solid edges, artifact dependencies dotted ones.
+func graphMermaid(w io.Writer, graph types.DependencyGraph) error {
+	var b strings.Builder
+
+	b.WriteString("flowchart LR\n")
+
+	ids := nodeIDs(graph)
+	classes := map[string][]string{}
+
+	for _, node := range graph.Nodes {
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", ids[node.ID], mermaidEscape(node.Label))
+
+		if class := nodeClass(node); class != "" {
+			classes[class] = append(classes[class], ids[node.ID])
+		}
+	}
+
+	for _, edge := range graph.Edges {
+		if edge.Type == types.DependencyArtifact {
+			fmt.Fprintf(&b, "  %s -.->|%s| %s\n", ids[edge.From], edge.Type, ids[edge.To])
+			continue
+		}
+
+		fmt.Fprintf(&b, "  %s --> %s\n", ids[edge.From], ids[edge.To])
+	}
+
+	for _, class := range nodeClasses {
+		if len(classes[class.name]) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&b, "  classDef %s fill:%s\n", class.name, class.color)
+		fmt.Fprintf(&b, "  class %s %s\n", strings.Join(classes[class.name], ","), class.name)
+	}
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+// nodeIDs maps the IDs of the nodes to identifiers that are valid in DOT and Mermaid, in the order of the nodes.
+func nodeIDs(graph types.DependencyGraph) map[string]string {
+	ids := make(map[string]string, len(graph.Nodes))
+	for i, node := range graph.Nodes {
+		ids[node.ID] = "n" + strconv.Itoa(i)
+	}
+
+	return ids
+}
+
+// nodeClass returns the class of a build node by its state and status, or "" for build type nodes.
+func nodeClass(node types.GraphNode) string {
+	if node.BuildID == 0 {
+		return ""
+	}
+
+	switch {
+	case node.State == types.BuildStateQueued:
+		return "queued"
+	case node.State == types.BuildStateRunning:
+		return "running"
+	case node.Status.WasCanceled():
+		return "canceled"
+	case node.Status.IsSuccessful():
+		return "success"
+	default:
+		return "failed"
+	}
+}
+
+func classColor(class string) string {
+	for _, c := range nodeClasses {
+		if c.name == class {
+			return c.color
+		}
+	}
+
+	return ""
+}
+
+func dotQuote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
+
+// mermaidEscape escapes a label with Mermaid entity codes, '#' first as it starts them.
+func mermaidEscape(value string) string {
+	return strings.NewReplacer("#", "#35;", `"`, "#quot;").Replace(value)
+}
@@ -0,0 +1,79 @@
+package graph
+
+import (
+	"bytes"
+	"testing"
+
+	"bbox/pkg/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testGraph = types.DependencyGraph{
+	Nodes: []types.GraphNode{
+		{ID: "1", Label: `Release "stable" #7`, BuildTypeID: "Release", BuildID: 1, Status: types.BuildStatusSuccess, State: types.BuildStateFinished, WebURL: "https://teamcity/build/1"},
+		{ID: "2", Label: "Api #7", BuildTypeID: "Api", BuildID: 2, Status: types.BuildStatusFailure, State: types.BuildStateFinished},
+		{ID: "3", Label: "Web #8", BuildTypeID: "Web", BuildID: 3, State: types.BuildStateRunning},
+	},
+	Edges: []types.GraphEdge{
+		{From: "1", To: "2", Type: types.DependencySnapshot},
+		{From: "1", To: "3", Type: types.DependencyArtifact},
+	},
+}
+
+func TestGraphDOT(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	require.NoError(t, renderGraph(&buf, testGraph, outputDOT))
+
+	assert.Equal(t, `digraph dependencies {
+  rankdir=LR;
+  node [shape=box, style="rounded,filled", fillcolor="#ffffff"];
+  n0 [label="Release \"stable\" #7", fillcolor="#c8e6c9", URL="https://teamcity/build/1"];
+  n1 [label="Api #7", fillcolor="#ffcdd2"];
+  n2 [label="Web #8", fillcolor="#bbdefb"];
+  n0 -> n1;
+  n0 -> n2 [style=dashed, label="artifact"];
+}
+`, buf.String())
+}
+
+func TestGraphMermaid(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	require.NoError(t, renderGraph(&buf, testGraph, outputMermaid))
+
+	assert.Equal(t, `flowchart LR
+  n0["Release #quot;stable#quot; #35;7"]
+  n1["Api #35;7"]
+  n2["Web #35;8"]
+  n0 --> n1
+  n0 -.->|artifact| n2
+  classDef success fill:#c8e6c9
+  class n0 success
+  classDef failed fill:#ffcdd2
+  class n1 failed
+  classDef running fill:#bbdefb
+  class n2 running
+`, buf.String())
+}
+
+func TestGraphBuildTypeNodesAreNotColored(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	err := renderGraph(&buf, types.DependencyGraph{Nodes: []types.GraphNode{{ID: "Backend_Api", Label: "Backend / Api", BuildTypeID: "Backend_Api"}}}, outputDOT)
+	require.NoError(t, err)
+
+	assert.Contains(t, buf.String(), `n0 [label="Backend / Api"];`)
+}
+
+func TestRenderGraphUnknownFormat(t *testing.T) {
+	t.Parallel()
+
+	err := renderGraph(&bytes.Buffer{}, testGraph, "svg")
+	assert.ErrorContains(t, err, `unknown output format "svg"`)
+}
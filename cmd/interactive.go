@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"bbox/pkg/models"
+	"bbox/pkg/params"
+	"bbox/pkg/types"
+	"bbox/teamcity"
+
+	tea "github.com/charmbracelet/bubbletea"
+	log "github.com/sirupsen/logrus"
+)
+
+var errInteractiveCanceled = errors.New("canceled by the user")
+
+// interactiveSelection holds the build chosen in the interactive trigger flow.
+type interactiveSelection struct {
+	BuildType  types.BuildType
+	BranchName string
+	Properties map[string]string
+	// Secrets holds the names of password parameters, which are masked when printed
+	Secrets map[string]bool
+}
+
+// interactiveTrigger lets the user pick a build type, a branch and the build parameters, and confirm triggering it.
+// The given properties are used as the initial parameter values.
+func interactiveTrigger(client *teamcity.Client, properties map[string]string) (interactiveSelection, error) {
+	buildType, err := pickBuildType(client)
+	if err != nil {
+		return interactiveSelection{}, err
+	}
+
+	branch, err := pickBranch(client, buildType)
+	if err != nil {
+		return interactiveSelection{}, err
+	}
+
+	buildProperties, secrets, err := fillParameters(client, buildType, properties)
+	if err != nil {
+		return interactiveSelection{}, err
+	}
+
+	selection := interactiveSelection{
+		BuildType:  buildType,
+		BranchName: branch,
+		Properties: buildProperties,
+		Secrets:    secrets,
+	}
+
+	printSelection(selection)
+
+	confirmed, err := runModel(models.NewConfirmActionModel())
+	if err != nil {
+		return interactiveSelection{}, err
+	}
+
+	if confirmModel, ok := confirmed.(models.ConfirmActionModel); !ok || !confirmModel.IsConfirmed() {
+		return interactiveSelection{}, errInteractiveCanceled
+	}
+
+	return selection, nil
+}
+
+func pickBuildType(client *teamcity.Client) (types.BuildType, error) {
+	log.Debug("fetching all build types")
+
+	buildTypes, err := client.BuildType.GetBuildTypes(nil)
+	if err != nil {
+		return types.BuildType{}, fmt.Errorf("error getting build types: %w", err)
+	}
+
+	sort.Slice(buildTypes, func(i, j int) bool {
+		return buildTypes[i].FullName() < buildTypes[j].FullName()
+	})
+
+	items := make([]models.SelectItem, 0, len(buildTypes))
+	for _, buildType := range buildTypes {
+		items = append(items, models.SelectItem{Label: buildType.Name, Value: buildType.ID, Group: buildType.ProjectName})
+	}
+
+	selected, err := runModel(models.NewSelectModel("Select a build configuration:", items, false))
+	if err != nil {
+		return types.BuildType{}, err
+	}
+
+	selectModel, ok := selected.(models.SelectModel)
+	if !ok || !selectModel.IsChosen() {
+		return types.BuildType{}, errInteractiveCanceled
+	}
+
+	for _, buildType := range buildTypes {
+		if buildType.ID == selectModel.Selected.Value {
+			return buildType, nil
+		}
+	}
+
+	return types.BuildType{}, fmt.Errorf("unknown build type %s", selectModel.Selected.Value)
+}
+
+func pickBranch(client *teamcity.Client, buildType types.BuildType) (string, error) {
+	branches, err := client.BuildType.GetBranches(buildType.ID)
+	if err != nil {
+		log.Warnf("error getting branches of %s, type a branch name instead: %s", buildType.ID, err)
+	}
+
+	// show the default branch first
+	sort.SliceStable(branches, func(i, j int) bool {
+		return branches[i].Default && !branches[j].Default
+	})
+
+	items := make([]models.SelectItem, 0, len(branches))
+	for _, branch := range branches {
+		label := branch.Name
+		if branch.Default {
+			label += " (default)"
+		}
+		items = append(items, models.SelectItem{Label: label, Value: branch.Name})
+	}
+
+	selected, err := runModel(models.NewSelectModel(fmt.Sprintf("Select a branch of %s:", buildType.FullName()), items, true))
+	if err != nil {
+		return "", err
+	}
+
+	selectModel, ok := selected.(models.SelectModel)
+	if !ok || !selectModel.IsChosen() {
+		return "", errInteractiveCanceled
+	}
+
+	return selectModel.Selected.Value, nil
+}
+
+func fillParameters(client *teamcity.Client, buildType types.BuildType, properties map[string]string) (map[string]string, map[string]bool, error) {
+	parameters, err := client.BuildType.GetParameters(buildType.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting parameters of %s: %w", buildType.ID, err)
+	}
+
+	fields := []models.ParameterField{}
+	secrets := map[string]bool{}
+
+	for _, parameter := range parameters {
+		spec := types.ParameterSpec{Type: params.ParameterTypeText, Display: params.ParameterDisplayNormal}
+
+		if parameter.Type != nil {
+			spec, err = params.ParseParameterSpec(parameter.Type.RawValue)
+			if err != nil {
+				log.Warnf("ignoring the type spec of %s: %s", parameter.Name, err)
+			}
+		}
+
+		// inherited parameters without a spec are mostly server wide settings, not build inputs
+		if spec.Display == params.ParameterDisplayHidden || (parameter.Inherited && parameter.Type == nil) {
+			continue
+		}
+
+		if spec.Type == params.ParameterTypePassword {
+			secrets[parameter.Name] = true
+		}
+
+		value := parameter.Value
+		if propertyValue, ok := properties[parameter.Name]; ok {
+			value = propertyValue
+		}
+
+		fields = append(fields, models.ParameterField{
+			Name:    parameter.Name,
+			Spec:    spec,
+			Default: parameter.Value,
+			Value:   value,
+		})
+	}
+
+	if len(fields) == 0 {
+		return properties, secrets, nil
+	}
+
+	form, err := runModel(models.NewParametersFormModel(fmt.Sprintf("Parameters of %s:", buildType.FullName()), fields))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	formModel, ok := form.(models.ParametersFormModel)
+	if !ok || !formModel.IsSubmitted() {
+		return nil, nil, errInteractiveCanceled
+	}
+
+	buildProperties := formModel.Values()
+
+	// keep properties given by flags that are not declared on the build type
+	for name, value := range properties {
+		if _, ok := buildProperties[name]; !ok {
+			buildProperties[name] = value
+		}
+	}
+
+	return buildProperties, secrets, nil
+}
+
+func printSelection(selection interactiveSelection) {
+	names := make([]string, 0, len(selection.Properties))
+	for name := range selection.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Build:  %s (%s)\n", selection.BuildType.FullName(), selection.BuildType.ID)
+	fmt.Fprintf(&b, "Branch: %s\n", selection.BranchName)
+
+	if len(names) > 0 {
+		b.WriteString("Properties:\n")
+		for _, name := range names {
+			value := selection.Properties[name]
+			if selection.Secrets[name] && value != "" {
+				value = models.PasswordMask
+			}
+			fmt.Fprintf(&b, "  %s=%s\n", name, value)
+		}
+	}
+
+	fmt.Print(b.String())
+}
+
+func runModel(model tea.Model) (tea.Model, error) {
+	activeModel, err := tea.NewProgram(model).Run()
+	if err != nil {
+		return nil, fmt.Errorf("error while running interactive model: %w", err)
+	}
+
+	return activeModel, nil
+}
@@ -0,0 +1,151 @@
+package multitrigger
+
+import (
+	"bbox/pkg/types"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// layoutData are the fields available in artifacts layout templates and per-combination artifacts paths.
+type layoutData struct {
+	BuildTypeID string
+	BranchName  string
+	BuildID     int
+}
+
+// artifactsLayout places the artifacts of every combination under the artifacts path, in a directory rendered from a template.
+// The artifacts of all combinations are downloaded to the artifacts path itself if there is no template.
+type artifactsLayout struct {
+	root     string
+	layout   string
+	template *template.Template
+	// paths are the parsed per-combination artifacts paths, keyed by the path they were parsed from
+	paths map[string]*template.Template
+}
+
+// sampleLayoutData are the build fields layout templates are checked with before any build is triggered.
+var sampleLayoutData = layoutData{BuildTypeID: "Project_Build", BranchName: "main", BuildID: 1}
+
+// newArtifactsLayout parses a layout template such as "{{.BuildTypeID}}/{{.BranchName}}/{{.BuildID}}" relative to root.
+// It fails if the layout resolves outside of root, e.g. "../{{.BuildID}}" or an absolute path.
+// The artifacts paths of the combinations are parsed as templates of the same fields.
+func newArtifactsLayout(root, layout string, combinations []types.BuildParameters) (artifactsLayout, error) {
+	l := artifactsLayout{root: root, layout: layout, paths: map[string]*template.Template{}}
+
+	for _, p := range combinations {
+		if p.ArtifactsPath == "" || l.paths[p.ArtifactsPath] != nil {
+			continue
+		}
+
+		tmpl, _, err := parseLayoutTemplate("artifacts path", p.ArtifactsPath)
+		if err != nil {
+			return artifactsLayout{}, err
+		}
+
+		l.paths[p.ArtifactsPath] = tmpl
+	}
+
+	if layout == "" {
+		return l, nil
+	}
+
+	tmpl, sample, err := parseLayoutTemplate("artifacts layout", layout)
+	if err != nil {
+		return artifactsLayout{}, err
+	}
+
+	// the fields are single directory names, so a layout within root for the sample build is within root for every build
+	if !filepath.IsLocal(sample) {
+		return artifactsLayout{}, fmt.Errorf("invalid artifacts layout %q: it resolves to %q outside of the artifacts path", layout, sample)
+	}
+
+	l.template = tmpl
+
+	return l, nil
+}
+
+// Path returns the directory the artifacts of a triggered build of a combination are downloaded to.
+// A per-combination artifacts path is used as is, and the layout is rendered under the root otherwise.
+func (l artifactsLayout) Path(p types.BuildParameters, buildID int) (string, error) {
+	data := newLayoutData(p, buildID)
+
+	if p.ArtifactsPath != "" {
+		tmpl, ok := l.paths[p.ArtifactsPath]
+		if !ok {
+			return "", fmt.Errorf("artifacts path %q of %s was not parsed with the artifacts layout", p.ArtifactsPath, p.BuildTypeID)
+		}
+
+		return renderLayout(tmpl, data)
+	}
+
+	if l.template == nil {
+		return l.root, nil
+	}
+
+	dir, err := renderLayout(l.template, data)
+	if err != nil {
+		return "", err
+	}
+
+	if !filepath.IsLocal(dir) {
+		return "", fmt.Errorf("artifacts layout %q resolves to %q outside of the artifacts path", l.layout, dir)
+	}
+
+	return filepath.Join(l.root, dir), nil
+}
+
+// parseLayoutTemplate parses a layout template, and returns it rendered for the sample build.
+func parseLayoutTemplate(name, text string) (*template.Template, string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid %s %q: %w", name, text, err)
+	}
+
+	// fail on unknown fields before any build is triggered
+	sample, err := renderLayout(tmpl, sampleLayoutData)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid %s %q: %w", name, text, err)
+	}
+
+	return tmpl, sample, nil
+}
+
+func renderLayout(tmpl *template.Template, data layoutData) (string, error) {
+	var b strings.Builder
+
+	err := tmpl.Execute(&b, data)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Clean(filepath.FromSlash(b.String())), nil
+}
+
+// newLayoutData returns the layout fields of a build, sanitized so every field is a single directory name,
+// e.g. the branch "feature/login" is rendered as "feature_login".
+func newLayoutData(p types.BuildParameters, buildID int) layoutData {
+	return layoutData{
+		BuildTypeID: pathSegment(p.BuildTypeID),
+		BranchName:  pathSegment(p.BranchName),
+		BuildID:     buildID,
+	}
+}
+
+// pathSegment replaces the characters that are not allowed in directory names on any platform with underscores.
+func pathSegment(s string) string {
+	segment := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < ' ' {
+			return '_'
+		}
+
+		return r
+	}, s)
+
+	if segment == "" || segment == "." || segment == ".." {
+		return "_"
+	}
+
+	return segment
+}
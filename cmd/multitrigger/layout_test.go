@@ -0,0 +1,82 @@
+package multitrigger
+
+import (
+	"path/filepath"
+	"testing"
+
+	"bbox/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtifactsLayoutPath(t *testing.T) {
+	testCases := []struct {
+		name          string
+		layout        string
+		parameters    types.BuildParameters
+		expectedPath  string
+		expectedError string
+	}{
+		{
+			name:         "no layout",
+			parameters:   types.BuildParameters{BuildTypeID: "bt1", BranchName: "main"},
+			expectedPath: "artifacts",
+		},
+		{
+			name:         "layout",
+			layout:       "{{.BuildTypeID}}/{{.BranchName}}/{{.BuildID}}",
+			parameters:   types.BuildParameters{BuildTypeID: "bt1", BranchName: "main"},
+			expectedPath: filepath.Join("artifacts", "bt1", "main", "42"),
+		},
+		{
+			name:         "branch with separators",
+			layout:       "{{.BranchName}}",
+			parameters:   types.BuildParameters{BuildTypeID: "bt1", BranchName: "feature/login"},
+			expectedPath: filepath.Join("artifacts", "feature_login"),
+		},
+		{
+			name:         "default branch",
+			layout:       "{{.BuildTypeID}}-{{.BranchName}}",
+			parameters:   types.BuildParameters{BuildTypeID: "bt1", BranchName: "<default>"},
+			expectedPath: filepath.Join("artifacts", "bt1-_default_"),
+		},
+		{
+			name:         "branch escaping the artifacts path",
+			layout:       "{{.BranchName}}",
+			parameters:   types.BuildParameters{BuildTypeID: "bt1", BranchName: ".."},
+			expectedPath: filepath.Join("artifacts", "_"),
+		},
+		{
+			name:         "combination artifacts path",
+			layout:       "{{.BuildTypeID}}",
+			parameters:   types.BuildParameters{BuildTypeID: "bt1", BranchName: "main", ArtifactsPath: "out/{{.BuildID}}"},
+			expectedPath: filepath.Join("out", "42"),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			layout, err := newArtifactsLayout("artifacts", tc.layout, []types.BuildParameters{tc.parameters})
+			require.NoError(t, err)
+
+			path, err := layout.Path(tc.parameters, 42)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedPath, path)
+		})
+	}
+}
+
+func TestNewArtifactsLayoutInvalid(t *testing.T) {
+	for _, layout := range []string{"{{.BuildTypeID", "{{.Unknown}}", "../{{.BuildID}}", "/tmp/{{.BuildID}}", "{{.BuildTypeID}}/../.."} {
+		layout := layout
+		t.Run(layout, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := newArtifactsLayout("artifacts", layout, nil)
+			assert.ErrorContains(t, err, "invalid artifacts layout")
+		})
+	}
+}
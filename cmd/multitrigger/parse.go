@@ -3,13 +3,21 @@ package multitrigger
 import (
 	"bbox/pkg/params"
 	"bbox/pkg/types"
+	"bbox/teamcity"
+	"errors"
 	"fmt"
 	log "github.com/sirupsen/logrus"
 	"strconv"
 	"strings"
 )
 
-const combinationPartsNumber = 4
+const (
+	combinationPartsNumber = 4
+	// combinationArtifactsPathPart is the optional last combination part, the artifacts path of the combination.
+	combinationArtifactsPathPart = 4
+	// buildTypeQueryPrefix marks the first combination part as a build type path or fuzzy query instead of an ID.
+	buildTypeQueryPrefix = "~"
+)
 
 // parseCombinations parses the combinations from the command line and returns a slice of BuildParameters.
 func parseCombinations(combinations []string) ([]types.BuildParameters, error) {
@@ -17,12 +25,18 @@ func parseCombinations(combinations []string) ([]types.BuildParameters, error) {
 
 	for _, combo := range combinations {
 		parts := strings.Split(combo, ";")
-		if len(parts) != combinationPartsNumber {
-			log.Errorf("invalid combination format: %s. expected: 'buildTypeID;branchName;downloadArtifactsBool;key1=value1&key2=value2'", combo)
+		if len(parts) != combinationPartsNumber && len(parts) != combinationPartsNumber+1 {
+			log.Errorf("invalid combination format: %s. expected: 'buildTypeID;branchName;downloadArtifactsBool;key1=value1&key2=value2[;artifactsPath]'", combo)
 			return nil, fmt.Errorf("invalid combination format: %s", combo)
 		}
 
-		if !params.IsValidBuildID(parts[0]) {
+		buildTypeID, buildTypeQuery := parts[0], ""
+		if strings.HasPrefix(parts[0], buildTypeQueryPrefix) {
+			buildTypeID, buildTypeQuery = "", strings.TrimSpace(strings.TrimPrefix(parts[0], buildTypeQueryPrefix))
+			if buildTypeQuery == "" {
+				return nil, fmt.Errorf("empty build type query: %s", parts[0])
+			}
+		} else if !params.IsValidBuildID(parts[0]) {
 			return nil, fmt.Errorf("invalid buildTypeID: %s", parts[0])
 		}
 
@@ -41,17 +55,90 @@ func parseCombinations(combinations []string) ([]types.BuildParameters, error) {
 			return nil, fmt.Errorf("failed to parse properties: %s, error: %w", parts[3], err)
 		}
 
+		var artifactsPath string
+		if len(parts) > combinationArtifactsPathPart {
+			artifactsPath = parts[combinationArtifactsPathPart]
+
+			// only validated here, the artifacts layout keeps the parsed template
+			_, _, err = parseLayoutTemplate("artifacts path", artifactsPath)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		parsed = append(parsed, types.BuildParameters{
-			BuildTypeID:       parts[0],
+			BuildTypeID:       buildTypeID,
+			BuildTypeQuery:    buildTypeQuery,
 			BranchName:        parts[1],
 			DownloadArtifacts: downloadArtifacts,
 			PropertiesFlag:    properties,
+			ArtifactsPath:     artifactsPath,
 		})
 	}
 
 	return parsed, nil
 }
 
+// resolveBuildTypes resolves the build type query of every combination that was not given a build type ID.
+func resolveBuildTypes(c *teamcity.Client, combinations []types.BuildParameters) error {
+	for i, combination := range combinations {
+		if combination.BuildTypeQuery == "" {
+			continue
+		}
+
+		buildTypeID, err := c.BuildType.ResolveBuildTypeID(combination.BuildTypeQuery)
+		if err != nil {
+			return fmt.Errorf("error resolving build type: %w", err)
+		}
+
+		combinations[i].BuildTypeID = buildTypeID
+	}
+
+	return nil
+}
+
+// loadCombinationsProperties merges the properties loaded from files and environment variables into every combination.
+// Properties given in a combination override the loaded ones.
+func loadCombinationsProperties(combinations []types.BuildParameters, files []string, envPrefix string) error {
+	if len(files) == 0 && envPrefix == "" {
+		return nil
+	}
+
+	loaded, err := params.LoadProperties(files, envPrefix, nil)
+	if err != nil {
+		return err
+	}
+
+	for i, combination := range combinations {
+		combinations[i].PropertiesFlag = params.MergeProperties(loaded, combination.PropertiesFlag)
+	}
+
+	return nil
+}
+
+// validateProperties validates the properties of every combination against its build type's declared parameters.
+func validateProperties(c *teamcity.Client, combinations []types.BuildParameters, strict bool) error {
+	var errs []error
+
+	for _, combination := range combinations {
+		err := c.BuildType.ValidateProperties(combination.BuildTypeID, combination.PropertiesFlag, strict)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// propertyValueUnescaper decodes the escapes of the separators in combination property values, so a value can contain '&', ';' and '='.
+// Any other '%' is taken literally, and "%25" escapes a '%' followed by characters that would be decoded otherwise.
+var propertyValueUnescaper = strings.NewReplacer(
+	"%25", "%",
+	"%26", "&",
+	"%3B", ";", "%3b", ";",
+	"%3D", "=", "%3d", "=",
+)
+
 // parseProperties parses the properties from the command line and returns a map of string to string.
 func parseProperties(properties string) (map[string]string, error) {
 	if properties == "" {
@@ -66,17 +153,13 @@ func parseProperties(properties string) (map[string]string, error) {
 			return nil, fmt.Errorf("invalid property format: %s", prop)
 		}
 
-		key, value := kv[0], kv[1]
+		key := kv[0]
 
 		if !params.ValidateParamKey(key) {
 			return nil, fmt.Errorf("invalid property key: %s", key)
 		}
 
-		if !params.ValidateParamValue(value) {
-			return nil, fmt.Errorf("invalid property value: %s", value)
-		}
-
-		propertiesMap[key] = value
+		propertiesMap[key] = propertyValueUnescaper.Replace(kv[1])
 	}
 
 	return propertiesMap, nil
@@ -49,6 +49,82 @@ func TestParseCombinations(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "percent-encoded property values",
+			combinations: []string{
+				"bt1;main;false;query=a%26b%3Bc%3dd&message=hello world",
+			},
+			expectedOutput: []types.BuildParameters{
+				{
+					BuildTypeID:       "bt1",
+					BranchName:        "main",
+					DownloadArtifacts: false,
+					PropertiesFlag: map[string]string{
+						"query":   "a&b;c=d",
+						"message": "hello world",
+					},
+				},
+			},
+		},
+		{
+			name: "literal percent in property values",
+			combinations: []string{
+				"bt1;main;false;threshold=50%&encoded=a%20b&escaped=%2526",
+			},
+			expectedOutput: []types.BuildParameters{
+				{
+					BuildTypeID:       "bt1",
+					BranchName:        "main",
+					DownloadArtifacts: false,
+					PropertiesFlag: map[string]string{
+						"threshold": "50%",
+						"encoded":   "a%20b",
+						"escaped":   "%26",
+					},
+				},
+			},
+		},
+		{
+			name: "build type query",
+			combinations: []string{
+				"~Project / Sub / Build name;main;false;",
+			},
+			expectedOutput: []types.BuildParameters{
+				{
+					BuildTypeQuery:    "Project / Sub / Build name",
+					BranchName:        "main",
+					DownloadArtifacts: false,
+				},
+			},
+		},
+		{
+			name: "artifacts path",
+			combinations: []string{
+				"bt1;main;true;;out/{{.BuildID}}",
+			},
+			expectedOutput: []types.BuildParameters{
+				{
+					BuildTypeID:       "bt1",
+					BranchName:        "main",
+					DownloadArtifacts: true,
+					ArtifactsPath:     "out/{{.BuildID}}",
+				},
+			},
+		},
+		{
+			name: "invalid artifacts path",
+			combinations: []string{
+				"bt1;main;true;;out/{{.Build}}",
+			},
+			expectedError: "invalid artifacts path",
+		},
+		{
+			name: "empty build type query",
+			combinations: []string{
+				"~;main;false;",
+			},
+			expectedError: "empty build type query",
+		},
 		{
 			name: "invalid combination format",
 			combinations: []string{
@@ -101,6 +177,7 @@ func TestParseCombinations(t *testing.T) {
 	}
 
 	for _, tc := range testCases {
+		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
@@ -111,6 +188,7 @@ func TestParseCombinations(t *testing.T) {
 				assert.Contains(t, err.Error(), tc.expectedError)
 			} else {
 				require.NoError(t, err)
+
 				assert.Equal(t, tc.expectedOutput, output)
 			}
 		})
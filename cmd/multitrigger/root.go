@@ -1,6 +1,9 @@
 package multitrigger
 
 import (
+	"bbox/pkg/flags"
+	"bbox/pkg/interrupt"
+	"bbox/pkg/params"
 	"bbox/teamcity"
 	"net/url"
 	"os"
@@ -14,9 +17,16 @@ var (
 	buildParamsCombinations []string
 	multiTriggerCmdName     = "multi-trigger"
 	multiArtifactsPath      = "./"
+	artifactsLayoutTemplate string
 	waitForBuilds           = true
 	waitTimeout             = 15 * time.Minute
 	requireArtifacts        bool
+	propertiesFiles         []string
+	propertiesEnvPrefix     string
+	strictParams            bool
+	exportParams            params.ExportOptions
+	artifactFlags           *flags.ArtifactFlags
+	cancelOnInterrupt       bool
 )
 
 var Cmd = &cobra.Command{
@@ -33,8 +43,36 @@ var Cmd = &cobra.Command{
 			log.Errorf("failed to parse combinations: %v", err)
 			os.Exit(1)
 		}
+		err = loadCombinationsProperties(allCombinations, propertiesFiles, propertiesEnvPrefix)
+		if err != nil {
+			log.Errorf("failed to load properties: %v", err)
+			os.Exit(1)
+		}
 		log.WithField("combinations", allCombinations).Debug("Here are the possible combinations")
 
+		artifactOptions, err := artifactFlags.Options()
+		if err != nil {
+			log.Error(err)
+			os.Exit(1)
+		}
+
+		artifactOptions.RequireMatch = requireArtifacts
+
+		// builds run concurrently, fail instead of letting one overwrite the artifacts of another
+		artifactOptions.Claims = teamcity.NewArtifactClaims()
+
+		err = exportParams.Validate()
+		if err != nil {
+			log.Errorf("invalid --export-params options: %v", err)
+			os.Exit(1)
+		}
+
+		layout, err := newArtifactsLayout(multiArtifactsPath, artifactsLayoutTemplate, allCombinations)
+		if err != nil {
+			log.Errorf("failed to parse artifacts layout: %v", err)
+			os.Exit(1)
+		}
+
 		url, err := url.Parse(teamcityURL)
 		if err != nil {
 			log.Errorf("error parsing TeamCity URL: %s", err)
@@ -48,7 +86,29 @@ var Cmd = &cobra.Command{
 			os.Exit(2)
 		}
 
-		err = triggerBuilds(client, allCombinations, waitForBuilds, waitTimeout, multiArtifactsPath, requireArtifacts)
+		err = resolveBuildTypes(client, allCombinations)
+		if err != nil {
+			log.Errorf("failed to resolve build types: %v", err)
+			os.Exit(1)
+		}
+
+		err = validateProperties(client, allCombinations, strictParams)
+		if err != nil {
+			log.Errorf("failed to validate properties: %v", err)
+			os.Exit(1)
+		}
+
+		ctx, stop := interrupt.NotifyContext()
+		defer stop()
+
+		tracker := interrupt.NewTracker()
+
+		err = triggerBuilds(ctx, client, tracker, allCombinations, waitForBuilds, waitTimeout, layout, requireArtifacts, exportParams, artifactOptions)
+
+		if ctx.Err() != nil {
+			interrupt.Handle(client, tracker, cancelOnInterrupt, os.Stdout)
+			os.Exit(interrupt.ExitCode)
+		}
 
 		if err != nil {
 			log.Errorf("trigger builds failed: %v", err)
@@ -58,9 +118,18 @@ var Cmd = &cobra.Command{
 }
 
 func init() {
-	Cmd.PersistentFlags().StringSliceVarP(&buildParamsCombinations, "build-params-combination", "c", []string{}, "Combinations as 'buildTypeID;branchName;downloadArtifactsBool;key1=value1&key2=value2' format. Repeatable. example: 'myBuildId;master;true;key=value&key2=value2'")
+	Cmd.PersistentFlags().StringSliceVarP(&buildParamsCombinations, "build-params-combination", "c", []string{}, "Combinations as 'buildTypeID;branchName;downloadArtifactsBool;key1=value1&key2=value2' format. Repeatable. example: 'myBuildId;master;true;key=value&key2=value2'. Prefix the buildTypeID with '~' to use a Build Type path or fuzzy name query instead, e.g. '~Project / Sub / Build name;master;true;'. An optional fifth part sets the artifacts path of the combination, e.g. 'myBuildId;master;true;;out/{{.BuildID}}'")
 	Cmd.PersistentFlags().StringVar(&multiArtifactsPath, "artifacts-path", multiArtifactsPath, "Path to download Artifacts to")
+	Cmd.PersistentFlags().StringVar(&artifactsLayoutTemplate, "artifacts-layout", "", "Template of the directory the artifacts of every build are downloaded to under --artifacts-path, e.g. '{{.BuildTypeID}}/{{.BranchName}}/{{.BuildID}}'. All builds share --artifacts-path if empty")
 	Cmd.PersistentFlags().BoolVarP(&waitForBuilds, "wait-for-builds", "w", waitForBuilds, "Wait for builds to finish and get status")
 	Cmd.PersistentFlags().DurationVarP(&waitTimeout, "wait-timeout", "t", waitTimeout, "Timeout for waiting for builds to finish, default is 15 minutes")
+	artifactFlags = flags.AddArtifactFlags(Cmd.PersistentFlags())
 	Cmd.PersistentFlags().BoolVar(&requireArtifacts, "require-artifacts", false, "If downloadArtifactsBool is true, and no artifacts found, return an error")
+	Cmd.PersistentFlags().StringVar(&exportParams.Path, "export-params", "", "Export the resulting parameters of the finished builds to this file, prefixed with their Build Type ID, and with their Build ID if a Build Type is triggered more than once")
+	Cmd.PersistentFlags().StringSliceVar(&exportParams.Filters, "export-params-filter", nil, "Only export the resulting parameters matching these glob patterns, e.g. 'env.*'. Repeatable")
+	Cmd.PersistentFlags().StringVar(&exportParams.Format, "export-params-format", "", "Format of the exported parameters: dotenv, json or github. Inferred from the file if not set")
+	Cmd.PersistentFlags().BoolVar(&cancelOnInterrupt, "cancel-on-interrupt", false, "Cancel all triggered builds without prompting when interrupted with ctrl+c")
+	Cmd.PersistentFlags().BoolVar(&strictParams, "strict-params", false, "Fail on properties that are not declared on the Build Type instead of warning")
+	Cmd.PersistentFlags().StringSliceVar(&propertiesFiles, "properties-file", nil, "Load properties for all combinations from a .env, JSON or YAML file. Repeatable, later files override earlier ones")
+	Cmd.PersistentFlags().StringVar(&propertiesEnvPrefix, "properties-from-env", "", "Load properties for all combinations from environment variables starting with this prefix, e.g. PREFIX_env__TAG sets env.TAG")
 }
@@ -1,23 +1,38 @@
 package multitrigger
 
 import (
+	"bbox/pkg/interrupt"
+	"bbox/pkg/params"
+	"bbox/pkg/report"
 	"bbox/pkg/types"
 	"bbox/teamcity"
+	"context"
 	"errors"
 	"fmt"
 	log "github.com/sirupsen/logrus"
+	"os"
+	"strconv"
 	"sync"
 	"time"
 )
 
 // triggerBuilds triggers the builds for each set of build parameters, wait and download artifacts if needed using work group.
-func triggerBuilds(c *teamcity.Client, parameters []types.BuildParameters, waitForBuilds bool, waitTimeout time.Duration, multiArtifactsPath string, requireArtifacts bool) error {
+// Triggered builds are recorded in tracker, and waiting stops when ctx is done. The artifacts of every build are downloaded to the directory given by layout.
+func triggerBuilds(ctx context.Context, c *teamcity.Client, tracker *interrupt.Tracker, parameters []types.BuildParameters, waitForBuilds bool, waitTimeout time.Duration, layout artifactsLayout, requireArtifacts bool, exportParams params.ExportOptions, artifactOptions teamcity.ArtifactOptions) error {
 	flowFailed := false
 	resultsChan := make(chan types.BuildResult, len(parameters))
 	errorChan := make(chan error, len(parameters))
 
 	var wg sync.WaitGroup
 
+	// resulting properties of all builds, prefixed with their build type ID, and their build ID if the build type is triggered more than once
+	var exportedMu sync.Mutex
+	exportedProperties := map[string]string{}
+	buildTypeCounts := map[string]int{}
+	for _, p := range parameters {
+		buildTypeCounts[p.BuildTypeID]++
+	}
+
 	for _, param := range parameters {
 		// Increment the WaitGroup's counter for each goroutine
 		wg.Add(1)
@@ -31,7 +46,7 @@ func triggerBuilds(c *teamcity.Client, parameters []types.BuildParameters, waitF
 				"buildTypeId":       p.BuildTypeID,
 				"properties":        p.PropertiesFlag,
 				"downloadArtifacts": p.DownloadArtifacts,
-				"artifactsPath":     multiArtifactsPath,
+				"artifactsPath":     layout.root,
 				"requireArtifacts":  requireArtifacts,
 				"waitForBuilds":     waitForBuilds,
 			}).Debug("triggering Build")
@@ -48,7 +63,7 @@ func triggerBuilds(c *teamcity.Client, parameters []types.BuildParameters, waitF
 					BuildName:           p.BuildTypeID,
 					WebURL:              triggerResponse.WebURL,
 					BranchName:          p.BranchName,
-					BuildStatus:         "NOT_TRIGGERED",
+					BuildStatus:         types.BuildStatusNotTriggered,
 					DownloadedArtifacts: false,
 					Error:               fmt.Errorf("error triggering build: %w", err),
 				}
@@ -61,13 +76,37 @@ func triggerBuilds(c *teamcity.Client, parameters []types.BuildParameters, waitF
 				"webURL":    triggerResponse.WebURL,
 			}).Info("Build Triggered")
 
+			tracker.Track(interrupt.TriggeredBuild{ID: triggerResponse.ID, Name: triggerResponse.BuildType.Name, WebURL: triggerResponse.WebURL})
+
+			artifactsPath, err := layout.Path(p, triggerResponse.ID)
+			if err != nil {
+				log.Errorf("error resolving the artifacts path of build %s: %s", triggerResponse.BuildType.Name, err.Error())
+
+				flowFailed = true
+
+				errorChan <- fmt.Errorf("error resolving artifacts path: %w", err)
+
+				resultsChan <- types.BuildResult{
+					BuildName:           triggerResponse.BuildType.Name,
+					WebURL:              triggerResponse.WebURL,
+					BranchName:          p.BranchName,
+					BuildStatus:         types.BuildStatusUnknown,
+					DownloadedArtifacts: false,
+					Error:               fmt.Errorf("error resolving artifacts path: %w", err),
+				}
+
+				return
+			}
+
 			downloadedArtifacts := false
-			status := "UNKNOWN"
+			status := types.BuildStatusUnknown
+			var duration time.Duration
+			var constituents []types.BuildResult
 
 			if waitForBuilds {
 				log.Infof("waiting for build %s", triggerResponse.BuildType.Name)
 
-				build, err := c.Build.WaitForBuild(triggerResponse.BuildType.Name, triggerResponse.ID, waitTimeout)
+				build, err := c.Build.WaitForBuild(triggerResponse.BuildType.Name, triggerResponse.ID, waitTimeout, teamcity.WithContext(ctx))
 				if err != nil {
 					log.Errorf("error waiting for build %s: %s", triggerResponse.BuildType.Name, err.Error())
 
@@ -87,20 +126,68 @@ func triggerBuilds(c *teamcity.Client, parameters []types.BuildParameters, waitF
 					return
 				}
 
-				status = build.Status
+				status = build.Result()
+				duration = build.Duration()
 
 				log.WithFields(log.Fields{
-					"buildStatus": build.Status,
+					"buildStatus": status,
 					"buildState":  build.State,
 				}).Infof("build %s finished", triggerResponse.BuildType.Name)
 
-				if status != "SUCCESS" {
+				if status.WasCanceled() {
+					flowFailed = true
+					errorChan <- fmt.Errorf("build %s was canceled", triggerResponse.BuildType.Name)
+				} else if !status.IsSuccessful() {
 					flowFailed = true
 					errorChan <- fmt.Errorf("Build status is not SUCCESS: (status: %s)", status)
 				}
 
-				if p.DownloadArtifacts && status == "SUCCESS" {
-					downloadedArtifacts, err = handleArtifacts(c, build.ID, p.BuildTypeID, triggerResponse.BuildType.Name, multiArtifactsPath, requireArtifacts)
+				if exportParams.Enabled() {
+					properties, err := c.Build.GetResultingProperties(build.ID, exportParams.Filters...)
+					if err != nil {
+						log.Errorf("error getting resulting parameters of build %s: %s", triggerResponse.BuildType.Name, err.Error())
+
+						flowFailed = true
+
+						errorChan <- fmt.Errorf("error getting resulting parameters: %w", err)
+					}
+
+					prefix := p.BuildTypeID
+					if buildTypeCounts[p.BuildTypeID] > 1 {
+						prefix += "." + strconv.Itoa(build.ID)
+					}
+
+					exportedMu.Lock()
+					for name, value := range properties {
+						exportedProperties[prefix+"."+name] = value
+					}
+					exportedMu.Unlock()
+				}
+
+				if build.Composite {
+					constituents, downloadedArtifacts, err = handleConstituents(c, build.ID, triggerResponse.BuildType.Name, artifactsPath, p.DownloadArtifacts && status.IsSuccessful(), requireArtifacts, artifactOptions)
+					if err != nil {
+						log.Errorf("error handling constituent builds of %s: %s", triggerResponse.BuildType.Name, err.Error())
+
+						flowFailed = true
+
+						errorChan <- fmt.Errorf("error handling constituent builds: %w", err)
+
+						resultsChan <- types.BuildResult{
+							BuildName:           triggerResponse.BuildType.Name,
+							WebURL:              triggerResponse.WebURL,
+							BranchName:          p.BranchName,
+							BuildStatus:         status,
+							Duration:            duration,
+							DownloadedArtifacts: downloadedArtifacts,
+							Error:               fmt.Errorf("error handling constituent builds: %w", err),
+							Constituents:        constituents,
+						}
+
+						return
+					}
+				} else if p.DownloadArtifacts && status.IsSuccessful() {
+					downloadedArtifacts, err = handleArtifacts(c, build.ID, p.BuildTypeID, triggerResponse.BuildType.Name, artifactsPath, requireArtifacts, artifactOptions)
 					if err != nil {
 						log.Errorf("error handling artifacts for build %s: %s", triggerResponse.BuildType.Name, err.Error())
 
@@ -113,6 +200,7 @@ func triggerBuilds(c *teamcity.Client, parameters []types.BuildParameters, waitF
 							WebURL:              triggerResponse.WebURL,
 							BranchName:          p.BranchName,
 							BuildStatus:         status,
+							Duration:            duration,
 							DownloadedArtifacts: downloadedArtifacts,
 							Error:               fmt.Errorf("error handling artifacts: %w", err),
 						}
@@ -122,15 +210,17 @@ func triggerBuilds(c *teamcity.Client, parameters []types.BuildParameters, waitF
 				}
 			}
 			// mark flow as failed if we had a build failure or error
-			flowFailed = flowFailed || (status != "SUCCESS")
+			flowFailed = flowFailed || !status.IsSuccessful()
 
 			resultsChan <- types.BuildResult{
 				BuildName:           triggerResponse.BuildType.Name,
 				WebURL:              triggerResponse.WebURL,
 				BranchName:          p.BranchName,
 				BuildStatus:         status,
+				Duration:            duration,
 				DownloadedArtifacts: downloadedArtifacts,
 				Error:               err,
+				Constituents:        constituents,
 			}
 		}(param)
 	}
@@ -144,7 +234,15 @@ func triggerBuilds(c *teamcity.Client, parameters []types.BuildParameters, waitF
 		results = append(results, result)
 	}
 
-	resultsTable(results)
+	report.ResultsTable(os.Stdout, results)
+
+	if exportParams.Enabled() && waitForBuilds && ctx.Err() == nil {
+		err := params.ExportProperties(exportParams, exportedProperties)
+		if err != nil {
+			log.Errorf("error exporting resulting parameters: %s", err)
+			return err
+		}
+	}
 
 	if flowFailed {
 		log.Error("one or more builds failed, more info in table")
@@ -157,14 +255,14 @@ func triggerBuilds(c *teamcity.Client, parameters []types.BuildParameters, waitF
 	return nil
 }
 
-// handleArtifacts handles the artifacts logic for a build, downloading and unzipping them if needed.
+// handleArtifacts handles the artifacts logic for a build, downloading the artifacts selected by the options if needed.
 // Returns true if artifacts were downloaded, false otherwise.
-func handleArtifacts(c *teamcity.Client, buildID int, buildTypeID, buildTypeName, artifactsPath string, requireArtifacts bool) (bool, error) {
+func handleArtifacts(c *teamcity.Client, buildID int, buildTypeID, buildTypeName, artifactsPath string, requireArtifacts bool, artifactOptions teamcity.ArtifactOptions) (bool, error) {
 	// if we have artifacts, download them
 	if c.Artifacts.BuildHasArtifact(buildID) {
 		log.Infof("downloading Artifacts for %s", buildTypeName)
 
-		err := c.Artifacts.DownloadAndUnzipArtifacts(buildID, buildTypeID, artifactsPath)
+		err := teamcity.DownloadBuildArtifacts(c, buildID, buildTypeID, artifactsPath, artifactOptions)
 		if err != nil {
 			log.Errorf("error downloading artifacts for build %s: %s", buildTypeName, err.Error())
 			return false, fmt.Errorf("error downloading artifacts: %w", err)
@@ -179,3 +277,27 @@ func handleArtifacts(c *teamcity.Client, buildID int, buildTypeID, buildTypeName
 
 	return false, nil
 }
+
+// handleConstituents collects the results of the constituent builds of a composite build,
+// downloading their artifacts to per-build sub-directories of artifactsPath if needed.
+// Returns true if artifacts of any constituent build were downloaded.
+func handleConstituents(c *teamcity.Client, buildID int, buildTypeName, artifactsPath string, downloadArtifacts, requireArtifacts bool, artifactOptions teamcity.ArtifactOptions) ([]types.BuildResult, bool, error) {
+	constituents, err := teamcity.ConstituentResults(c, buildID, artifactsPath, downloadArtifacts, artifactOptions)
+
+	downloadedArtifacts := false
+	for _, constituent := range constituents {
+		downloadedArtifacts = downloadedArtifacts || constituent.DownloadedArtifacts
+	}
+
+	if err != nil {
+		return constituents, downloadedArtifacts, err
+	}
+
+	// if we require artifacts and none of the constituent builds produced any, fail the build
+	if downloadArtifacts && requireArtifacts && !downloadedArtifacts {
+		log.Errorf("did not get artifacts for any constituent build of %s, and requireArtifacts is true", buildTypeName)
+		return constituents, false, errors.New("composite build requires artifacts and none of its constituent builds produced any")
+	}
+
+	return constituents, downloadedArtifacts, nil
+}
@@ -1,11 +1,17 @@
 package multitrigger
 
 import (
+	"bbox/pkg/interrupt"
+	"bbox/pkg/params"
 	"bbox/pkg/types"
 	"bbox/pkg/utils/testutils"
 	"bbox/teamcity"
+	"context"
+	"encoding/json"
 	"errors"
 	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -302,6 +308,39 @@ func TestTriggerBuilds(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:               "canceled build is reported as canceled",
+			waitForBuilds:      true,
+			waitTimeout:        30 * time.Second,
+			multiArtifactsPath: "artifacts/",
+			requireArtifacts:   false,
+			expectedResults:    []types.BuildResult{},
+			exitError:          errors.New("build canceledBuild was canceled"),
+			buildsTriggered: []buildTestCase{
+				{
+					parameters: types.BuildParameters{
+						BuildTypeID:       "bt123",
+						BranchName:        "master",
+						PropertiesFlag:    map[string]string{"key": "value"},
+						DownloadArtifacts: false,
+					},
+					triggerBuildResponse: types.TriggerBuildWithParametersResponse{
+						BuildTypeID: "bt123",
+						WebURL:      "https://teamcity-example.com/",
+						ID:          123,
+						BuildType: types.BuildType{
+							Name: "canceledBuild",
+						},
+					},
+					waitForBuildResponse: types.BuildStatusResponse{
+						ID:           123,
+						Status:       types.BuildStatusUnknown,
+						State:        types.BuildStateFinished,
+						CanceledInfo: &types.CanceledInfo{Text: "not needed anymore"},
+					},
+				},
+			},
+		},
 	}
 
 	for _, tc := range newTests {
@@ -326,13 +365,14 @@ func TestTriggerBuilds(t *testing.T) {
 					mockArtifactsService.On("BuildHasArtifact", build.triggerBuildResponse.ID).Return(build.buildHasArtifactsResponse)
 					mockArtifactsService.On("GetArtifactChildren", build.triggerBuildResponse.ID).Return(build.getArtifactChildrenResponse, build.getArtifactChildrenError)
 					if build.buildHasArtifactsResponse {
-						mockArtifactsService.On("DownloadAndUnzipArtifacts", build.triggerBuildResponse.ID, build.parameters.BuildTypeID, tc.multiArtifactsPath).Return(build.downloadError)
+						mockArtifactsService.On("ListArtifacts", build.triggerBuildResponse.ID).Return([]types.ArtifactFile{}, nil)
+						mockArtifactsService.On("DownloadAndUnzipArtifacts", build.triggerBuildResponse.ID, build.parameters.BuildTypeID, tc.multiArtifactsPath).Return(nil, build.downloadError)
 						mockArtifactsService.On("GetAllBuildTypeArtifacts", build.triggerBuildResponse.ID, build.parameters.BuildTypeID).Return(build.getAllBuildTypeArtifactsResponse, build.getAllBuildTypeArtifactsError)
 					}
 				}
 			}
 
-			err := triggerBuilds(client, parameters, tc.waitForBuilds, tc.waitTimeout, tc.multiArtifactsPath, tc.requireArtifacts)
+			err := triggerBuilds(context.Background(), client, interrupt.NewTracker(), parameters, tc.waitForBuilds, tc.waitTimeout, artifactsLayout{root: tc.multiArtifactsPath}, tc.requireArtifacts, params.ExportOptions{}, teamcity.ArtifactOptions{})
 
 			if tc.exitError != nil {
 				assert.EqualError(t, err, tc.exitError.Error())
@@ -347,3 +387,69 @@ func TestTriggerBuilds(t *testing.T) {
 
 	}
 }
+
+func TestTriggerBuildsExportsParamsOfRepeatedBuildTypeByBuildID(t *testing.T) {
+	mockBuildService := new(testutils.MockBuildService)
+	client := &teamcity.Client{
+		Build:     mockBuildService,
+		Artifacts: new(testutils.MockArtifactsService),
+	}
+
+	parameters := []types.BuildParameters{
+		{BuildTypeID: "bt1", BranchName: "main"},
+		{BuildTypeID: "bt1", BranchName: "release"},
+		{BuildTypeID: "bt2", BranchName: "main"},
+	}
+
+	for i, p := range parameters {
+		buildID := 100 + i
+		name := p.BuildTypeID + "-" + p.BranchName
+
+		mockBuildService.On("TriggerBuild", p.BuildTypeID, p.BranchName, p.PropertiesFlag).Return(types.TriggerBuildWithParametersResponse{ID: buildID, BuildType: types.BuildType{Name: name}}, nil)
+		mockBuildService.On("WaitForBuild", name, buildID, time.Minute).Return(types.BuildStatusResponse{ID: buildID, Status: "SUCCESS", State: "finished"}, nil)
+		mockBuildService.On("GetResultingProperties", buildID, []string{"env.*"}).Return(map[string]string{"env.TAG": p.BranchName}, nil)
+		mockBuildService.On("GetBuildStatus", buildID).Return(types.BuildStatusResponse{}, nil)
+	}
+
+	exportPath := filepath.Join(t.TempDir(), "params.json")
+	exportParams := params.ExportOptions{Path: exportPath, Filters: []string{"env.*"}}
+
+	err := triggerBuilds(context.Background(), client, interrupt.NewTracker(), parameters, true, time.Minute, artifactsLayout{root: t.TempDir()}, false, exportParams, teamcity.ArtifactOptions{})
+	assert.NoError(t, err)
+
+	content, err := os.ReadFile(exportPath)
+	assert.NoError(t, err)
+
+	var exported map[string]string
+	assert.NoError(t, json.Unmarshal(content, &exported))
+	assert.Equal(t, map[string]string{
+		"bt1.100.env.TAG": "main",
+		"bt1.101.env.TAG": "release",
+		"bt2.env.TAG":     "main",
+	}, exported)
+}
+
+func TestTriggerBuildsSkipsConstituentArtifactsOfFailedCompositeBuild(t *testing.T) {
+	mockBuildService := new(testutils.MockBuildService)
+	mockArtifactsService := new(testutils.MockArtifactsService)
+	client := &teamcity.Client{
+		Build:     mockBuildService,
+		Artifacts: mockArtifactsService,
+	}
+
+	p := types.BuildParameters{BuildTypeID: "composite", BranchName: "main", DownloadArtifacts: true}
+
+	mockBuildService.On("TriggerBuild", p.BuildTypeID, p.BranchName, p.PropertiesFlag).Return(types.TriggerBuildWithParametersResponse{ID: 10, BuildType: types.BuildType{Name: "composite"}}, nil)
+	mockBuildService.On("WaitForBuild", "composite", 10, time.Minute).Return(types.BuildStatusResponse{ID: 10, Status: "FAILURE", State: "finished", Composite: true}, nil)
+	mockBuildService.On("GetBuildStatus", 10).Return(types.BuildStatusResponse{}, nil)
+	mockBuildService.On("GetConstituentBuilds", 10).Return([]types.BuildStatusResponse{
+		{ID: 11, BuildTypeID: "backend", Status: "SUCCESS", State: "finished"},
+		{ID: 12, BuildTypeID: "frontend", Status: "FAILURE", State: "finished"},
+	}, nil)
+
+	err := triggerBuilds(context.Background(), client, interrupt.NewTracker(), []types.BuildParameters{p}, true, time.Minute, artifactsLayout{root: t.TempDir()}, false, params.ExportOptions{}, teamcity.ArtifactOptions{})
+	assert.EqualError(t, err, "Build status is not SUCCESS: (status: FAILURE)")
+
+	mockBuildService.AssertExpectations(t)
+	mockArtifactsService.AssertNotCalled(t, "BuildHasArtifact", 11)
+}
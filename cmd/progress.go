@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"bbox/pkg/models"
+	"bbox/pkg/types"
+	"bbox/pkg/utils"
+	"bbox/teamcity"
+
+	tea "github.com/charmbracelet/bubbletea"
+	log "github.com/sirupsen/logrus"
+)
+
+var errWaitInterrupted = errors.New("stopped waiting for the build")
+
+// waitForBuildWithProgress waits for a build to finish, rendering its live progress when stdout is a terminal and logging it otherwise.
+// Waiting stops when ctx is done, or when ctrl+c is pressed while the progress is rendered.
+func waitForBuildWithProgress(ctx context.Context, client *teamcity.Client, buildName string, buildID int, timeout time.Duration) (types.BuildStatusResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if !utils.IsTerminal(os.Stdout) {
+		return client.Build.WaitForBuild(buildName, buildID, timeout, teamcity.WithContext(ctx), teamcity.WithProgress(func(status types.BuildStatusResponse) {
+			logProgress(buildName, status, queuePosition(client, status))
+		}))
+	}
+
+	program := tea.NewProgram(models.NewBuildProgressModel(buildName))
+
+	go func() {
+		status, err := client.Build.WaitForBuild(buildName, buildID, timeout, teamcity.WithContext(ctx), teamcity.WithProgress(func(status types.BuildStatusResponse) {
+			program.Send(models.BuildProgressMsg{Status: status, QueuePosition: queuePosition(client, status)})
+		}))
+
+		program.Send(models.BuildFinishedMsg{Status: status, Err: err})
+	}()
+
+	finalModel, err := program.Run()
+	if err != nil {
+		return types.BuildStatusResponse{}, fmt.Errorf("error while rendering build progress: %w", err)
+	}
+
+	progress := finalModel.(models.BuildProgressModel)
+	if !progress.IsFinished() {
+		return progress.Status, errWaitInterrupted
+	}
+
+	return progress.Status, progress.Err
+}
+
+// queuePosition returns the position of a queued build in the build queue, or 0 if it is not queued or the queue is unavailable.
+func queuePosition(client *teamcity.Client, status types.BuildStatusResponse) int {
+	if status.State != types.BuildStateQueued {
+		return 0
+	}
+
+	position, err := client.Queue.GetQueuePosition(status.ID)
+	if err != nil {
+		log.Debugf("error getting queue position of build %d: %s", status.ID, err)
+		return 0
+	}
+
+	return position
+}
+
+func logProgress(buildName string, status types.BuildStatusResponse, queuePosition int) {
+	fields := log.Fields{"state": status.State}
+
+	switch status.State {
+	case types.BuildStateQueued:
+		if queuePosition > 0 {
+			fields["queuePosition"] = queuePosition
+		}
+
+		if status.WaitReason != "" {
+			fields["waitReason"] = status.WaitReason
+		}
+	case types.BuildStateRunning:
+		fields["percentageComplete"] = status.PercentageComplete
+
+		if info := status.RunningInfo; info != nil {
+			fields["stage"] = info.CurrentStageText
+			fields["elapsed"] = (time.Duration(info.ElapsedSeconds) * time.Second).String()
+
+			if info.EstimatedTotalSeconds > 0 {
+				fields["estimated"] = (time.Duration(info.EstimatedTotalSeconds) * time.Second).String()
+			}
+		}
+	default:
+		return
+	}
+
+	log.WithFields(fields).Infof("build %s is %s", buildName, status.State)
+}
@@ -6,7 +6,12 @@ package cmd
 import (
 	"os"
 
+	"bbox/cmd/artifacts"
+	"bbox/cmd/builds"
+	"bbox/cmd/changes"
 	"bbox/cmd/clean"
+	"bbox/cmd/gate"
+	"bbox/cmd/graph"
 	"bbox/cmd/multitrigger"
 	"bbox/logger"
 	"github.com/spf13/cobra"
@@ -50,6 +55,11 @@ func init() {
 	RootCmd.MarkFlagsRequiredTogether("teamcity-username", "teamcity-password")
 	RootCmd.AddCommand(clean.Cmd)
 	RootCmd.AddCommand(multitrigger.Cmd)
+	RootCmd.AddCommand(builds.Cmd)
+	RootCmd.AddCommand(gate.Cmd)
+	RootCmd.AddCommand(graph.Cmd)
+	RootCmd.AddCommand(changes.Cmd)
+	RootCmd.AddCommand(artifacts.Cmd)
 }
 
 func initCmd() {
@@ -1,10 +1,18 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"net/url"
 	"os"
 	"time"
 
+	"bbox/pkg/flags"
+	"bbox/pkg/interrupt"
+	"bbox/pkg/params"
+	"bbox/pkg/report"
+	"bbox/pkg/types"
+	"bbox/pkg/utils"
 	"bbox/teamcity"
 
 	log "github.com/sirupsen/logrus"
@@ -18,17 +26,25 @@ var (
 
 var (
 	buildTypeID         string
+	buildTypeQuery      string
 	propertiesFlag      map[string]string
+	propertiesFiles     []string
+	propertiesEnvPrefix string
+	strictParams        bool
+	exportParams        params.ExportOptions
+	artifactFlags       *flags.ArtifactFlags
 	downloadArtifacts   bool
 	waitForBuild        bool
 	waitForBuildTimeout = 15 * time.Minute
 	requireArtifacts    bool
+	cancelOnInterrupt   bool
 )
 
 var triggerCmd = &cobra.Command{
 	Use:   "trigger",
 	Short: "Trigger a single TeamCity Build",
-	Long:  `Trigger a single TeamCity Build`,
+	Long: `Trigger a single TeamCity Build.
+When no Build Type is given and bbox runs in a terminal, an interactive flow lets you pick the build configuration, branch and parameters, and then triggers the build and waits for it.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		url, err := url.Parse(TeamcityURL)
 		if err != nil {
@@ -42,7 +58,66 @@ var triggerCmd = &cobra.Command{
 			log.Errorf("error initializing TeamCity Client: %s", err)
 			os.Exit(2)
 		}
-		trigger(client, buildTypeID, branchName, artifactsPath, propertiesFlag, requireArtifacts, waitForBuild, downloadArtifacts, waitForBuildTimeout)
+		propertiesFlag, err = params.LoadProperties(propertiesFiles, propertiesEnvPrefix, propertiesFlag)
+		if err != nil {
+			log.Errorf("error loading properties: %s", err)
+			os.Exit(2)
+		}
+
+		if buildTypeQuery != "" {
+			buildTypeID, err = client.BuildType.ResolveBuildTypeID(buildTypeQuery)
+			if err != nil {
+				log.Errorf("error resolving build type: %s", err)
+				os.Exit(2)
+			}
+		}
+
+		if buildTypeID == "" {
+			if !utils.IsInteractiveTerminal() {
+				log.Error("one of --build-type-id or --build-type is required")
+				os.Exit(2)
+			}
+
+			selection, err := interactiveTrigger(client, propertiesFlag)
+			if errors.Is(err, errInteractiveCanceled) {
+				log.Info("trigger canceled by the user.")
+				return
+			}
+
+			if err != nil {
+				log.Errorf("error selecting a build to trigger: %s", err)
+				os.Exit(2)
+			}
+
+			buildTypeID, branchName, propertiesFlag = selection.BuildType.ID, selection.BranchName, selection.Properties
+			waitForBuild = true
+		}
+
+		err = client.BuildType.ValidateProperties(buildTypeID, propertiesFlag, strictParams)
+		if err != nil {
+			log.Errorf("error validating properties: %s", err)
+			os.Exit(2)
+		}
+
+		artifactOptions, err := artifactFlags.Options()
+		if err != nil {
+			log.Error(err)
+			os.Exit(2)
+		}
+
+		artifactOptions.RequireMatch = requireArtifacts
+
+		err = exportParams.Validate()
+		if err != nil {
+			log.Errorf("invalid --export-params options: %s", err)
+			os.Exit(2)
+		}
+
+		if exportParams.Enabled() && !waitForBuild {
+			log.Warn("--export-params requires --wait-for-build, the resulting parameters will not be exported")
+		}
+
+		trigger(client, buildTypeID, branchName, artifactsPath, propertiesFlag, requireArtifacts, waitForBuild, downloadArtifacts, waitForBuildTimeout, exportParams, artifactOptions, cancelOnInterrupt)
 	},
 }
 
@@ -50,16 +125,31 @@ func init() {
 	RootCmd.AddCommand(triggerCmd)
 
 	triggerCmd.PersistentFlags().StringVarP(&buildTypeID, "build-type-id", "i", "", "The Build Type")
+	triggerCmd.PersistentFlags().StringVar(&buildTypeQuery, "build-type", "", "The Build Type path ('Project / Sub / Build name') or a fuzzy name query, resolved to a Build Type ID")
 	triggerCmd.PersistentFlags().StringVar(&artifactsPath, "artifacts-path", artifactsPath, "Path to download Artifacts to")
 	triggerCmd.PersistentFlags().BoolVarP(&waitForBuild, "wait-for-build", "w", waitForBuild, "Wait for build to finish and get status")
 	triggerCmd.PersistentFlags().DurationVarP(&waitForBuildTimeout, "wait-timeout", "t", waitForBuildTimeout, "Timeout for waiting for build to finish")
 	triggerCmd.PersistentFlags().BoolVarP(&downloadArtifacts, "download-artifacts", "d", downloadArtifacts, "Download Artifacts")
 	triggerCmd.PersistentFlags().StringVarP(&branchName, "branch-name", "b", branchName, "The Branch Name")
 	triggerCmd.PersistentFlags().StringToStringVarP(&propertiesFlag, "properties", "p", nil, "The properties in key=value format")
+	triggerCmd.PersistentFlags().StringSliceVar(&propertiesFiles, "properties-file", nil, "Load properties from a .env, JSON or YAML file. Repeatable, later files override earlier ones")
+	triggerCmd.PersistentFlags().StringVar(&propertiesEnvPrefix, "properties-from-env", "", "Load properties from environment variables starting with this prefix, e.g. PREFIX_env__TAG sets env.TAG")
+	artifactFlags = flags.AddArtifactFlags(triggerCmd.PersistentFlags())
 	triggerCmd.PersistentFlags().BoolVar(&requireArtifacts, "require-artifacts", false, "If downloadArtifacts is true, and no artifacts found, return an error")
+	triggerCmd.PersistentFlags().BoolVar(&strictParams, "strict-params", false, "Fail on properties that are not declared on the Build Type instead of warning")
+	triggerCmd.PersistentFlags().StringVar(&exportParams.Path, "export-params", "", "Export the resulting parameters of the finished build to this file")
+	triggerCmd.PersistentFlags().StringSliceVar(&exportParams.Filters, "export-params-filter", nil, "Only export the resulting parameters matching these glob patterns, e.g. 'env.*'. Repeatable")
+	triggerCmd.PersistentFlags().StringVar(&exportParams.Format, "export-params-format", "", "Format of the exported parameters: dotenv, json or github. Inferred from the file if not set")
+	triggerCmd.PersistentFlags().BoolVar(&cancelOnInterrupt, "cancel-on-interrupt", false, "Cancel the triggered build without prompting when interrupted with ctrl+c while waiting for it")
+	triggerCmd.MarkFlagsMutuallyExclusive("build-type-id", "build-type")
 }
 
-func trigger(client *teamcity.Client, buildTypeID, branchName, artifactsPath string, propertiesFlag map[string]string, requireArtifacts, waitForBuild, downloadArtifacts bool, waitForBuildTimeout time.Duration) {
+func trigger(client *teamcity.Client, buildTypeID, branchName, artifactsPath string, propertiesFlag map[string]string, requireArtifacts, waitForBuild, downloadArtifacts bool, waitForBuildTimeout time.Duration, exportParams params.ExportOptions, artifactOptions teamcity.ArtifactOptions, cancelOnInterrupt bool) {
+	ctx, stop := interrupt.NotifyContext()
+	defer stop()
+
+	tracker := interrupt.NewTracker()
+
 	log.WithFields(log.Fields{
 		"TeamcityURL":       TeamcityURL,
 		"branchName":        branchName,
@@ -81,48 +171,126 @@ func trigger(client *teamcity.Client, buildTypeID, branchName, artifactsPath str
 		"webURL":    triggerResponse.WebURL,
 	}).Info("build Triggered")
 
+	tracker.Track(interrupt.TriggeredBuild{ID: triggerResponse.ID, Name: triggerResponse.BuildType.Name, WebURL: triggerResponse.WebURL})
+
 	downloadedArtifacts := false
-	status := "UNKNOWN"
+	status := types.BuildStatusUnknown
 	if waitForBuild {
 		log.Infof("waiting for build %s", triggerResponse.BuildType.Name)
 
-		build, err := client.Build.WaitForBuild(triggerResponse.BuildType.Name, triggerResponse.ID, waitForBuildTimeout)
+		build, err := waitForBuildWithProgress(ctx, client, triggerResponse.BuildType.Name, triggerResponse.ID, waitForBuildTimeout)
+		if errors.Is(err, errWaitInterrupted) || errors.Is(err, context.Canceled) {
+			// ctrl+c in the progress view is a key press rather than a signal, restore the default signal behavior so a second one exits right away
+			stop()
+			interrupt.Handle(client, tracker, cancelOnInterrupt, os.Stdout)
+			os.Exit(interrupt.ExitCode)
+		}
+
 		if err != nil {
 			log.Error("error waiting for build: ", err)
 			os.Exit(2)
 		}
 
-		status = build.Status
+		status = build.Result()
+		downloadedArtifacts = finishBuild(client, build, triggerResponse.BuildType.Name, buildTypeID, artifactsPath, downloadArtifacts, requireArtifacts, exportParams, artifactOptions)
+	}
+	log.WithFields(log.Fields{
+		"BuildName":           triggerResponse.BuildType.Name,
+		"WebURL":              triggerResponse.BuildType.WebURL,
+		"BranchName":          branchName,
+		"BuildStatus":         status,
+		"DownloadedArtifacts": downloadedArtifacts,
+		"Error":               err,
+	}).Info("Done triggering build")
+}
 
-		log.WithFields(log.Fields{
-			"buildStatus": status,
-			"buildState":  build.State,
-		}).Infof("Build %s Finished", triggerResponse.BuildType.Name)
+// finishBuild reports a finished build, exports its resulting parameters and downloads its artifacts selected by the options if requested.
+// It exits with code 2 on errors, including artifacts failing the checksum verification, and returns true if artifacts were downloaded.
+func finishBuild(client *teamcity.Client, build types.BuildStatusResponse, buildName, buildTypeID, artifactsPath string, downloadArtifacts, requireArtifacts bool, exportParams params.ExportOptions, artifactOptions teamcity.ArtifactOptions) bool {
+	status := build.Result()
+	downloadedArtifacts := false
 
-		if downloadArtifacts && status == "SUCCESS" {
-			artifactsExist := client.Artifacts.BuildHasArtifact(build.ID)
+	log.WithFields(log.Fields{
+		"buildStatus": status,
+		"buildState":  build.State,
+	}).Infof("Build %s Finished", buildName)
 
-			if requireArtifacts && !artifactsExist {
-				log.Errorf("did not get artifacts for build %s, and requireArtifacts is true", triggerResponse.BuildType.Name)
-				os.Exit(2)
+	if status.WasCanceled() && build.CanceledInfo != nil {
+		log.Warnf("build %s was canceled by %s: %s", buildName, build.CanceledInfo.User.Username, build.CanceledInfo.Text)
+	}
+
+	if exportParams.Enabled() {
+		err := exportResultingProperties(client, build.ID, exportParams)
+		if err != nil {
+			log.Errorf("error exporting parameters of build %s: %s", buildName, err)
+			os.Exit(2)
+		}
+	}
+
+	if build.Composite {
+		// the artifacts of the constituent builds are only downloaded if the composite build succeeded, like the ones of a plain build
+		downloadConstituentArtifacts := downloadArtifacts && status.IsSuccessful()
+
+		constituents, err := teamcity.ConstituentResults(client, build.ID, artifactsPath, downloadConstituentArtifacts, artifactOptions)
+
+		for _, constituent := range constituents {
+			downloadedArtifacts = downloadedArtifacts || constituent.DownloadedArtifacts
+		}
+
+		report.ResultsTable(os.Stdout, constituents)
+
+		if err != nil {
+			log.Errorf("error handling constituent builds of %s: %s", buildName, err)
+
+			if errors.Is(err, teamcity.ErrChecksumMismatch) {
+				log.Errorf("artifacts of build %s could not be verified against %s", buildName, artifactOptions.VerifyChecksums)
 			}
 
-			if artifactsExist {
-				log.Infof("downloading Artifacts for %s", triggerResponse.BuildType.Name)
-				err = client.Artifacts.DownloadAndUnzipArtifacts(build.ID, buildTypeID, artifactsPath)
-				if err != nil {
-					log.Errorf("error downloading artifacts for build %s: %s", triggerResponse.BuildType.Name, err.Error())
+			os.Exit(2)
+		}
+
+		if downloadConstituentArtifacts && requireArtifacts && !downloadedArtifacts {
+			log.Errorf("did not get artifacts for any constituent build of %s, and requireArtifacts is true", buildName)
+			os.Exit(2)
+		}
+	} else if downloadArtifacts && status.IsSuccessful() {
+		artifactsExist := client.Artifacts.BuildHasArtifact(build.ID)
+
+		if requireArtifacts && !artifactsExist {
+			log.Errorf("did not get artifacts for build %s, and requireArtifacts is true", buildName)
+			os.Exit(2)
+		}
+
+		if artifactsExist {
+			log.Infof("downloading Artifacts for %s", buildName)
+			err := teamcity.DownloadBuildArtifacts(client, build.ID, buildTypeID, artifactsPath, artifactOptions)
+			if err != nil {
+				log.Errorf("error downloading artifacts for build %s: %s", buildName, err.Error())
+
+				// unverified, unexpectedly large or required but unmatched artifacts must not be used by the rest of the pipeline
+				if artifactOptions.VerifyChecksums != "" || errors.Is(err, teamcity.ErrArtifactsTooLarge) || errors.Is(err, teamcity.ErrInsufficientDiskSpace) || errors.Is(err, teamcity.ErrNoArtifactsMatch) {
+					os.Exit(2)
 				}
-				downloadedArtifacts = err == nil
 			}
+			downloadedArtifacts = err == nil
 		}
 	}
+
+	return downloadedArtifacts
+}
+
+// exportResultingProperties exports the filtered resulting properties of a finished build.
+func exportResultingProperties(client *teamcity.Client, buildID int, exportParams params.ExportOptions) error {
+	properties, err := client.Build.GetResultingProperties(buildID, exportParams.Filters...)
+	if err != nil {
+		return err
+	}
+
 	log.WithFields(log.Fields{
-		"BuildName":           triggerResponse.BuildType.Name,
-		"WebURL":              triggerResponse.BuildType.WebURL,
-		"BranchName":          branchName,
-		"BuildStatus":         status,
-		"DownloadedArtifacts": downloadedArtifacts,
-		"Error":               err,
-	}).Info("Done triggering build")
+		"buildID":    buildID,
+		"parameters": len(properties),
+		"path":       exportParams.Path,
+	}).Info("exporting resulting parameters")
+
+	return params.ExportProperties(exportParams, properties)
 }
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"bbox/pkg/params"
 	"bbox/pkg/types"
 	"bbox/pkg/utils/testutils"
 	"bbox/teamcity"
@@ -114,12 +115,13 @@ func TestTrigger(t *testing.T) {
 
 			if tt.waitForBuild && tt.downloadArtifacts {
 				mockArtifacts.On("BuildHasArtifact", tt.triggerBuildResponse.ID).Return(tt.buildHasArtifactsResponse)
-				mockArtifacts.On("DownloadAndUnzipArtifacts", tt.triggerBuildResponse.ID, tt.buildTypeID, tt.artifactsPath).Return(tt.downloadAndUnzipArtifactsErr)
+				mockArtifacts.On("ListArtifacts", tt.triggerBuildResponse.ID).Return([]types.ArtifactFile{}, nil)
+				mockArtifacts.On("DownloadAndUnzipArtifacts", tt.triggerBuildResponse.ID, tt.buildTypeID, tt.artifactsPath).Return(nil, tt.downloadAndUnzipArtifactsErr)
 				mockArtifacts.On("GetAllBuildTypeArtifacts", tt.triggerBuildResponse.ID, tt.buildTypeID).Return(tt.getAllBuildTypeArtifactsResponse, tt.getAllBuildTypeArtifactsError)
 				mockArtifacts.On("GetArtifactChildren", tt.triggerBuildResponse.ID).Return(tt.getArtifactChildrenResponse, tt.getArtifactChildrenError)
 			}
 
-			trigger(client, tt.buildTypeID, tt.branchName, tt.artifactsPath, tt.properties, tt.requireArtifacts, tt.waitForBuild, tt.downloadArtifacts, tt.waitForBuildTimeout)
+			trigger(client, tt.buildTypeID, tt.branchName, tt.artifactsPath, tt.properties, tt.requireArtifacts, tt.waitForBuild, tt.downloadArtifacts, tt.waitForBuildTimeout, params.ExportOptions{}, teamcity.ArtifactOptions{}, false)
 
 			mockBuild.AssertExpectations(t)
 			mockArtifacts.AssertExpectations(t)
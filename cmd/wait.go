@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"bbox/pkg/flags"
+	"bbox/pkg/interrupt"
+	"bbox/pkg/params"
+	"bbox/pkg/types"
+	"bbox/teamcity"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	waitBuildID           int
+	waitBuildTypeID       string
+	waitBranchName        string
+	waitRevision          string
+	waitArtifactsPath     = "./"
+	waitDownloadArtifacts bool
+	waitRequireArtifacts  bool
+	waitTimeout           = 15 * time.Minute
+	waitExportParams      params.ExportOptions
+	waitArtifactFlags     *flags.ArtifactFlags
+)
+
+var waitCmd = &cobra.Command{
+	Use:   "wait",
+	Short: "Wait for a TeamCity Build that bbox did not trigger",
+	Long: `Wait for a TeamCity Build that bbox did not trigger, e.g. one started by a VCS trigger or by another job.
+The build is given by its ID, or found as the running or queued build of a Build Type, optionally on a branch and revision.`,
+	Example: `  # wait for the build of a commit and download its artifacts
+  bbox wait --build-type-id Backend_Build --branch main --revision 0a1b2c3 --download-artifacts`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if waitBuildID == 0 && waitBuildTypeID == "" {
+			log.Error("one of --build-id or --build-type-id is required")
+			os.Exit(2)
+		}
+
+		artifactOptions, err := waitArtifactFlags.Options()
+		if err != nil {
+			log.Error(err)
+			os.Exit(2)
+		}
+
+		artifactOptions.RequireMatch = waitRequireArtifacts
+
+		err = waitExportParams.Validate()
+		if err != nil {
+			log.Errorf("invalid --export-params options: %s", err)
+			os.Exit(2)
+		}
+
+		url, err := url.Parse(TeamcityURL)
+		if err != nil {
+			log.Errorf("error parsing TeamCity URL: %s", err)
+			os.Exit(2)
+		}
+
+		client, err := teamcity.NewTeamCityClient(url, TeamcityUsername, TeamcityPassword)
+		if err != nil {
+			log.Errorf("error initializing TeamCity Client: %s", err)
+			os.Exit(2)
+		}
+
+		buildID, buildName, buildTypeID := waitBuildID, strconv.Itoa(waitBuildID), waitBuildTypeID
+
+		if buildID == 0 {
+			build, err := findBuildToWait(client, waitBuildTypeID, waitBranchName, waitRevision)
+			if err != nil {
+				log.Errorf("error finding the build to wait for: %s", err)
+				os.Exit(2)
+			}
+
+			buildID, buildName = build.ID, build.BuildType.Name
+
+			log.WithFields(log.Fields{
+				"buildID": build.ID,
+				"state":   build.State,
+				"webURL":  build.WebURL,
+			}).Infof("found build %s #%s", buildName, build.Number)
+		} else {
+			build, err := client.Build.GetBuildStatus(buildID)
+			if err != nil {
+				log.Errorf("error getting build %d: %s", buildID, err)
+				os.Exit(2)
+			}
+
+			if build.BuildType.Name != "" {
+				buildName = build.BuildType.Name
+			}
+
+			buildTypeID = build.BuildTypeID
+		}
+
+		ctx, stop := interrupt.NotifyContext()
+		defer stop()
+
+		log.Infof("waiting for build %s", buildName)
+
+		build, err := waitForBuildWithProgress(ctx, client, buildName, buildID, waitTimeout)
+		if errors.Is(err, errWaitInterrupted) || errors.Is(err, context.Canceled) {
+			log.Warnf("stopped waiting for build %s, it keeps running in TeamCity", buildName)
+			os.Exit(interrupt.ExitCode)
+		}
+
+		if err != nil {
+			log.Error("error waiting for build: ", err)
+			os.Exit(2)
+		}
+
+		downloadedArtifacts := finishBuild(client, build, buildName, buildTypeID, waitArtifactsPath, waitDownloadArtifacts, waitRequireArtifacts, waitExportParams, artifactOptions)
+
+		log.WithFields(log.Fields{
+			"BuildName":           buildName,
+			"WebURL":              build.WebURL,
+			"BuildStatus":         build.Result(),
+			"DownloadedArtifacts": downloadedArtifacts,
+		}).Info("Done waiting for build")
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(waitCmd)
+
+	waitCmd.Flags().IntVar(&waitBuildID, "build-id", 0, "The ID of the build to wait for")
+	waitCmd.Flags().StringVarP(&waitBuildTypeID, "build-type-id", "i", "", "Wait for the running or queued build of this Build Type")
+	waitCmd.Flags().StringVarP(&waitBranchName, "branch", "b", "", "Only wait for a build of this branch, builds of all branches are matched if empty")
+	waitCmd.Flags().StringVar(&waitRevision, "revision", "", "Only wait for a build of this VCS revision, e.g. a commit SHA")
+	waitCmd.Flags().DurationVarP(&waitTimeout, "wait-timeout", "t", waitTimeout, "Timeout for waiting for build to finish")
+	waitCmd.Flags().StringVar(&waitArtifactsPath, "artifacts-path", waitArtifactsPath, "Path to download Artifacts to")
+	waitCmd.Flags().BoolVarP(&waitDownloadArtifacts, "download-artifacts", "d", false, "Download Artifacts")
+	waitArtifactFlags = flags.AddArtifactFlags(waitCmd.Flags())
+	waitCmd.Flags().BoolVar(&waitRequireArtifacts, "require-artifacts", false, "If downloadArtifacts is true, and no artifacts found, return an error")
+	waitCmd.Flags().StringVar(&waitExportParams.Path, "export-params", "", "Export the resulting parameters of the finished build to this file")
+	waitCmd.Flags().StringSliceVar(&waitExportParams.Filters, "export-params-filter", nil, "Only export the resulting parameters matching these glob patterns, e.g. 'env.*'. Repeatable")
+	waitCmd.Flags().StringVar(&waitExportParams.Format, "export-params-format", "", "Format of the exported parameters: dotenv, json or github. Inferred from the file if not set")
+	waitCmd.MarkFlagsMutuallyExclusive("build-id", "build-type-id")
+	waitCmd.MarkFlagsMutuallyExclusive("build-id", "branch")
+	waitCmd.MarkFlagsMutuallyExclusive("build-id", "revision")
+}
+
+// findBuildToWait returns the most recent running build of the Build Type matching the branch and revision, or the most recent queued one if none is running.
+func findBuildToWait(client *teamcity.Client, buildTypeID, branchName, revision string) (types.Build, error) {
+	for _, state := range []types.BuildState{types.BuildStateRunning, types.BuildStateQueued} {
+		builds, err := client.Build.ListBuilds(types.BuildLocator{
+			BuildTypeID: buildTypeID,
+			Branch:      branchName,
+			Revision:    revision,
+			State:       string(state),
+			Count:       1,
+		})
+		if err != nil {
+			return types.Build{}, err
+		}
+
+		if len(builds) > 0 {
+			return builds[0], nil
+		}
+	}
+
+	return types.Build{}, fmt.Errorf("no running or queued build of %s matches the branch %q and revision %q", buildTypeID, branchName, revision)
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"testing"
+
+	"bbox/pkg/types"
+	"bbox/pkg/utils/testutils"
+	"bbox/teamcity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindBuildToWait(t *testing.T) {
+	locator := func(state types.BuildState) types.BuildLocator {
+		return types.BuildLocator{BuildTypeID: "bt123", Branch: "main", Revision: "0a1b2c3", State: string(state), Count: 1}
+	}
+
+	tests := []struct {
+		name          string
+		running       []types.Build
+		queued        []types.Build
+		expectedID    int
+		expectedError string
+	}{
+		{
+			name:       "running build is preferred",
+			running:    []types.Build{{ID: 1, State: types.BuildStateRunning}},
+			expectedID: 1,
+		},
+		{
+			name:       "queued build when none is running",
+			running:    []types.Build{},
+			queued:     []types.Build{{ID: 2, State: types.BuildStateQueued}},
+			expectedID: 2,
+		},
+		{
+			name:          "no matching build",
+			running:       []types.Build{},
+			queued:        []types.Build{},
+			expectedError: "no running or queued build of bt123",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockBuild := new(testutils.MockBuildService)
+			client := &teamcity.Client{Build: mockBuild}
+
+			mockBuild.On("ListBuilds", locator(types.BuildStateRunning)).Return(tt.running, nil)
+			if tt.queued != nil {
+				mockBuild.On("ListBuilds", locator(types.BuildStateQueued)).Return(tt.queued, nil)
+			}
+
+			build, err := findBuildToWait(client, "bt123", "main", "0a1b2c3")
+
+			if tt.expectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedID, build.ID)
+			}
+
+			mockBuild.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,545 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"bbox/pkg/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	artifactsDirName = "artifacts"
+	objectsDirName   = "objects"
+	buildsDirName    = "builds"
+	// completeFileName lists the artifact paths of a build whose artifacts are all cached
+	completeFileName = "complete.json"
+)
+
+// DefaultArtifactsMaxSize is the size the artifact cache is pruned to by default.
+const DefaultArtifactsMaxSize = 10 << 30
+
+// ArtifactCache is a content-addressed cache of downloaded artifact files, shared by the builds of a runner.
+// Files are stored once by their SHA-256 digest, and indexed by TeamCity server, build ID and artifact path.
+// Downloaded files are copied into the cache, and cached files are hardlinked to their destination when possible, and copied otherwise.
+type ArtifactCache struct {
+	dir     string
+	maxSize int64
+	// server is the directory of the index of the builds of a TeamCity server, as build IDs are only unique per server
+	server string
+}
+
+// ArtifactEntry is an artifact file of a build in the cache.
+type ArtifactEntry struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// ArtifactCacheStats is the content of the artifact cache.
+type ArtifactCacheStats struct {
+	Dir string
+	// Builds is the number of builds with cached artifacts, and Files the number of their cached artifact files
+	Builds int
+	Files  int
+	// Objects is the number of distinct files stored, whose total size is Size
+	Objects int
+	Size    int64
+	MaxSize int64
+}
+
+// PruneResult is the outcome of pruning the artifact cache.
+type PruneResult struct {
+	Removed int
+	Freed   int64
+}
+
+// ArtifactsDir returns the default artifact cache directory, in the bbox cache directory.
+func ArtifactsDir() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, artifactsDirName), nil
+}
+
+// OpenArtifactCache opens the artifact cache in dir, creating it if needed.
+// maxSize is the size the cache is pruned to, DefaultArtifactsMaxSize if not positive.
+func OpenArtifactCache(dir string, maxSize int64) (*ArtifactCache, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultArtifactsMaxSize
+	}
+
+	for _, sub := range []string{objectsDirName, buildsDirName} {
+		err := os.MkdirAll(filepath.Join(dir, sub), 0o755)
+		if err != nil {
+			return nil, fmt.Errorf("error creating artifact cache dir: %w", err)
+		}
+	}
+
+	return &ArtifactCache{dir: dir, maxSize: maxSize, server: serverDirName("")}, nil
+}
+
+// ForServer returns the cache of the builds of the TeamCity server at baseURL.
+// The files are shared with the other servers, but the builds are indexed separately.
+func (c *ArtifactCache) ForServer(baseURL string) *ArtifactCache {
+	return &ArtifactCache{dir: c.dir, maxSize: c.maxSize, server: serverDirName(baseURL)}
+}
+
+// serverDirName returns the name of the index directory of the builds of a server, the digest of its base URL.
+func serverDirName(baseURL string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSuffix(baseURL, "/")))
+
+	return hex.EncodeToString(sum[:8])
+}
+
+// Dir returns the directory of the cache.
+func (c *ArtifactCache) Dir() string {
+	return c.dir
+}
+
+// MaxSize returns the size the cache is pruned to.
+func (c *ArtifactCache) MaxSize() int64 {
+	return c.maxSize
+}
+
+func (c *ArtifactCache) objectPath(sum string) string {
+	return filepath.Join(c.dir, objectsDirName, sum[:2], sum)
+}
+
+func (c *ArtifactCache) buildDir(buildID int) string {
+	return filepath.Join(c.dir, buildsDirName, c.server, strconv.Itoa(buildID))
+}
+
+// buildDirs returns the index directories of the cached builds of all servers.
+func (c *ArtifactCache) buildDirs() ([]string, error) {
+	dirs, err := filepath.Glob(filepath.Join(c.dir, buildsDirName, "*", "*"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading artifact cache: %w", err)
+	}
+
+	buildDirs := dirs[:0]
+	for _, dir := range dirs {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			buildDirs = append(buildDirs, dir)
+		}
+	}
+
+	return buildDirs, nil
+}
+
+// entryPath returns the index file of an artifact, named after the digest of its path as artifact paths can be long.
+func (c *ArtifactCache) entryPath(buildID int, artifactPath string) string {
+	sum := sha256.Sum256([]byte(artifactPath))
+
+	return filepath.Join(c.buildDir(buildID), hex.EncodeToString(sum[:])+".json")
+}
+
+// Lookup returns the cache entry of an artifact file of a build, if it is cached.
+func (c *ArtifactCache) Lookup(buildID int, artifactPath string) (ArtifactEntry, bool) {
+	var entry ArtifactEntry
+
+	err := readJSON(c.entryPath(buildID, artifactPath), &entry)
+	if err != nil || entry.Path != artifactPath || len(entry.SHA256) != sha256.Size*2 {
+		return ArtifactEntry{}, false
+	}
+
+	info, err := os.Stat(c.objectPath(entry.SHA256))
+	if err != nil || info.Size() != entry.Size {
+		return ArtifactEntry{}, false
+	}
+
+	return entry, true
+}
+
+// Restore hardlinks or copies a cached artifact file of a build to destFile, and returns false if it is not cached.
+// The cached file is verified against its digest first, and removed from the cache if it was corrupted.
+func (c *ArtifactCache) Restore(buildID int, artifactPath, destFile string) (bool, error) {
+	entry, ok := c.Lookup(buildID, artifactPath)
+	if !ok {
+		return false, nil
+	}
+
+	object := c.objectPath(entry.SHA256)
+
+	sum, size, err := utils.FileSHA256(object)
+	if err != nil || sum != entry.SHA256 || size != entry.Size {
+		log.Warnf("removing corrupted artifact %s of build %d from the cache", artifactPath, buildID)
+		os.Remove(object)
+		os.Remove(c.entryPath(buildID, artifactPath))
+
+		return false, nil
+	}
+
+	err = utils.CreateDir(destFile)
+	if err != nil {
+		return false, fmt.Errorf("error creating dir: %w", err)
+	}
+
+	err = linkOrCopy(object, destFile)
+	if err != nil {
+		return false, fmt.Errorf("error restoring %s from the cache: %w", artifactPath, err)
+	}
+
+	touch(object)
+
+	return true, nil
+}
+
+// Store adds a copy of a downloaded artifact file of a build to the cache, so changing the file later does not change the cached one.
+func (c *ArtifactCache) Store(buildID int, artifactPath, srcFile string) (ArtifactEntry, error) {
+	sum, size, err := utils.FileSHA256(srcFile)
+	if err != nil {
+		return ArtifactEntry{}, fmt.Errorf("error hashing %s: %w", srcFile, err)
+	}
+
+	object := c.objectPath(sum)
+
+	if info, err := os.Stat(object); err == nil && info.Size() == size {
+		touch(object)
+	} else {
+		err = os.MkdirAll(filepath.Dir(object), 0o755)
+		if err != nil {
+			return ArtifactEntry{}, fmt.Errorf("error creating dir: %w", err)
+		}
+
+		err = copyFile(srcFile, object)
+		if err != nil {
+			return ArtifactEntry{}, fmt.Errorf("error caching %s: %w", artifactPath, err)
+		}
+	}
+
+	entry := ArtifactEntry{Path: artifactPath, SHA256: sum, Size: size}
+
+	err = writeJSON(c.entryPath(buildID, artifactPath), entry)
+	if err != nil {
+		return entry, err
+	}
+
+	return entry, nil
+}
+
+// MarkComplete records that all artifacts of a build are cached, as the paths of its artifact files.
+func (c *ArtifactCache) MarkComplete(buildID int, artifactPaths []string) error {
+	return writeJSON(filepath.Join(c.buildDir(buildID), completeFileName), artifactPaths)
+}
+
+// Complete returns the paths of the artifact files of a build marked complete, if all of them are still cached.
+func (c *ArtifactCache) Complete(buildID int) ([]string, bool) {
+	var artifactPaths []string
+
+	err := readJSON(filepath.Join(c.buildDir(buildID), completeFileName), &artifactPaths)
+	if err != nil {
+		return nil, false
+	}
+
+	for _, artifactPath := range artifactPaths {
+		if _, ok := c.Lookup(buildID, artifactPath); !ok {
+			return nil, false
+		}
+	}
+
+	return artifactPaths, true
+}
+
+// Stats returns the number of builds, files and objects in the cache, and its size.
+func (c *ArtifactCache) Stats() (ArtifactCacheStats, error) {
+	stats := ArtifactCacheStats{Dir: c.dir, MaxSize: c.maxSize}
+
+	objects, err := c.objects()
+	if err != nil {
+		return stats, err
+	}
+
+	stats.Objects = len(objects)
+	for _, object := range objects {
+		stats.Size += object.size
+	}
+
+	buildDirs, err := c.buildDirs()
+	if err != nil {
+		return stats, err
+	}
+
+	for _, buildDir := range buildDirs {
+		entries, err := filepath.Glob(filepath.Join(buildDir, "*.json"))
+		if err != nil {
+			return stats, err
+		}
+
+		files := 0
+		for _, entry := range entries {
+			if filepath.Base(entry) != completeFileName {
+				files++
+			}
+		}
+
+		if files > 0 {
+			stats.Builds++
+			stats.Files += files
+		}
+	}
+
+	return stats, nil
+}
+
+// Prune evicts the least recently used files until the cache is at most maxSize bytes, and removes the entries of evicted files.
+// A maxSize of 0 empties the cache.
+func (c *ArtifactCache) Prune(maxSize int64) (PruneResult, error) {
+	var result PruneResult
+
+	objects, err := c.objects()
+	if err != nil {
+		return result, err
+	}
+
+	var total int64
+	for _, object := range objects {
+		total += object.size
+	}
+
+	// least recently used first
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].used.Before(objects[j].used)
+	})
+
+	for _, object := range objects {
+		if total <= maxSize {
+			break
+		}
+
+		err = os.Remove(object.path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return result, fmt.Errorf("error evicting %s: %w", object.path, err)
+		}
+
+		total -= object.size
+		result.Removed++
+		result.Freed += object.size
+	}
+
+	if result.Removed > 0 {
+		log.WithFields(log.Fields{
+			"files": result.Removed,
+			"freed": utils.FormatBytes(result.Freed),
+		}).Debug("evicted artifacts from the cache")
+	}
+
+	return result, c.removeDanglingEntries()
+}
+
+type cachedObject struct {
+	path string
+	size int64
+	used time.Time
+}
+
+func (c *ArtifactCache) objects() ([]cachedObject, error) {
+	var objects []cachedObject
+
+	err := filepath.WalkDir(filepath.Join(c.dir, objectsDirName), func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// skip directories and the temporary files of concurrent writes
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		objects = append(objects, cachedObject{path: path, size: info.Size(), used: info.ModTime()})
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading artifact cache: %w", err)
+	}
+
+	return objects, nil
+}
+
+// removeDanglingEntries removes the entries of evicted files, the builds left without entries, and the servers left without builds.
+func (c *ArtifactCache) removeDanglingEntries() error {
+	buildDirs, err := c.buildDirs()
+	if err != nil {
+		return err
+	}
+
+	for _, buildDir := range buildDirs {
+		entries, err := filepath.Glob(filepath.Join(buildDir, "*.json"))
+		if err != nil {
+			return err
+		}
+
+		remaining := 0
+
+		for _, entryFile := range entries {
+			if filepath.Base(entryFile) == completeFileName {
+				continue
+			}
+
+			var entry ArtifactEntry
+			if readJSON(entryFile, &entry) == nil && len(entry.SHA256) == sha256.Size*2 {
+				if _, err := os.Stat(c.objectPath(entry.SHA256)); err == nil {
+					remaining++
+					continue
+				}
+			}
+
+			os.Remove(entryFile)
+			// the build is no longer complete
+			os.Remove(filepath.Join(buildDir, completeFileName))
+		}
+
+		if remaining == 0 {
+			os.RemoveAll(buildDir)
+		}
+	}
+
+	servers, err := os.ReadDir(filepath.Join(c.dir, buildsDirName))
+	if err != nil {
+		return fmt.Errorf("error reading artifact cache: %w", err)
+	}
+
+	// this also removes the builds cached before they were indexed by server
+	for _, server := range servers {
+		serverDir := filepath.Join(c.dir, buildsDirName, server.Name())
+
+		buildDirs, err := filepath.Glob(filepath.Join(serverDir, "*", "*.json"))
+		if err == nil && len(buildDirs) == 0 {
+			os.RemoveAll(serverDir)
+		}
+	}
+
+	return nil
+}
+
+// linkOrCopy atomically replaces dest with a hardlink to src, or with a copy of src if they are on different file systems.
+func linkOrCopy(src, dest string) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".*.link")
+	if err != nil {
+		return err
+	}
+
+	tmpFile.Close()
+	os.Remove(tmpFile.Name())
+
+	err = os.Link(src, tmpFile.Name())
+	if err == nil {
+		err = os.Rename(tmpFile.Name(), dest)
+		if err != nil {
+			os.Remove(tmpFile.Name())
+		}
+
+		return err
+	}
+
+	log.Debugf("copying %s, it cannot be hardlinked: %s", src, err)
+
+	return copyFile(src, dest)
+}
+
+// copyFile atomically replaces dest with a copy of src, keeping its permissions.
+func copyFile(src, dest string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteFileAtomic(dest, f, info.Mode().Perm())
+}
+
+// touch marks a cached file as used, the least recently used files being evicted first.
+func touch(path string) {
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+}
+
+func readJSON(path string, v any) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(content, v)
+}
+
+func writeJSON(path string, v any) error {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("error encoding %s: %w", path, err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return fmt.Errorf("error creating dir: %w", err)
+	}
+
+	err = utils.WriteFileAtomic(path, bytes.NewReader(content), 0o644)
+	if err != nil {
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// ArtifactCacheOptions configure the opt-in artifact cache.
+type ArtifactCacheOptions struct {
+	Enabled bool
+	// Dir is the directory of the cache, the artifacts directory of the bbox cache directory if empty
+	Dir string
+	// MaxSize is the size the cache is pruned to, e.g. "10GiB"
+	MaxSize string
+}
+
+// Open opens the artifact cache if it is enabled, and returns nil otherwise.
+func (o ArtifactCacheOptions) Open() (*ArtifactCache, error) {
+	if !o.Enabled {
+		return nil, nil
+	}
+
+	dir := o.Dir
+	if dir == "" {
+		var err error
+
+		dir, err = ArtifactsDir()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var maxSize int64
+	if o.MaxSize != "" {
+		var err error
+
+		maxSize, err = utils.ParseBytes(o.MaxSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid artifact cache size: %w", err)
+		}
+	}
+
+	return OpenArtifactCache(dir, maxSize)
+}
@@ -0,0 +1,240 @@
+package cache_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"bbox/pkg/cache"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path, content string) string {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	return path
+}
+
+func TestArtifactCacheStoreAndRestore(t *testing.T) {
+	t.Parallel()
+
+	artifactCache, err := cache.OpenArtifactCache(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	src := writeFile(t, filepath.Join(t.TempDir(), "app.jar"), "jar")
+
+	entry, err := artifactCache.Store(10, "dist/app.jar", src)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), entry.Size)
+
+	dest := filepath.Join(t.TempDir(), "out", "app.jar")
+
+	restored, err := artifactCache.Restore(10, "dist/app.jar", dest)
+	require.NoError(t, err)
+	assert.True(t, restored)
+
+	content, err := os.ReadFile(dest)
+	require.NoError(t, err)
+	assert.Equal(t, "jar", string(content))
+
+	restored, err = artifactCache.Restore(11, "dist/app.jar", dest)
+	require.NoError(t, err)
+	assert.False(t, restored, "artifacts are cached per build")
+}
+
+func TestArtifactCacheCorruptedFile(t *testing.T) {
+	t.Parallel()
+
+	artifactCache, err := cache.OpenArtifactCache(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	src := writeFile(t, filepath.Join(t.TempDir(), "app.jar"), "jar")
+
+	entry, err := artifactCache.Store(10, "app.jar", src)
+	require.NoError(t, err)
+
+	// the file is copied into the cache, so modifying it in place leaves the cache intact
+	require.NoError(t, os.WriteFile(src, []byte("JAR"), 0o644))
+
+	dest := filepath.Join(t.TempDir(), "app.jar")
+
+	restored, err := artifactCache.Restore(10, "app.jar", dest)
+	require.NoError(t, err)
+	assert.True(t, restored)
+
+	content, err := os.ReadFile(dest)
+	require.NoError(t, err)
+	assert.Equal(t, "jar", string(content))
+
+	object := filepath.Join(artifactCache.Dir(), "objects", entry.SHA256[:2], entry.SHA256)
+	require.NoError(t, os.Remove(dest))
+	require.NoError(t, os.WriteFile(object, []byte("JAR"), 0o644))
+
+	restored, err = artifactCache.Restore(10, "app.jar", filepath.Join(t.TempDir(), "app.jar"))
+	require.NoError(t, err)
+	assert.False(t, restored)
+
+	_, cached := artifactCache.Lookup(10, "app.jar")
+	assert.False(t, cached, "the corrupted file is removed from the cache")
+}
+
+func TestArtifactCacheKeepsMode(t *testing.T) {
+	t.Parallel()
+
+	artifactCache, err := cache.OpenArtifactCache(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	src := writeFile(t, filepath.Join(t.TempDir(), "run.sh"), "#!/bin/sh")
+	require.NoError(t, os.Chmod(src, 0o755))
+
+	entry, err := artifactCache.Store(10, "run.sh", src)
+	require.NoError(t, err)
+
+	info, err := os.Stat(filepath.Join(artifactCache.Dir(), "objects", entry.SHA256[:2], entry.SHA256))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+
+	dest := filepath.Join(t.TempDir(), "run.sh")
+
+	restored, err := artifactCache.Restore(10, "run.sh", dest)
+	require.NoError(t, err)
+	require.True(t, restored)
+
+	info, err = os.Stat(dest)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+}
+
+func TestArtifactCacheServers(t *testing.T) {
+	t.Parallel()
+
+	artifactCache, err := cache.OpenArtifactCache(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	serverA := artifactCache.ForServer("https://a.example.com/")
+	serverB := artifactCache.ForServer("https://b.example.com")
+
+	_, err = serverA.Store(10, "app.jar", writeFile(t, filepath.Join(t.TempDir(), "app.jar"), "a"))
+	require.NoError(t, err)
+
+	_, ok := serverA.Lookup(10, "app.jar")
+	assert.True(t, ok)
+
+	_, ok = artifactCache.ForServer("https://a.example.com").Lookup(10, "app.jar")
+	assert.True(t, ok, "the trailing slash of the base URL is ignored")
+
+	_, ok = serverB.Lookup(10, "app.jar")
+	assert.False(t, ok, "builds are cached per server")
+
+	_, err = serverB.Store(10, "app.jar", writeFile(t, filepath.Join(t.TempDir(), "app.jar"), "b"))
+	require.NoError(t, err)
+
+	// a build cached before builds were indexed by server
+	writeFile(t, filepath.Join(artifactCache.Dir(), "builds", "10", "entry.json"), "{}")
+
+	stats, err := artifactCache.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.Builds)
+	assert.Equal(t, 2, stats.Objects)
+
+	_, err = artifactCache.Prune(cache.DefaultArtifactsMaxSize)
+	require.NoError(t, err)
+
+	_, err = os.Stat(filepath.Join(artifactCache.Dir(), "builds", "10"))
+	assert.ErrorIs(t, err, os.ErrNotExist, "the builds cached before are removed")
+
+	_, ok = serverB.Lookup(10, "app.jar")
+	assert.True(t, ok)
+}
+
+func TestArtifactCacheComplete(t *testing.T) {
+	t.Parallel()
+
+	artifactCache, err := cache.OpenArtifactCache(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	_, ok := artifactCache.Complete(10)
+	assert.False(t, ok)
+
+	srcDir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt"} {
+		_, err = artifactCache.Store(10, name, writeFile(t, filepath.Join(srcDir, name), name))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, artifactCache.MarkComplete(10, []string{"a.txt", "b.txt"}))
+
+	paths, ok := artifactCache.Complete(10)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"a.txt", "b.txt"}, paths)
+
+	require.NoError(t, artifactCache.MarkComplete(11, []string{"missing.txt"}))
+
+	_, ok = artifactCache.Complete(11)
+	assert.False(t, ok, "builds with missing files are not complete")
+}
+
+func TestArtifactCachePrune(t *testing.T) {
+	t.Parallel()
+
+	artifactCache, err := cache.OpenArtifactCache(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	srcDir := t.TempDir()
+	old := writeFile(t, filepath.Join(srcDir, "old.bin"), "0123456789")
+	recent := writeFile(t, filepath.Join(srcDir, "recent.bin"), "abcdefghij")
+
+	_, err = artifactCache.Store(1, "old.bin", old)
+	require.NoError(t, err)
+	_, err = artifactCache.Store(2, "recent.bin", recent)
+	require.NoError(t, err)
+	// the same content in another build is stored once
+	_, err = artifactCache.Store(3, "copy.bin", recent)
+	require.NoError(t, err)
+
+	entry, ok := artifactCache.Lookup(1, "old.bin")
+	require.True(t, ok)
+
+	// mark old.bin as used an hour ago
+	objects, err := filepath.Glob(filepath.Join(artifactCache.Dir(), "objects", entry.SHA256[:2], entry.SHA256))
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+
+	hourAgo := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(objects[0], hourAgo, hourAgo))
+
+	stats, err := artifactCache.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.Builds)
+	assert.Equal(t, 3, stats.Files)
+	assert.Equal(t, 2, stats.Objects)
+	assert.Equal(t, int64(20), stats.Size)
+
+	result, err := artifactCache.Prune(15)
+	require.NoError(t, err)
+	assert.Equal(t, cache.PruneResult{Removed: 1, Freed: 10}, result)
+
+	_, ok = artifactCache.Lookup(1, "old.bin")
+	assert.False(t, ok, "the least recently used file is evicted")
+
+	_, ok = artifactCache.Lookup(2, "recent.bin")
+	assert.True(t, ok)
+
+	stats, err = artifactCache.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.Builds, "the builds left without files are removed")
+
+	result, err = artifactCache.Prune(0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Removed)
+
+	stats, err = artifactCache.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, cache.ArtifactCacheStats{Dir: artifactCache.Dir(), MaxSize: cache.DefaultArtifactsMaxSize}, stats)
+}
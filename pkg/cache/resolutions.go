@@ -0,0 +1,144 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"bbox/pkg/utils"
+)
+
+const resolutionsFileName = "build-types.json"
+
+// Resolution is a cached build type query resolution.
+type Resolution struct {
+	BuildTypeID string    `json:"buildTypeId"`
+	ResolvedAt  time.Time `json:"resolvedAt"`
+}
+
+// Resolutions is a file backed cache of build type queries resolved to build type IDs.
+type Resolutions struct {
+	path    string
+	mu      sync.Mutex
+	Entries map[string]Resolution `json:"entries"`
+}
+
+// Dir returns the bbox cache directory, creating it if needed.
+func Dir() (string, error) {
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("error getting user cache dir: %w", err)
+	}
+
+	dir := filepath.Join(userCacheDir, "bbox")
+
+	err = os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return "", fmt.Errorf("error creating cache dir: %w", err)
+	}
+
+	return dir, nil
+}
+
+// LoadResolutions loads the build type resolutions from the bbox cache directory.
+// A missing cache file results in an empty cache.
+func LoadResolutions() (*Resolutions, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	return LoadResolutionsFromFile(filepath.Join(dir, resolutionsFileName))
+}
+
+// LoadResolutionsFromFile loads the build type resolutions from the given file.
+// A missing cache file results in an empty cache.
+func LoadResolutionsFromFile(path string) (*Resolutions, error) {
+	resolutions := &Resolutions{
+		path:    path,
+		Entries: map[string]Resolution{},
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return resolutions, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error reading cache file %s: %w", path, err)
+	}
+
+	err = json.Unmarshal(content, resolutions)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding cache file %s: %w", path, err)
+	}
+
+	if resolutions.Entries == nil {
+		resolutions.Entries = map[string]Resolution{}
+	}
+
+	return resolutions, nil
+}
+
+// Get returns the cached build type ID for key, if it was resolved less than ttl ago.
+func (r *Resolutions) Get(key string, ttl time.Duration) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	resolution, ok := r.Entries[key]
+	if !ok || time.Since(resolution.ResolvedAt) > ttl {
+		return "", false
+	}
+
+	return resolution.BuildTypeID, true
+}
+
+// Set stores the build type ID resolved for key.
+func (r *Resolutions) Set(key, buildTypeID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Entries[key] = Resolution{
+		BuildTypeID: buildTypeID,
+		ResolvedAt:  time.Now(),
+	}
+}
+
+// Save writes the resolutions back to the cache file.
+// The entries saved by concurrent bbox processes since the cache was loaded are merged first, keeping the most recent resolution of every key,
+// and the file is replaced atomically so it is never read half written.
+func (r *Resolutions) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	saved, err := LoadResolutionsFromFile(r.path)
+	if err == nil {
+		for key, resolution := range saved.Entries {
+			if current, ok := r.Entries[key]; !ok || resolution.ResolvedAt.After(current.ResolvedAt) {
+				r.Entries[key] = resolution
+			}
+		}
+	}
+
+	content, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding cache: %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(r.path), 0o755)
+	if err != nil {
+		return fmt.Errorf("error creating cache dir: %w", err)
+	}
+
+	err = utils.WriteFileAtomic(r.path, bytes.NewReader(content), 0o644)
+	if err != nil {
+		return fmt.Errorf("error writing cache file %s: %w", r.path, err)
+	}
+
+	return nil
+}
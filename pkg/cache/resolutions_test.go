@@ -0,0 +1,66 @@
+package cache_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"bbox/pkg/cache"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolutionsSaveMergesConcurrentEntries(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "build-types.json")
+
+	first, err := cache.LoadResolutionsFromFile(path)
+	require.NoError(t, err)
+
+	second, err := cache.LoadResolutionsFromFile(path)
+	require.NoError(t, err)
+
+	first.Set("server|backend", "Backend_Build")
+	require.NoError(t, first.Save())
+
+	second.Set("server|frontend", "Frontend_Build")
+	require.NoError(t, second.Save())
+
+	saved, err := cache.LoadResolutionsFromFile(path)
+	require.NoError(t, err)
+
+	buildTypeID, ok := saved.Get("server|backend", time.Hour)
+	assert.True(t, ok)
+	assert.Equal(t, "Backend_Build", buildTypeID)
+
+	buildTypeID, ok = saved.Get("server|frontend", time.Hour)
+	assert.True(t, ok)
+	assert.Equal(t, "Frontend_Build", buildTypeID)
+}
+
+func TestResolutionsSaveKeepsMostRecentEntry(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "build-types.json")
+
+	stale, err := cache.LoadResolutionsFromFile(path)
+	require.NoError(t, err)
+
+	stale.Set("server|backend", "Old_Build")
+
+	fresh, err := cache.LoadResolutionsFromFile(path)
+	require.NoError(t, err)
+
+	fresh.Set("server|backend", "New_Build")
+	require.NoError(t, fresh.Save())
+	require.NoError(t, stale.Save())
+
+	saved, err := cache.LoadResolutionsFromFile(path)
+	require.NoError(t, err)
+
+	buildTypeID, ok := saved.Get("server|backend", time.Hour)
+	assert.True(t, ok)
+	assert.Equal(t, "New_Build", buildTypeID)
+}
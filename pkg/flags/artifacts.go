@@ -0,0 +1,110 @@
+package flags
+
+import (
+	"fmt"
+
+	"bbox/pkg/cache"
+	"bbox/pkg/utils"
+	"bbox/teamcity"
+
+	"github.com/spf13/pflag"
+)
+
+// ArtifactFlags are the command line flags selecting and downloading the artifacts of a build, shared by the commands that download artifacts.
+type ArtifactFlags struct {
+	Include         []string
+	Exclude         []string
+	VerifyChecksums string
+	PerFile         bool
+	Parallelism     int
+	Retries         int
+	ExtractNested   bool
+	NoExtract       bool
+	MaxSize         string
+	Cache           cache.ArtifactCacheOptions
+}
+
+// AddArtifactFlags registers the artifact filter and download flags on fs.
+func AddArtifactFlags(fs *pflag.FlagSet) *ArtifactFlags {
+	f := &ArtifactFlags{}
+	f.AddFilterFlags(fs)
+	f.AddDownloadFlags(fs)
+
+	return f
+}
+
+// AddFilterFlags registers the flags selecting the artifacts, --artifact-include and --artifact-exclude, on fs.
+func (f *ArtifactFlags) AddFilterFlags(fs *pflag.FlagSet) {
+	fs.StringSliceVar(&f.Include, "artifact-include", nil, "Only select the artifacts matching these glob patterns or TeamCity artifact rules, e.g. 'dist/**/*.jar => lib/'. Repeatable")
+	fs.StringSliceVar(&f.Exclude, "artifact-exclude", nil, "Do not select the artifacts matching these glob patterns, e.g. '**/*.map'. Repeatable")
+}
+
+// AddDownloadFlags registers the flags configuring how the selected artifacts are downloaded on fs.
+func (f *ArtifactFlags) AddDownloadFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&f.VerifyChecksums, "verify-checksums", "", "Verify the downloaded artifacts against a checksum file of the build in sha256sum format, SHA256SUMS if no file is given")
+	fs.Lookup("verify-checksums").NoOptDefVal = "SHA256SUMS"
+	fs.BoolVar(&f.PerFile, "download-per-file", false, "Download the artifacts file by file instead of as a single zip, retrying and resuming failed files. Always the case with --artifact-include or --artifact-exclude")
+	fs.IntVar(&f.Parallelism, "download-parallelism", teamcity.DefaultDownloadParallelism, "Number of artifact files downloaded at the same time")
+	fs.IntVar(&f.Retries, "download-retries", 3, "Number of times the download of an artifact file is retried, resuming it where it failed")
+	fs.BoolVar(&f.ExtractNested, "extract-nested", false, "Extract the archives found in the downloaded artifacts, e.g. .tgz bundles, to a directory named after them. Supports .zip, .tar, .tar.gz, .tgz, .tar.zst and .tzst")
+	fs.BoolVar(&f.NoExtract, "no-extract", false, "Keep the zip of all artifacts as downloaded instead of extracting and deleting it")
+	fs.StringVar(&f.MaxSize, "max-artifacts-size", "", "Fail before downloading if the selected artifacts of a build are larger than this size, e.g. 2GiB or 500MB")
+	fs.BoolVar(&f.Cache.Enabled, "artifacts-cache", false, "Restore the artifacts downloaded before from a local cache shared by the builds of the runner, and cache the downloaded ones")
+	fs.StringVar(&f.Cache.Dir, "artifacts-cache-dir", "", "Directory of the artifact cache, bbox/artifacts in the user cache directory if empty")
+	fs.StringVar(&f.Cache.MaxSize, "artifacts-cache-size", "10GiB", "Size the artifact cache is pruned to, least recently used artifacts first, e.g. 10GiB or 500MB")
+}
+
+// Filter parses the artifact include and exclude flags.
+func (f *ArtifactFlags) Filter() (teamcity.ArtifactFilter, error) {
+	filter, err := teamcity.NewArtifactFilter(f.Include, f.Exclude)
+	if err != nil {
+		return teamcity.ArtifactFilter{}, fmt.Errorf("error parsing artifact filter: %w", err)
+	}
+
+	return filter, nil
+}
+
+// Options parses and validates the flags into the options of the artifact downloads, opening the artifact cache if it is enabled.
+func (f *ArtifactFlags) Options() (teamcity.ArtifactOptions, error) {
+	if f.Parallelism < 1 {
+		return teamcity.ArtifactOptions{}, fmt.Errorf("--download-parallelism must be at least 1, got %d", f.Parallelism)
+	}
+
+	if f.Retries < 0 {
+		return teamcity.ArtifactOptions{}, fmt.Errorf("--download-retries must not be negative, got %d", f.Retries)
+	}
+
+	filter, err := f.Filter()
+	if err != nil {
+		return teamcity.ArtifactOptions{}, err
+	}
+
+	options := teamcity.ArtifactOptions{
+		Filter:          filter,
+		PerFile:         f.PerFile,
+		Parallelism:     f.Parallelism,
+		Retries:         f.Retries,
+		VerifyChecksums: f.VerifyChecksums,
+		ExtractNested:   f.ExtractNested,
+		NoExtract:       f.NoExtract,
+	}
+
+	if f.MaxSize != "" {
+		options.MaxSize, err = utils.ParseBytes(f.MaxSize)
+		if err != nil {
+			return teamcity.ArtifactOptions{}, fmt.Errorf("error parsing --max-artifacts-size: %w", err)
+		}
+	}
+
+	options.Cache, err = f.Cache.Open()
+	if err != nil {
+		return teamcity.ArtifactOptions{}, fmt.Errorf("error opening the artifact cache: %w", err)
+	}
+
+	err = options.Validate()
+	if err != nil {
+		return teamcity.ArtifactOptions{}, fmt.Errorf("invalid artifact options: %w", err)
+	}
+
+	return options, nil
+}
@@ -0,0 +1,59 @@
+package flags
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtifactFlagsOptions(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		args      []string
+		errSubstr string
+	}{
+		{name: "defaults"},
+		{name: "filter and size", args: []string{"--artifact-include", "dist/**/*.jar => lib/", "--artifact-exclude", "**/*.map", "--max-artifacts-size", "2GiB"}},
+		{name: "negative retries", args: []string{"--download-retries=-1"}, errSubstr: "--download-retries must not be negative"},
+		{name: "no parallelism", args: []string{"--download-parallelism", "0"}, errSubstr: "--download-parallelism must be at least 1"},
+		{name: "invalid size", args: []string{"--max-artifacts-size", "lots"}, errSubstr: "error parsing --max-artifacts-size"},
+		{name: "invalid combination", args: []string{"--no-extract", "--extract-nested"}, errSubstr: "invalid artifact options"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			artifactFlags := AddArtifactFlags(fs)
+			require.NoError(t, fs.Parse(tc.args))
+
+			options, err := artifactFlags.Options()
+			if tc.errSubstr != "" {
+				assert.ErrorContains(t, err, tc.errSubstr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, artifactFlags.Parallelism, options.Parallelism)
+			assert.Equal(t, 3, options.Retries)
+		})
+	}
+}
+
+func TestArtifactFlagsVerifyChecksumsDefault(t *testing.T) {
+	t.Parallel()
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	artifactFlags := AddArtifactFlags(fs)
+	require.NoError(t, fs.Parse([]string{"--verify-checksums"}))
+
+	options, err := artifactFlags.Options()
+	require.NoError(t, err)
+	assert.Equal(t, "SHA256SUMS", options.VerifyChecksums)
+}
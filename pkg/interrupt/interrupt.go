@@ -0,0 +1,170 @@
+package interrupt
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+
+	"bbox/pkg/models"
+	"bbox/pkg/utils"
+	"bbox/teamcity"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/olekukonko/tablewriter"
+	log "github.com/sirupsen/logrus"
+)
+
+// ExitCode is the exit code of bbox when it is interrupted, 128 + SIGINT as used by shells.
+const ExitCode = 130
+
+const cancelComment = "Canceled by bbox: interrupted by the user"
+
+// TriggeredBuild is a build triggered by this invocation of bbox.
+type TriggeredBuild struct {
+	ID     int
+	Name   string
+	WebURL string
+}
+
+// Tracker records the builds triggered by this invocation of bbox, to cancel them when it is interrupted.
+// It is safe for concurrent use.
+type Tracker struct {
+	mu     sync.Mutex
+	builds []TriggeredBuild
+}
+
+// NewTracker creates a new instance of Tracker.
+func NewTracker() *Tracker {
+	return &Tracker{}
+}
+
+// Track records a triggered build.
+func (t *Tracker) Track(build TriggeredBuild) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.builds = append(t.builds, build)
+}
+
+// Builds returns the triggered builds, in the order they were triggered.
+func (t *Tracker) Builds() []TriggeredBuild {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]TriggeredBuild{}, t.builds...)
+}
+
+// NotifyContext returns a context that is done on ctrl+c or SIGTERM.
+// The default signal behavior is restored as soon as the context is done, so a second ctrl+c exits right away,
+// even while the interrupted work, like downloading artifacts, is still winding down.
+func NotifyContext() (ctx context.Context, stop context.CancelFunc) {
+	ctx, stop = signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	return ctx, stop
+}
+
+// CancelResult is the outcome of canceling a triggered build.
+type CancelResult struct {
+	Build TriggeredBuild
+	// Canceled is false if the build already finished, or if canceling it failed with Err
+	Canceled bool
+	Err      error
+}
+
+// Handle offers to cancel the tracked builds after bbox was interrupted, and prints a summary of what was canceled.
+// The builds are canceled right away if cancelOnInterrupt is set, after confirming in an interactive terminal, and left running otherwise.
+func Handle(client *teamcity.Client, tracker *Tracker, cancelOnInterrupt bool, w io.Writer) []CancelResult {
+	builds := tracker.Builds()
+	if len(builds) == 0 {
+		log.Warn("interrupted, no builds were triggered")
+		return nil
+	}
+
+	if !cancelOnInterrupt && !confirmCancel(builds, w) {
+		log.Warnf("interrupted, %d triggered builds keep running in TeamCity", len(builds))
+		for _, build := range builds {
+			log.Warnf("  %s: %s", build.Name, build.WebURL)
+		}
+
+		return nil
+	}
+
+	results := CancelBuilds(client, builds)
+	SummaryTable(w, results)
+
+	return results
+}
+
+// CancelBuilds cancels the builds, reporting the outcome of each one.
+func CancelBuilds(client *teamcity.Client, builds []TriggeredBuild) []CancelResult {
+	results := make([]CancelResult, 0, len(builds))
+
+	for _, build := range builds {
+		canceled, err := client.Build.CancelBuild(build.ID, cancelComment)
+		if err != nil {
+			log.Errorf("error canceling build %s: %s", build.Name, err)
+		}
+
+		results = append(results, CancelResult{Build: build, Canceled: canceled, Err: err})
+	}
+
+	return results
+}
+
+func confirmCancel(builds []TriggeredBuild, w io.Writer) bool {
+	if !utils.IsInteractiveTerminal() {
+		return false
+	}
+
+	fmt.Fprintf(w, "\nInterrupted. Cancel the %d builds triggered by bbox?\n", len(builds))
+	for _, build := range builds {
+		fmt.Fprintf(w, "  - %s (%s)\n", build.Name, build.WebURL)
+	}
+
+	activeModel, err := tea.NewProgram(models.NewConfirmActionModel()).Run()
+	if err != nil {
+		log.Error("error while running confirmation model: ", err)
+		return false
+	}
+
+	confirmedModel, ok := activeModel.(models.ConfirmActionModel)
+
+	return ok && confirmedModel.IsConfirmed()
+}
+
+// SummaryTable renders the outcome of canceling the triggered builds.
+func SummaryTable(w io.Writer, results []CancelResult) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Build Name", "Build ID", "Result", "Web URL"})
+	table.SetHeaderColor(tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiCyanColor}, tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiCyanColor}, tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiCyanColor}, tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiCyanColor})
+	table.SetBorders(tablewriter.Border{Left: false, Top: true, Right: false, Bottom: true})
+
+	for _, result := range results {
+		outcome := "already finished"
+		outcomeColor := tablewriter.FgWhiteColor
+
+		switch {
+		case result.Err != nil:
+			outcome = "error: " + result.Err.Error()
+			outcomeColor = tablewriter.FgHiRedColor
+		case result.Canceled:
+			outcome = "canceled"
+			outcomeColor = tablewriter.FgHiYellowColor
+		}
+
+		table.Rich([]string{result.Build.Name, strconv.Itoa(result.Build.ID), outcome, result.Build.WebURL}, []tablewriter.Colors{{}, {}, {tablewriter.Bold, outcomeColor}, {}})
+	}
+
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.Render()
+}
@@ -0,0 +1,49 @@
+package interrupt_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"bbox/pkg/interrupt"
+	"bbox/pkg/utils/testutils"
+	"bbox/teamcity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCancelBuilds(t *testing.T) {
+	mockBuildService := new(testutils.MockBuildService)
+	client := &teamcity.Client{Build: mockBuildService}
+
+	tracker := interrupt.NewTracker()
+	tracker.Track(interrupt.TriggeredBuild{ID: 1, Name: "Queued", WebURL: "https://teamcity/build/1"})
+	tracker.Track(interrupt.TriggeredBuild{ID: 2, Name: "Finished", WebURL: "https://teamcity/build/2"})
+	tracker.Track(interrupt.TriggeredBuild{ID: 3, Name: "Broken", WebURL: "https://teamcity/build/3"})
+
+	mockBuildService.On("CancelBuild", 1, mock.Anything).Return(true, nil)
+	mockBuildService.On("CancelBuild", 2, mock.Anything).Return(false, nil)
+	mockBuildService.On("CancelBuild", 3, mock.Anything).Return(false, errors.New("forbidden"))
+
+	results := interrupt.CancelBuilds(client, tracker.Builds())
+
+	assert.Equal(t, []interrupt.CancelResult{
+		{Build: interrupt.TriggeredBuild{ID: 1, Name: "Queued", WebURL: "https://teamcity/build/1"}, Canceled: true},
+		{Build: interrupt.TriggeredBuild{ID: 2, Name: "Finished", WebURL: "https://teamcity/build/2"}},
+		{Build: interrupt.TriggeredBuild{ID: 3, Name: "Broken", WebURL: "https://teamcity/build/3"}, Err: errors.New("forbidden")},
+	}, results)
+
+	var summary bytes.Buffer
+	interrupt.SummaryTable(&summary, results)
+
+	assert.Contains(t, summary.String(), "canceled")
+	assert.Contains(t, summary.String(), "already finished")
+	assert.Contains(t, summary.String(), "error: forbidden")
+
+	mockBuildService.AssertExpectations(t)
+}
+
+func TestHandleWithoutTriggeredBuilds(t *testing.T) {
+	assert.Nil(t, interrupt.Handle(&teamcity.Client{}, interrupt.NewTracker(), true, &bytes.Buffer{}))
+}
@@ -0,0 +1,171 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"bbox/pkg/types"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const buildProgressBarWidth = 30
+
+// BuildProgressMsg reports the latest status of the build, and its position in the queue while it is queued.
+type BuildProgressMsg struct {
+	Status        types.BuildStatusResponse
+	QueuePosition int
+}
+
+// BuildFinishedMsg reports that waiting for the build is over, either because it finished or because of Err.
+type BuildFinishedMsg struct {
+	Status types.BuildStatusResponse
+	Err    error
+}
+
+type buildProgressTickMsg time.Time
+
+// BuildProgressModel renders the live progress of a build: its state, percentage, current stage, elapsed and estimated time, and queue position.
+type BuildProgressModel struct {
+	BuildName     string
+	Status        types.BuildStatusResponse
+	QueuePosition int
+	Err           error
+	Finished      bool
+	// Interrupted is true if the user pressed ctrl+c before the build finished
+	Interrupted bool
+	Quitting    bool
+
+	// updatedAt is when the last status was received, the elapsed time keeps ticking from it
+	updatedAt time.Time
+	now       time.Time
+}
+
+// NewBuildProgressModel creates a new instance of BuildProgressModel.
+func NewBuildProgressModel(buildName string) BuildProgressModel {
+	now := time.Now()
+
+	return BuildProgressModel{
+		BuildName: buildName,
+		updatedAt: now,
+		now:       now,
+	}
+}
+
+func (m BuildProgressModel) Init() tea.Cmd {
+	return buildProgressTick()
+}
+
+func buildProgressTick() tea.Cmd {
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
+		return buildProgressTickMsg(t)
+	})
+}
+
+func (m BuildProgressModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.Type == tea.KeyCtrlC {
+			m.Interrupted = true
+			m.Quitting = true
+		}
+	case BuildProgressMsg:
+		m.Status = msg.Status
+		m.QueuePosition = msg.QueuePosition
+		m.updatedAt = time.Now()
+		m.now = m.updatedAt
+	case BuildFinishedMsg:
+		m.Status = msg.Status
+		m.Err = msg.Err
+		m.Finished = true
+		m.Quitting = true
+	case buildProgressTickMsg:
+		m.now = time.Time(msg)
+		return m, buildProgressTick()
+	}
+
+	if m.Quitting {
+		return m, tea.Quit
+	}
+
+	return m, nil
+}
+
+func (m BuildProgressModel) View() string {
+	if m.Quitting {
+		return ""
+	}
+
+	var b strings.Builder
+
+	state := m.Status.State
+	if state == "" {
+		state = types.BuildStateQueued
+	}
+
+	fmt.Fprintf(&b, "%s: %s\n", m.BuildName, state)
+
+	switch state {
+	case types.BuildStateQueued:
+		if m.QueuePosition > 0 {
+			fmt.Fprintf(&b, "Position in queue: %d\n", m.QueuePosition)
+		}
+
+		if m.Status.WaitReason != "" {
+			fmt.Fprintf(&b, "Waiting: %s\n", m.Status.WaitReason)
+		}
+	case types.BuildStateRunning:
+		percentage := m.Status.PercentageComplete
+		info := m.Status.RunningInfo
+
+		if info != nil && info.PercentageComplete > percentage {
+			percentage = info.PercentageComplete
+		}
+
+		fmt.Fprintf(&b, "%s %3d%%\n", ProgressBar(percentage, buildProgressBarWidth), percentage)
+
+		if info != nil {
+			if info.CurrentStageText != "" {
+				fmt.Fprintf(&b, "Stage: %s\n", info.CurrentStageText)
+			}
+
+			elapsed := time.Duration(info.ElapsedSeconds)*time.Second + m.now.Sub(m.updatedAt)
+			timing := fmt.Sprintf("Elapsed: %s", elapsed.Round(time.Second))
+
+			if info.EstimatedTotalSeconds > 0 {
+				timing += fmt.Sprintf(" / estimated %s", (time.Duration(info.EstimatedTotalSeconds) * time.Second).Round(time.Second))
+			}
+
+			if info.ProbablyHanging {
+				timing += " (probably hanging)"
+			}
+
+			fmt.Fprintln(&b, timing)
+		}
+	}
+
+	b.WriteString("\nPress ctrl+c to stop waiting.\n")
+
+	return b.String()
+}
+
+// IsFinished returns true if waiting for the build is over.
+func (m BuildProgressModel) IsFinished() bool {
+	return m.Finished
+}
+
+// ProgressBar renders a bar of the given width filled up to percentage.
+func ProgressBar(percentage, width int) string {
+	if percentage < 0 {
+		percentage = 0
+	}
+
+	if percentage > 100 {
+		percentage = 100
+	}
+
+	filled := width * percentage / 100
+
+	return "[" + strings.Repeat("█", filled) + strings.Repeat("░", width-filled) + "]"
+}
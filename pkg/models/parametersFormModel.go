@@ -0,0 +1,333 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"bbox/pkg/params"
+	"bbox/pkg/types"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ParameterField is a single build parameter edited by a ParametersFormModel.
+type ParameterField struct {
+	Name    string
+	Spec    types.ParameterSpec
+	Default string
+	Value   string
+
+	// option is the focused option of a multi-select field
+	option int
+}
+
+// ParametersFormModel handles editing build parameters according to their type specs.
+type ParametersFormModel struct {
+	Title     string
+	Fields    []ParameterField
+	Error     string
+	Submitted bool
+	Quitting  bool
+
+	cursor int
+}
+
+// NewParametersFormModel creates a new instance of ParametersFormModel.
+func NewParametersFormModel(title string, fields []ParameterField) ParametersFormModel {
+	return ParametersFormModel{
+		Title:  title,
+		Fields: fields,
+	}
+}
+
+func (m ParametersFormModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m ParametersFormModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	if len(m.Fields) == 0 {
+		m.Submitted = true
+		m.Quitting = true
+
+		return m, tea.Quit
+	}
+
+	field := &m.Fields[m.cursor]
+
+	switch keyMsg.Type {
+	case tea.KeyCtrlC, tea.KeyEsc:
+		m.Quitting = true
+	case tea.KeyUp, tea.KeyShiftTab:
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case tea.KeyDown, tea.KeyTab:
+		if m.cursor < len(m.Fields)-1 {
+			m.cursor++
+		}
+	case tea.KeyEnter:
+		if m.cursor < len(m.Fields)-1 {
+			m.cursor++
+		} else {
+			m.submit()
+		}
+	case tea.KeyCtrlS:
+		m.submit()
+	case tea.KeyLeft:
+		if isMultiSelect(*field) {
+			moveFieldOption(field, -1)
+		} else {
+			cycleFieldValue(field, -1)
+		}
+	case tea.KeyRight:
+		if isMultiSelect(*field) {
+			moveFieldOption(field, 1)
+		} else {
+			cycleFieldValue(field, 1)
+		}
+	case tea.KeySpace:
+		if isMultiSelect(*field) {
+			toggleFieldOption(field)
+		} else if field.Spec.Type == params.ParameterTypeCheckbox {
+			cycleFieldValue(field, 1)
+		} else if field.Spec.Type != params.ParameterTypeSelect {
+			field.Value += " "
+		}
+	case tea.KeyBackspace:
+		if field.Spec.Type != params.ParameterTypeSelect && field.Spec.Type != params.ParameterTypeCheckbox && len(field.Value) > 0 {
+			runes := []rune(field.Value)
+			field.Value = string(runes[:len(runes)-1])
+		}
+	case tea.KeyRunes:
+		if field.Spec.Type != params.ParameterTypeSelect && field.Spec.Type != params.ParameterTypeCheckbox {
+			field.Value += string(keyMsg.Runes)
+		}
+	}
+
+	if m.Quitting {
+		return m, tea.Quit
+	}
+
+	return m, nil
+}
+
+func (m ParametersFormModel) View() string {
+	if m.Quitting {
+		return ""
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s\n\n", m.Title)
+
+	for i, field := range m.Fields {
+		cursor := "  "
+		if i == m.cursor {
+			cursor = "> "
+		}
+
+		required := " "
+		if field.Spec.Required {
+			required = "*"
+		}
+
+		label := field.Name
+		if field.Spec.Label != "" {
+			label = fmt.Sprintf("%s (%s)", field.Spec.Label, field.Name)
+		}
+
+		fmt.Fprintf(&b, "%s%s %s: %s\n", cursor, required, label, displayFieldValue(field, i == m.cursor))
+
+		if i == m.cursor && field.Spec.Description != "" {
+			fmt.Fprintf(&b, "      %s\n", field.Spec.Description)
+		}
+	}
+
+	if m.Error != "" {
+		fmt.Fprintf(&b, "\nError: %s\n", m.Error)
+	}
+
+	b.WriteString("\n'up'/'down' to move, 'left'/'right' to change a select or checkbox, 'space' to toggle an option of a multi-select,\n")
+	b.WriteString("'enter' on the last field or 'ctrl+s' to continue, 'esc' to cancel.\n")
+	b.WriteString("* marks a required parameter.\n")
+
+	return b.String()
+}
+
+// Values returns the parameters that were changed from their defaults, and all parameters that TeamCity prompts for.
+func (m ParametersFormModel) Values() map[string]string {
+	values := map[string]string{}
+
+	for _, field := range m.Fields {
+		if field.Value != field.Default || field.Spec.Display == params.ParameterDisplayPrompt {
+			values[field.Name] = field.Value
+		}
+	}
+
+	return values
+}
+
+func (m ParametersFormModel) IsSubmitted() bool {
+	return m.Submitted
+}
+
+// submit validates the fields like params.ValidateProperties validates the submitted values, and quits if all of them are valid.
+func (m *ParametersFormModel) submit() {
+	for i, field := range m.Fields {
+		provided := field.Value != field.Default || field.Spec.Display == params.ParameterDisplayPrompt
+
+		for _, violation := range params.ValidateValue(field.Name, field.Value, provided, field.Spec) {
+			if violation.Warning {
+				continue
+			}
+
+			m.Error = violation.String()
+			m.cursor = i
+
+			return
+		}
+	}
+
+	m.Error = ""
+	m.Submitted = true
+	m.Quitting = true
+}
+
+// cycleFieldValue moves a select or checkbox field to its next or previous value.
+func cycleFieldValue(field *ParameterField, step int) {
+	var values []string
+
+	switch field.Spec.Type {
+	case params.ParameterTypeSelect:
+		for _, option := range field.Spec.Options {
+			values = append(values, option.Value)
+		}
+	case params.ParameterTypeCheckbox:
+		values = []string{field.Spec.UncheckedValue, field.Spec.CheckedValue}
+	default:
+		return
+	}
+
+	if len(values) == 0 {
+		return
+	}
+
+	current := 0
+	for i, value := range values {
+		if value == field.Value {
+			current = i
+			break
+		}
+	}
+
+	field.Value = values[(current+step+len(values))%len(values)]
+}
+
+// isMultiSelect returns whether a field is a select parameter accepting several values.
+func isMultiSelect(field ParameterField) bool {
+	return field.Spec.Type == params.ParameterTypeSelect && field.Spec.Multiple
+}
+
+// selectedValues returns the values of a multi-select field, split by its value separator.
+func selectedValues(field ParameterField) map[string]bool {
+	selected := map[string]bool{}
+
+	for _, value := range strings.Split(field.Value, field.Spec.ValueSeparator) {
+		value = strings.TrimSpace(value)
+		if value != "" {
+			selected[value] = true
+		}
+	}
+
+	return selected
+}
+
+// moveFieldOption moves the focus of a multi-select field to its next or previous option.
+func moveFieldOption(field *ParameterField, step int) {
+	if len(field.Spec.Options) == 0 {
+		return
+	}
+
+	field.option = (field.option + step + len(field.Spec.Options)) % len(field.Spec.Options)
+}
+
+// toggleFieldOption selects or deselects the focused option of a multi-select field, keeping the values in the order of the options.
+func toggleFieldOption(field *ParameterField) {
+	if field.option >= len(field.Spec.Options) {
+		return
+	}
+
+	selected := selectedValues(*field)
+	focused := field.Spec.Options[field.option].Value
+	selected[focused] = !selected[focused]
+
+	values := []string{}
+	for _, option := range field.Spec.Options {
+		if selected[option.Value] {
+			values = append(values, option.Value)
+		}
+	}
+
+	field.Value = strings.Join(values, field.Spec.ValueSeparator)
+}
+
+// PasswordMask is displayed for a password value, independent of its length so the length of the secret is not revealed.
+const PasswordMask = "********"
+
+func displayFieldValue(field ParameterField, focused bool) string {
+	if isMultiSelect(field) {
+		return displayMultiSelectValue(field, focused)
+	}
+
+	switch field.Spec.Type {
+	case params.ParameterTypeSelect:
+		for _, option := range field.Spec.Options {
+			if option.Value == field.Value {
+				return fmt.Sprintf("< %s >", option.Label)
+			}
+		}
+
+		return fmt.Sprintf("< %s >", field.Value)
+	case params.ParameterTypeCheckbox:
+		if field.Value == field.Spec.CheckedValue {
+			return "[x]"
+		}
+
+		return "[ ]"
+	case params.ParameterTypePassword:
+		if field.Value == "" {
+			return ""
+		}
+
+		return PasswordMask
+	default:
+		return field.Value
+	}
+}
+
+// displayMultiSelectValue lists the options of a multi-select field with their selection, marking the focused option.
+func displayMultiSelectValue(field ParameterField, focused bool) string {
+	selected := selectedValues(field)
+	options := make([]string, 0, len(field.Spec.Options))
+
+	for i, option := range field.Spec.Options {
+		check := "[ ]"
+		if selected[option.Value] {
+			check = "[x]"
+		}
+
+		cursor := " "
+		if focused && i == field.option {
+			cursor = ">"
+		}
+
+		options = append(options, fmt.Sprintf("%s%s %s", cursor, check, option.Label))
+	}
+
+	return strings.Join(options, " ")
+}
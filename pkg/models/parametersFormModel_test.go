@@ -0,0 +1,150 @@
+package models_test
+
+import (
+	"testing"
+
+	"bbox/pkg/models"
+	"bbox/pkg/params"
+	"bbox/pkg/types"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func updateForm(t *testing.T, m models.ParametersFormModel, msgs ...tea.Msg) models.ParametersFormModel {
+	t.Helper()
+
+	for _, msg := range msgs {
+		updated, _ := m.Update(msg)
+
+		var ok bool
+		m, ok = updated.(models.ParametersFormModel)
+		require.True(t, ok)
+	}
+
+	return m
+}
+
+var submitKey = tea.KeyMsg{Type: tea.KeyCtrlS}
+
+func TestParametersFormModelEditing(t *testing.T) {
+	t.Parallel()
+
+	fields := []models.ParameterField{
+		{Name: "env.MESSAGE", Spec: types.ParameterSpec{Type: params.ParameterTypeText}, Default: "hi", Value: "hi"},
+		{
+			Name:    "env.ENVIRONMENT",
+			Spec:    types.ParameterSpec{Type: params.ParameterTypeSelect, Options: []types.ParameterOption{{Label: "Dev", Value: "dev"}, {Label: "Production", Value: "prod"}}},
+			Default: "dev",
+			Value:   "dev",
+		},
+		{Name: "env.DRY_RUN", Spec: types.ParameterSpec{Type: params.ParameterTypeCheckbox, CheckedValue: "true", UncheckedValue: "false"}, Default: "false", Value: "false"},
+		{
+			Name: "env.REGIONS",
+			Spec: types.ParameterSpec{
+				Type:           params.ParameterTypeSelect,
+				Multiple:       true,
+				ValueSeparator: ",",
+				Options:        []types.ParameterOption{{Label: "US", Value: "us"}, {Label: "EU", Value: "eu"}, {Label: "Asia", Value: "asia"}},
+			},
+		},
+	}
+
+	m := updateForm(t, models.NewParametersFormModel("Parameters:", fields),
+		tea.KeyMsg{Type: tea.KeyBackspace}, typeKeys("ey"), tea.KeyMsg{Type: tea.KeyDown},
+		tea.KeyMsg{Type: tea.KeyRight}, tea.KeyMsg{Type: tea.KeyDown},
+		tea.KeyMsg{Type: tea.KeySpace}, tea.KeyMsg{Type: tea.KeyDown},
+		tea.KeyMsg{Type: tea.KeyRight}, tea.KeyMsg{Type: tea.KeyRight}, tea.KeyMsg{Type: tea.KeySpace},
+		tea.KeyMsg{Type: tea.KeyLeft}, tea.KeyMsg{Type: tea.KeyLeft}, tea.KeyMsg{Type: tea.KeySpace},
+	)
+
+	assert.Contains(t, m.View(), "< Production >")
+	assert.Contains(t, m.View(), "env.REGIONS: >[x] US  [ ] EU  [x] Asia")
+
+	m = updateForm(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+
+	require.True(t, m.IsSubmitted())
+	assert.Equal(t, map[string]string{
+		"env.MESSAGE":     "hey",
+		"env.ENVIRONMENT": "prod",
+		"env.DRY_RUN":     "true",
+		"env.REGIONS":     "us,asia",
+	}, m.Values())
+}
+
+func TestParametersFormModelPasswordMask(t *testing.T) {
+	t.Parallel()
+
+	fields := []models.ParameterField{{Name: "env.TOKEN", Spec: types.ParameterSpec{Type: params.ParameterTypePassword}}}
+
+	short := updateForm(t, models.NewParametersFormModel("Parameters:", fields), typeKeys("ab"))
+	long := updateForm(t, models.NewParametersFormModel("Parameters:", fields), typeKeys("a-much-longer-secret"))
+
+	assert.Contains(t, short.View(), "env.TOKEN: "+models.PasswordMask+"\n")
+	assert.Contains(t, long.View(), "env.TOKEN: "+models.PasswordMask+"\n")
+	assert.NotContains(t, long.View(), "a-much-longer-secret")
+}
+
+func TestParametersFormModelSubmitValidation(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		field         models.ParameterField
+		expectedError string
+	}{
+		{
+			name:          "missing required value",
+			field:         models.ParameterField{Name: "env.TICKET", Spec: types.ParameterSpec{Type: params.ParameterTypeText, Required: true}},
+			expectedError: "env.TICKET: missing required parameter",
+		},
+		{
+			name:          "regex matches only a part of the value",
+			field:         models.ParameterField{Name: "env.BUILD", Spec: types.ParameterSpec{Type: params.ParameterTypeText, Regex: `\d+`}, Value: "abc1"},
+			expectedError: `env.BUILD: value "abc1" does not match \d+`,
+		},
+		{
+			name: "regex with validation message",
+			field: models.ParameterField{
+				Name:  "env.VERSION",
+				Spec:  types.ParameterSpec{Type: params.ParameterTypeText, Regex: `\d+\.\d+`, ValidationMessage: "use major.minor"},
+				Value: "latest",
+			},
+			expectedError: `env.VERSION: value "latest" does not match \d+\.\d+ (use major.minor)`,
+		},
+		{
+			name: "multi-select value that is not an option",
+			field: models.ParameterField{
+				Name:  "env.REGIONS",
+				Spec:  types.ParameterSpec{Type: params.ParameterTypeSelect, Multiple: true, ValueSeparator: ",", Options: []types.ParameterOption{{Label: "US", Value: "us"}}},
+				Value: "us,asia",
+			},
+			expectedError: `env.REGIONS: invalid value "asia", allowed values: us`,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := updateForm(t, models.NewParametersFormModel("Parameters:", []models.ParameterField{tc.field}), submitKey)
+
+			assert.False(t, m.IsSubmitted())
+			assert.Equal(t, tc.expectedError, m.Error)
+			assert.Contains(t, m.View(), "Error: "+tc.expectedError)
+		})
+	}
+}
+
+func TestParametersFormModelSubmitIgnoresInvalidRegex(t *testing.T) {
+	t.Parallel()
+
+	fields := []models.ParameterField{{Name: "env.NAME", Spec: types.ParameterSpec{Type: params.ParameterTypeText, Regex: "[a-"}, Value: "x"}}
+
+	m := updateForm(t, models.NewParametersFormModel("Parameters:", fields), submitKey)
+
+	assert.True(t, m.IsSubmitted())
+	assert.Empty(t, m.Error)
+}
@@ -0,0 +1,194 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const selectModelVisibleItems = 15
+
+// SelectItem is a single selectable entry of a SelectModel, optionally grouped under a header.
+type SelectItem struct {
+	Label string
+	Value string
+	Group string
+}
+
+// SelectModel handles picking a single item out of a searchable, optionally grouped list.
+type SelectModel struct {
+	Title       string
+	Items       []SelectItem
+	Filter      string
+	AllowCustom bool
+	Selected    SelectItem
+	Chosen      bool
+	Quitting    bool
+
+	cursor int
+}
+
+// NewSelectModel creates a new instance of SelectModel.
+// If allowCustom is true, an entry to use the typed filter as a custom value is listed after the matching items,
+// so a value that is a substring of an item can still be chosen.
+func NewSelectModel(title string, items []SelectItem, allowCustom bool) SelectModel {
+	return SelectModel{
+		Title:       title,
+		Items:       items,
+		AllowCustom: allowCustom,
+	}
+}
+
+func (m SelectModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m SelectModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	filtered := m.visibleItems()
+
+	switch keyMsg.Type {
+	case tea.KeyCtrlC, tea.KeyEsc:
+		m.Quitting = true
+	case tea.KeyUp, tea.KeyCtrlP:
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case tea.KeyDown, tea.KeyCtrlN:
+		if m.cursor < len(filtered)-1 {
+			m.cursor++
+		}
+	case tea.KeyEnter:
+		if len(filtered) > 0 {
+			m.Selected = filtered[m.cursor]
+			m.Chosen = true
+			m.Quitting = true
+		}
+	case tea.KeyBackspace:
+		if len(m.Filter) > 0 {
+			runes := []rune(m.Filter)
+			m.Filter = string(runes[:len(runes)-1])
+			m.cursor = 0
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.Filter += string(keyMsg.Runes)
+		m.cursor = 0
+	}
+
+	if m.Quitting {
+		return m, tea.Quit
+	}
+
+	return m, nil
+}
+
+func (m SelectModel) View() string {
+	if m.Quitting {
+		return ""
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s\n", m.Title)
+	fmt.Fprintf(&b, "Search: %s\n\n", m.Filter)
+
+	filtered := m.visibleItems()
+	if len(filtered) == 0 {
+		b.WriteString("  No matches\n")
+	}
+
+	start := 0
+	if m.cursor >= selectModelVisibleItems {
+		start = m.cursor - selectModelVisibleItems + 1
+	}
+
+	end := start + selectModelVisibleItems
+	if end > len(filtered) {
+		end = len(filtered)
+	}
+
+	lastGroup := ""
+	for i := start; i < end; i++ {
+		item := filtered[i]
+
+		indent := ""
+		if item.Group != "" {
+			indent = "  "
+			if item.Group != lastGroup || i == start {
+				fmt.Fprintf(&b, "%s\n", item.Group)
+			}
+		}
+		lastGroup = item.Group
+
+		cursor := "  "
+		if i == m.cursor {
+			cursor = "> "
+		}
+
+		fmt.Fprintf(&b, "%s%s%s\n", cursor, indent, item.Label)
+	}
+
+	if len(filtered) > end {
+		fmt.Fprintf(&b, "  ... %d more\n", len(filtered)-end)
+	}
+
+	b.WriteString("\nType to search, 'up'/'down' to move, 'enter' to select, 'esc' to cancel.\n")
+
+	return b.String()
+}
+
+// visibleItems returns the items matching the filter, followed by an entry to use the typed filter as is if custom values are allowed
+// and no item has exactly that value.
+func (m SelectModel) visibleItems() []SelectItem {
+	filtered := m.filteredItems()
+
+	custom := strings.TrimSpace(m.Filter)
+	if !m.AllowCustom || custom == "" {
+		return filtered
+	}
+
+	for _, item := range filtered {
+		if item.Value == custom {
+			return filtered
+		}
+	}
+
+	return append(filtered[:len(filtered):len(filtered)], SelectItem{Label: fmt.Sprintf("Use %q", custom), Value: custom})
+}
+
+// filteredItems returns the items whose group and label contain every word of the filter, ignoring case.
+func (m SelectModel) filteredItems() []SelectItem {
+	words := strings.Fields(strings.ToLower(m.Filter))
+	if len(words) == 0 {
+		return m.Items
+	}
+
+	filtered := []SelectItem{}
+
+	for _, item := range m.Items {
+		haystack := strings.ToLower(item.Group + " " + item.Label + " " + item.Value)
+
+		matched := true
+		for _, word := range words {
+			if !strings.Contains(haystack, word) {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			filtered = append(filtered, item)
+		}
+	}
+
+	return filtered
+}
+
+func (m SelectModel) IsChosen() bool {
+	return m.Chosen
+}
@@ -0,0 +1,101 @@
+package models_test
+
+import (
+	"testing"
+
+	"bbox/pkg/models"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func updateSelect(t *testing.T, m models.SelectModel, msgs ...tea.Msg) models.SelectModel {
+	t.Helper()
+
+	for _, msg := range msgs {
+		updated, _ := m.Update(msg)
+
+		var ok bool
+		m, ok = updated.(models.SelectModel)
+		require.True(t, ok)
+	}
+
+	return m
+}
+
+func typeKeys(text string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(text)}
+}
+
+func TestSelectModelFilter(t *testing.T) {
+	t.Parallel()
+
+	items := []models.SelectItem{
+		{Label: "Build", Value: "Backend_Build", Group: "Backend"},
+		{Label: "Deploy", Value: "Backend_Deploy", Group: "Backend"},
+		{Label: "Build", Value: "Frontend_Build", Group: "Frontend"},
+	}
+
+	m := updateSelect(t, models.NewSelectModel("Select:", items, false), typeKeys("front build"), tea.KeyMsg{Type: tea.KeyEnter})
+
+	assert.True(t, m.IsChosen())
+	assert.Equal(t, items[2], m.Selected)
+}
+
+func TestSelectModelNoMatch(t *testing.T) {
+	t.Parallel()
+
+	items := []models.SelectItem{{Label: "main", Value: "main"}}
+
+	m := updateSelect(t, models.NewSelectModel("Select:", items, false), typeKeys("feature"))
+	assert.Contains(t, m.View(), "No matches")
+
+	m = updateSelect(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	assert.False(t, m.IsChosen())
+}
+
+func TestSelectModelCustomValue(t *testing.T) {
+	t.Parallel()
+
+	items := []models.SelectItem{
+		{Label: "main", Value: "main"},
+		{Label: "feature/login", Value: "feature/login"},
+	}
+
+	testCases := []struct {
+		name     string
+		filter   string
+		moves    int
+		expected models.SelectItem
+	}{
+		{name: "matching item first", filter: "feature", expected: items[1]},
+		{name: "custom value after the matching items", filter: "feature", moves: 1, expected: models.SelectItem{Label: `Use "feature"`, Value: "feature"}},
+		{name: "no custom value for an existing item", filter: "main", moves: 1, expected: items[0]},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := updateSelect(t, models.NewSelectModel("Select:", items, true), typeKeys(tc.filter))
+			for i := 0; i < tc.moves; i++ {
+				m = updateSelect(t, m, tea.KeyMsg{Type: tea.KeyDown})
+			}
+			m = updateSelect(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+
+			assert.True(t, m.IsChosen())
+			assert.Equal(t, tc.expected, m.Selected)
+		})
+	}
+}
+
+func TestSelectModelCancel(t *testing.T) {
+	t.Parallel()
+
+	m := updateSelect(t, models.NewSelectModel("Select:", []models.SelectItem{{Label: "main", Value: "main"}}, false), tea.KeyMsg{Type: tea.KeyEsc})
+
+	assert.False(t, m.IsChosen())
+	assert.True(t, m.Quitting)
+}
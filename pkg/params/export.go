@@ -0,0 +1,173 @@
+package params
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ExportFormatDotEnv = "dotenv"
+	ExportFormatJSON   = "json"
+	// ExportFormatGitHub appends the properties to a GitHub Actions $GITHUB_OUTPUT file
+	ExportFormatGitHub = "github"
+)
+
+var exportKeyInvalidChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// ExportOptions configures exporting the resulting properties of a finished build.
+type ExportOptions struct {
+	Path    string
+	Filters []string
+	Format  string
+}
+
+// Enabled returns true if an export file was requested.
+func (eo ExportOptions) Enabled() bool {
+	return eo.Path != ""
+}
+
+// Validate checks the filter patterns and the format, so an invalid export fails before any build is triggered.
+func (eo ExportOptions) Validate() error {
+	for _, pattern := range eo.Filters {
+		_, err := path.Match(pattern, "")
+		if err != nil {
+			return fmt.Errorf("invalid filter %q: %w", pattern, err)
+		}
+	}
+
+	switch eo.Format {
+	case "", ExportFormatDotEnv, ExportFormatJSON, ExportFormatGitHub:
+		return nil
+	default:
+		return fmt.Errorf("unknown export format %q, expected one of: %s, %s, %s", eo.Format, ExportFormatDotEnv, ExportFormatJSON, ExportFormatGitHub)
+	}
+}
+
+// FilterProperties returns the properties whose name matches any of the glob patterns, e.g. "env.*".
+// All properties are returned if no pattern is given.
+func FilterProperties(properties map[string]string, patterns []string) (map[string]string, error) {
+	if len(patterns) == 0 {
+		return properties, nil
+	}
+
+	filtered := map[string]string{}
+
+	for name, value := range properties {
+		for _, pattern := range patterns {
+			matched, err := path.Match(pattern, name)
+			if err != nil {
+				return nil, fmt.Errorf("invalid filter %q: %w", pattern, err)
+			}
+
+			if matched {
+				filtered[name] = value
+				break
+			}
+		}
+	}
+
+	return filtered, nil
+}
+
+// ExportProperties writes the properties to the export file in the requested format.
+// If no format is given, it is inferred from the file: $GITHUB_OUTPUT is written as GitHub output, .json as JSON, and anything else as dotenv.
+func ExportProperties(options ExportOptions, properties map[string]string) error {
+	format := options.Format
+	if format == "" {
+		format = inferExportFormat(options.Path)
+	}
+
+	var err error
+
+	switch format {
+	case ExportFormatDotEnv:
+		err = os.WriteFile(options.Path, []byte(formatDotEnv(properties)), 0o644)
+	case ExportFormatJSON:
+		var content []byte
+		content, err = json.MarshalIndent(properties, "", "  ")
+		if err == nil {
+			err = os.WriteFile(options.Path, append(content, '\n'), 0o644)
+		}
+	case ExportFormatGitHub:
+		err = appendToFile(options.Path, formatGitHubOutput(properties))
+	default:
+		return fmt.Errorf("unknown export format %q, expected one of: %s, %s, %s", format, ExportFormatDotEnv, ExportFormatJSON, ExportFormatGitHub)
+	}
+
+	if err != nil {
+		return fmt.Errorf("error exporting properties to %s: %w", options.Path, err)
+	}
+
+	return nil
+}
+
+func inferExportFormat(filePath string) string {
+	if githubOutput := os.Getenv("GITHUB_OUTPUT"); githubOutput != "" && filepath.Clean(githubOutput) == filepath.Clean(filePath) {
+		return ExportFormatGitHub
+	}
+
+	if strings.EqualFold(filepath.Ext(filePath), ".json") {
+		return ExportFormatJSON
+	}
+
+	return ExportFormatDotEnv
+}
+
+// ExportKey turns a property name into a valid environment variable or output name, e.g. env.IMAGE_TAG => env_IMAGE_TAG.
+func ExportKey(name string) string {
+	return exportKeyInvalidChars.ReplaceAllString(name, "_")
+}
+
+func formatDotEnv(properties map[string]string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+
+	var b strings.Builder
+	for _, name := range sortedKeys(properties) {
+		fmt.Fprintf(&b, "%s=\"%s\"\n", ExportKey(name), replacer.Replace(properties[name]))
+	}
+
+	return b.String()
+}
+
+// formatGitHubOutput uses the multiline syntax for every value, with a random delimiter that can not appear in the value.
+func formatGitHubOutput(properties map[string]string) string {
+	var b strings.Builder
+	for _, name := range sortedKeys(properties) {
+		delimiter := "ghadelimiter_" + uuid.New().String()
+		fmt.Fprintf(&b, "%s<<%s\n%s\n%s\n", ExportKey(name), delimiter, properties[name], delimiter)
+	}
+
+	return b.String()
+}
+
+func appendToFile(filePath, content string) error {
+	f, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.WriteString(content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+func sortedKeys(properties map[string]string) []string {
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,119 @@
+package params_test
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"bbox/pkg/params"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterProperties(t *testing.T) {
+	t.Parallel()
+
+	properties := map[string]string{
+		"env.IMAGE_TAG":      "1.2.3",
+		"env.VERSION":        "1.2",
+		"system.build.count": "5",
+		"teamcity.version":   "2024.1",
+	}
+
+	filtered, err := params.FilterProperties(properties, []string{"env.*", "system.build.*"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"env.IMAGE_TAG":      "1.2.3",
+		"env.VERSION":        "1.2",
+		"system.build.count": "5",
+	}, filtered)
+
+	all, err := params.FilterProperties(properties, nil)
+	require.NoError(t, err)
+	assert.Equal(t, properties, all)
+
+	_, err = params.FilterProperties(properties, []string{"[env"})
+	assert.Error(t, err)
+}
+
+func TestExportProperties(t *testing.T) {
+	t.Parallel()
+
+	properties := map[string]string{
+		"env.IMAGE_TAG": "1.2.3",
+		"notes":         "line \"one\"\nline two",
+	}
+
+	testCases := []struct {
+		name     string
+		fileName string
+		format   string
+		existing string
+		expected string
+	}{
+		{
+			name:     "dotenv",
+			fileName: "build.env",
+			expected: "env_IMAGE_TAG=\"1.2.3\"\nnotes=\"line \\\"one\\\"\\nline two\"\n",
+		},
+		{
+			name:     "json inferred from extension",
+			fileName: "build.json",
+			expected: "{\n  \"env.IMAGE_TAG\": \"1.2.3\",\n  \"notes\": \"line \\\"one\\\"\\nline two\"\n}\n",
+		},
+		{
+			name:     "github output appends",
+			fileName: "github_output",
+			format:   params.ExportFormatGitHub,
+			existing: "previous=value\n",
+			expected: "previous=value\nenv_IMAGE_TAG<<DELIMITER\n1.2.3\nDELIMITER\nnotes<<DELIMITER\nline \"one\"\nline two\nDELIMITER\n",
+		},
+	}
+
+	delimiter := regexp.MustCompile(`ghadelimiter_[0-9a-f-]+`)
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), tc.fileName)
+			if tc.existing != "" {
+				require.NoError(t, os.WriteFile(path, []byte(tc.existing), 0o600))
+			}
+
+			err := params.ExportProperties(params.ExportOptions{Path: path, Format: tc.format}, properties)
+			require.NoError(t, err)
+
+			content, err := os.ReadFile(path)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, delimiter.ReplaceAllString(string(content), "DELIMITER"))
+		})
+	}
+}
+
+func TestExportPropertiesUnknownFormat(t *testing.T) {
+	t.Parallel()
+
+	err := params.ExportProperties(params.ExportOptions{Path: filepath.Join(t.TempDir(), "out"), Format: "xml"}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown export format")
+}
+
+func TestExportOptionsValidate(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, params.ExportOptions{Filters: []string{"env.*", "system.build.?"}, Format: params.ExportFormatJSON}.Validate())
+	assert.NoError(t, params.ExportOptions{}.Validate())
+
+	err := params.ExportOptions{Filters: []string{"env.*", "[env"}}.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid filter "[env"`)
+
+	err = params.ExportOptions{Format: "xml"}.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown export format")
+}
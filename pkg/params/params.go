@@ -21,9 +21,3 @@ func ValidateParamKey(key string) bool {
 	matched, _ := regexp.MatchString(`^\w+[a-zA-Z0-9\\;,*/_.-]*`, key)
 	return matched
 }
-
-// ValidateParamValue checks if the parameter is valid value and returns a boolean.
-func ValidateParamValue(value string) bool {
-	matched, _ := regexp.MatchString(`^[a-zA-Z0-9\\;,*/@:_.-]*$`, value)
-	return matched
-}
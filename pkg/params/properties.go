@@ -0,0 +1,269 @@
+package params
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envPropertySeparator is replaced with "." when mapping environment variables to properties, e.g. PREFIX_env__TAG => env.TAG.
+const envPropertySeparator = "__"
+
+// LoadProperties merges the properties of the given files, environment variables and inline properties.
+// Later files override earlier ones, environment variables override files, and inline properties override both.
+func LoadProperties(files []string, envPrefix string, inline map[string]string) (map[string]string, error) {
+	if len(files) == 0 && envPrefix == "" {
+		return inline, nil
+	}
+
+	layers := make([]map[string]string, 0, len(files)+2)
+
+	for _, file := range files {
+		properties, err := LoadPropertiesFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		layers = append(layers, properties)
+	}
+
+	if envPrefix != "" {
+		layers = append(layers, PropertiesFromEnv(envPrefix, os.Environ()))
+	}
+
+	layers = append(layers, inline)
+
+	return MergeProperties(layers...), nil
+}
+
+// MergeProperties merges the given properties, where properties of later maps override earlier ones.
+func MergeProperties(layers ...map[string]string) map[string]string {
+	merged := map[string]string{}
+
+	for _, layer := range layers {
+		for key, value := range layer {
+			merged[key] = value
+		}
+	}
+
+	return merged
+}
+
+// PropertiesFromEnv maps environment variables starting with prefix to properties.
+// The prefix is stripped and "__" is replaced with ".", so PREFIX_env__IMAGE_TAG becomes env.IMAGE_TAG.
+func PropertiesFromEnv(prefix string, environ []string) map[string]string {
+	properties := map[string]string{}
+
+	for _, variable := range environ {
+		name, value, found := strings.Cut(variable, "=")
+		if !found || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		key := strings.ReplaceAll(strings.TrimPrefix(name, prefix), envPropertySeparator, ".")
+		if key == "" {
+			continue
+		}
+
+		properties[key] = value
+	}
+
+	return properties
+}
+
+// LoadPropertiesFile reads properties from a JSON (.json), YAML (.yaml, .yml) or dotenv (anything else) file.
+func LoadPropertiesFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading properties file: %w", err)
+	}
+
+	var properties map[string]string
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		properties, err = parseJSONProperties(content)
+	case ".yaml", ".yml":
+		properties, err = parseYAMLProperties(content)
+	default:
+		properties, err = parseDotEnvProperties(string(content))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error parsing properties file %s: %w", path, err)
+	}
+
+	for key := range properties {
+		if !ValidateParamKey(key) {
+			return nil, fmt.Errorf("invalid property key in %s: %s", path, key)
+		}
+	}
+
+	return properties, nil
+}
+
+// parseJSONProperties parses a flat JSON object, whose values are strings, numbers, booleans or null.
+func parseJSONProperties(content []byte) (map[string]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+
+	var raw map[string]interface{}
+
+	err := decoder.Decode(&raw)
+	if err != nil {
+		return nil, err
+	}
+
+	properties := make(map[string]string, len(raw))
+
+	for key, value := range raw {
+		switch v := value.(type) {
+		case nil:
+			properties[key] = ""
+		case string:
+			properties[key] = v
+		case json.Number:
+			properties[key] = v.String()
+		case bool:
+			properties[key] = fmt.Sprint(v)
+		default:
+			return nil, fmt.Errorf("value of %s is not a string, number or boolean", key)
+		}
+	}
+
+	return properties, nil
+}
+
+// parseYAMLProperties parses a flat YAML mapping of scalars, keeping the scalars as written.
+func parseYAMLProperties(content []byte) (map[string]string, error) {
+	var document yaml.Node
+
+	err := yaml.Unmarshal(content, &document)
+	if err != nil {
+		return nil, err
+	}
+
+	properties := map[string]string{}
+
+	if len(document.Content) == 0 {
+		return properties, nil
+	}
+
+	mapping := document.Content[0]
+	if mapping.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("expected a mapping of properties")
+	}
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key, value := mapping.Content[i], mapping.Content[i+1]
+
+		if value.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("value of %s is not a scalar", key.Value)
+		}
+
+		if value.Tag == "!!null" {
+			properties[key.Value] = ""
+			continue
+		}
+
+		properties[key.Value] = value.Value
+	}
+
+	return properties, nil
+}
+
+// parseDotEnvProperties parses KEY=VALUE lines, with optional "export", comments and quoting.
+// Double quoted values support \n, \r, \t, \" and \\ escapes, single quoted values are literal, and both may span lines.
+func parseDotEnvProperties(content string) (map[string]string, error) {
+	properties := map[string]string{}
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+
+	for line := 1; content != ""; line++ {
+		var current string
+		current, content, _ = strings.Cut(content, "\n")
+
+		trimmed := strings.TrimSpace(current)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		trimmed = strings.TrimPrefix(trimmed, "export ")
+
+		key, value, found := strings.Cut(trimmed, "=")
+		if !found {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", line)
+		}
+
+		key = strings.TrimSpace(key)
+		value = strings.TrimLeft(value, " \t")
+
+		if value == "" || (value[0] != '"' && value[0] != '\'') {
+			if index := strings.Index(value, " #"); index >= 0 {
+				value = value[:index]
+			}
+			properties[key] = strings.TrimSpace(value)
+
+			continue
+		}
+
+		// a quoted value may continue on the following lines
+		quote := value[0]
+		rest := value[1:] + "\n" + content
+
+		parsed, consumed, err := parseQuotedValue(rest, quote)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		line += strings.Count(rest[:consumed], "\n")
+		remaining := rest[consumed:]
+		trailing, after, _ := strings.Cut(remaining, "\n")
+
+		if trailing = strings.TrimSpace(trailing); trailing != "" && !strings.HasPrefix(trailing, "#") {
+			return nil, fmt.Errorf("line %d: unexpected characters after quoted value: %s", line, trailing)
+		}
+
+		properties[key] = parsed
+		content = after
+	}
+
+	return properties, nil
+}
+
+// parseQuotedValue parses s up to the closing quote, returning the value and the number of bytes consumed.
+func parseQuotedValue(s string, quote byte) (string, int, error) {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		if c == quote {
+			return b.String(), i + 1, nil
+		}
+
+		if c == '\\' && quote == '"' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(s[i])
+			}
+
+			continue
+		}
+
+		b.WriteByte(c)
+	}
+
+	return "", 0, fmt.Errorf("unterminated %c quoted value", quote)
+}
@@ -0,0 +1,150 @@
+package params_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"bbox/pkg/params"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadPropertiesFile(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		fileName      string
+		content       string
+		expected      map[string]string
+		expectedError string
+	}{
+		{
+			name:     "dotenv",
+			fileName: "build.env",
+			content: `# release properties
+export env.TAG=1.2.3
+plain = value with spaces # comment
+empty=
+double="a&b;c=d\nnext line"
+single='literal \n $HOME'
+multiline="first
+second"
+`,
+			expected: map[string]string{
+				"env.TAG":   "1.2.3",
+				"plain":     "value with spaces",
+				"empty":     "",
+				"double":    "a&b;c=d\nnext line",
+				"single":    `literal \n $HOME`,
+				"multiline": "first\nsecond",
+			},
+		},
+		{
+			name:          "dotenv unterminated quote",
+			fileName:      ".env",
+			content:       "key=\"value\n",
+			expectedError: "unterminated",
+		},
+		{
+			name:          "dotenv missing separator",
+			fileName:      ".env",
+			content:       "key\n",
+			expectedError: "line 1: expected KEY=VALUE",
+		},
+		{
+			name:     "json",
+			fileName: "build.json",
+			content:  `{"env.TAG": "1.2.3", "retries": 3, "version": 1.10, "dryRun": false, "empty": null}`,
+			expected: map[string]string{
+				"env.TAG": "1.2.3",
+				"retries": "3",
+				"version": "1.10",
+				"dryRun":  "false",
+				"empty":   "",
+			},
+		},
+		{
+			name:          "json nested value",
+			fileName:      "build.json",
+			content:       `{"nested": {"key": "value"}}`,
+			expectedError: "nested is not a string",
+		},
+		{
+			name:     "yaml",
+			fileName: "build.yaml",
+			content: `env.TAG: "1.2.3"
+version: 1.10
+message: |
+  multi
+  line
+empty: ~
+`,
+			expected: map[string]string{
+				"env.TAG": "1.2.3",
+				"version": "1.10",
+				"message": "multi\nline\n",
+				"empty":   "",
+			},
+		},
+		{
+			name:          "yaml list value",
+			fileName:      "build.yml",
+			content:       "list:\n  - a\n",
+			expectedError: "list is not a scalar",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), tc.fileName)
+			require.NoError(t, os.WriteFile(path, []byte(tc.content), 0o600))
+
+			properties, err := params.LoadPropertiesFile(path)
+
+			if tc.expectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, properties)
+		})
+	}
+}
+
+func TestPropertiesFromEnv(t *testing.T) {
+	t.Parallel()
+
+	environ := []string{
+		"BBOX_PARAM_env__IMAGE_TAG=v1",
+		"BBOX_PARAM_release=a=b",
+		"BBOX_PARAM_=ignored",
+		"OTHER=ignored",
+	}
+
+	expected := map[string]string{
+		"env.IMAGE_TAG": "v1",
+		"release":       "a=b",
+	}
+
+	assert.Equal(t, expected, params.PropertiesFromEnv("BBOX_PARAM_", environ))
+}
+
+func TestMergeProperties(t *testing.T) {
+	t.Parallel()
+
+	file := map[string]string{"a": "file", "b": "file", "c": "file"}
+	env := map[string]string{"b": "env", "c": "env"}
+	inline := map[string]string{"c": "inline"}
+
+	expected := map[string]string{"a": "file", "b": "env", "c": "inline"}
+
+	assert.Equal(t, expected, params.MergeProperties(file, env, inline))
+}
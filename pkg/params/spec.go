@@ -0,0 +1,183 @@
+package params
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"bbox/pkg/types"
+)
+
+const (
+	ParameterTypeText     = "text"
+	ParameterTypeSelect   = "select"
+	ParameterTypeCheckbox = "checkbox"
+	ParameterTypePassword = "password"
+
+	ParameterDisplayNormal = "normal"
+	ParameterDisplayHidden = "hidden"
+	ParameterDisplayPrompt = "prompt"
+
+	validationModeNotEmpty = "not_empty"
+	validationModeRegex    = "regex"
+
+	selectOptionSeparator = "=>"
+)
+
+// ParseParameterSpec parses the raw type spec of a TeamCity typed parameter,
+// e.g. "select display='prompt' label='Environment' data_1='dev' data_2='Production => prod'".
+func ParseParameterSpec(raw string) (types.ParameterSpec, error) {
+	raw = strings.TrimSpace(raw)
+	spec := types.ParameterSpec{Type: ParameterTypeText, Display: ParameterDisplayNormal}
+
+	if raw == "" {
+		return spec, nil
+	}
+
+	specType, rest, _ := strings.Cut(raw, " ")
+	spec.Type = specType
+
+	attributes, err := parseSpecAttributes(rest)
+	if err != nil {
+		return types.ParameterSpec{}, fmt.Errorf("invalid parameter spec %q: %w", raw, err)
+	}
+
+	optionValues := map[int]string{}
+	optionLabels := map[int]string{}
+
+	for name, value := range attributes {
+		switch {
+		case name == "label":
+			spec.Label = value
+		case name == "description":
+			spec.Description = value
+		case name == "display":
+			spec.Display = value
+		case name == "validationMode":
+			spec.Required = value == validationModeNotEmpty
+		case name == "regexp":
+			spec.Regex = value
+		case name == "validationMessage":
+			spec.ValidationMessage = value
+		case name == "multiple":
+			spec.Multiple = value == "true"
+		case name == "valueSeparator":
+			spec.ValueSeparator = value
+		case name == "checkedValue":
+			spec.CheckedValue = value
+		case name == "uncheckedValue":
+			spec.UncheckedValue = value
+		case strings.HasPrefix(name, "data_"):
+			index, err := strconv.Atoi(strings.TrimPrefix(name, "data_"))
+			if err == nil {
+				optionValues[index] = value
+			}
+		case strings.HasPrefix(name, "label_"):
+			index, err := strconv.Atoi(strings.TrimPrefix(name, "label_"))
+			if err == nil {
+				optionLabels[index] = value
+			}
+		}
+	}
+
+	if validationMode := attributes["validationMode"]; validationMode != validationModeRegex {
+		spec.Regex = ""
+	}
+
+	if spec.Type == ParameterTypeCheckbox && spec.CheckedValue == "" {
+		spec.CheckedValue = "true"
+	}
+
+	if spec.Multiple && spec.ValueSeparator == "" {
+		spec.ValueSeparator = ","
+	}
+
+	indexes := make([]int, 0, len(optionValues))
+	for index := range optionValues {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	for _, index := range indexes {
+		option := types.ParameterOption{Label: optionLabels[index], Value: optionValues[index]}
+
+		if label, value, found := strings.Cut(option.Value, selectOptionSeparator); found {
+			option.Label, option.Value = strings.TrimSpace(label), strings.TrimSpace(value)
+		}
+
+		if option.Label == "" {
+			option.Label = option.Value
+		}
+
+		spec.Options = append(spec.Options, option)
+	}
+
+	return spec, nil
+}
+
+// parseSpecAttributes parses space separated name='value' pairs, where values use TeamCity's '|' escaping.
+func parseSpecAttributes(raw string) (map[string]string, error) {
+	attributes := map[string]string{}
+
+	for i := 0; i < len(raw); {
+		if raw[i] == ' ' || raw[i] == '\t' || raw[i] == '\n' || raw[i] == '\r' {
+			i++
+			continue
+		}
+
+		nameEnd := strings.Index(raw[i:], "=")
+		if nameEnd < 0 {
+			return nil, fmt.Errorf("missing value for attribute %q", strings.TrimSpace(raw[i:]))
+		}
+
+		name := strings.TrimSpace(raw[i : i+nameEnd])
+		i += nameEnd + 1
+
+		if i >= len(raw) || raw[i] != '\'' {
+			return nil, fmt.Errorf("value of attribute %q is not quoted", name)
+		}
+		i++
+
+		var value strings.Builder
+		closed := false
+
+		for i < len(raw) {
+			c := raw[i]
+
+			if c == '|' && i+1 < len(raw) {
+				value.WriteString(unescapeSpecChar(raw[i+1]))
+				i += 2
+				continue
+			}
+
+			i++
+
+			if c == '\'' {
+				closed = true
+				break
+			}
+
+			value.WriteByte(c)
+		}
+
+		if !closed {
+			return nil, fmt.Errorf("value of attribute %q is not terminated", name)
+		}
+
+		attributes[name] = value.String()
+	}
+
+	return attributes, nil
+}
+
+func unescapeSpecChar(c byte) string {
+	switch c {
+	case 'n':
+		return "\n"
+	case 'r':
+		return "\r"
+	default:
+		return string(c)
+	}
+}
@@ -0,0 +1,96 @@
+package params_test
+
+import (
+	"testing"
+
+	"bbox/pkg/params"
+	"bbox/pkg/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseParameterSpec(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		raw           string
+		expected      types.ParameterSpec
+		expectedError string
+	}{
+		{
+			name:     "empty spec",
+			raw:      "",
+			expected: types.ParameterSpec{Type: "text", Display: "normal"},
+		},
+		{
+			name: "required text",
+			raw:  "text display='prompt' label='Version' description='The version to release' validationMode='not_empty'",
+			expected: types.ParameterSpec{
+				Type:        "text",
+				Label:       "Version",
+				Description: "The version to release",
+				Display:     "prompt",
+				Required:    true,
+			},
+		},
+		{
+			name: "regex text with escaped quote",
+			raw:  `text validationMode='regex' regexp='^\d+$' validationMessage='it|'s not a number'`,
+			expected: types.ParameterSpec{
+				Type:              "text",
+				Display:           "normal",
+				Regex:             `^\d+$`,
+				ValidationMessage: "it's not a number",
+			},
+		},
+		{
+			name: "select with labels",
+			raw:  "select data_2='Production => prod' data_1='dev' label_1='Development' multiple='true'",
+			expected: types.ParameterSpec{
+				Type:    "select",
+				Display: "normal",
+				Options: []types.ParameterOption{
+					{Label: "Development", Value: "dev"},
+					{Label: "Production", Value: "prod"},
+				},
+				Multiple:       true,
+				ValueSeparator: ",",
+			},
+		},
+		{
+			name: "checkbox",
+			raw:  "checkbox uncheckedValue='no'",
+			expected: types.ParameterSpec{
+				Type:           "checkbox",
+				Display:        "normal",
+				CheckedValue:   "true",
+				UncheckedValue: "no",
+			},
+		},
+		{
+			name:          "unterminated value",
+			raw:           "text label='oops",
+			expectedError: "not terminated",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			spec, err := params.ParseParameterSpec(tc.raw)
+
+			if tc.expectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, spec)
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package params
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"bbox/pkg/types"
+)
+
+// Violation is a property that does not fit the declared parameters of a build type.
+// Warnings are problems TeamCity accepts, like unknown parameters, which only fail in strict mode.
+type Violation struct {
+	Name    string
+	Message string
+	Warning bool
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s", v.Name, v.Message)
+}
+
+// ValidateProperties checks the properties against the parameters declared on a build type.
+// It reports unknown keys as warnings, and invalid select values, failed regex validation and missing required parameters as errors.
+func ValidateProperties(properties map[string]string, parameters []types.Parameter) []Violation {
+	violations := []Violation{}
+	declared := make(map[string]bool, len(parameters))
+
+	for _, parameter := range parameters {
+		declared[parameter.Name] = true
+
+		if parameter.Type == nil {
+			continue
+		}
+
+		spec, err := ParseParameterSpec(parameter.Type.RawValue)
+		if err != nil {
+			violations = append(violations, Violation{Name: parameter.Name, Message: err.Error(), Warning: true})
+			continue
+		}
+
+		value, provided := properties[parameter.Name]
+		if !provided {
+			value = parameter.Value
+		}
+
+		violations = append(violations, ValidateValue(parameter.Name, value, provided, spec)...)
+	}
+
+	for name := range properties {
+		if !declared[name] {
+			violations = append(violations, Violation{
+				Name:    name,
+				Message: "unknown parameter, it will be added to the build as a new custom parameter",
+				Warning: true,
+			})
+		}
+	}
+
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].Name < violations[j].Name
+	})
+
+	return violations
+}
+
+// ValidateValue checks a single value against its spec. Only a provided value is checked against select options and regex.
+func ValidateValue(name, value string, provided bool, spec types.ParameterSpec) []Violation {
+	if spec.Required && strings.TrimSpace(value) == "" {
+		return []Violation{{Name: name, Message: "missing required parameter"}}
+	}
+
+	if !provided {
+		return nil
+	}
+
+	if spec.Type == ParameterTypeSelect && len(spec.Options) > 0 {
+		values := []string{value}
+		if spec.Multiple {
+			values = strings.Split(value, spec.ValueSeparator)
+		}
+
+		allowed := make([]string, 0, len(spec.Options))
+		for _, option := range spec.Options {
+			allowed = append(allowed, option.Value)
+		}
+
+		for _, v := range values {
+			if !contains(allowed, v) {
+				return []Violation{{Name: name, Message: fmt.Sprintf("invalid value %q, allowed values: %s", v, strings.Join(allowed, ", "))}}
+			}
+		}
+	}
+
+	if spec.Regex != "" {
+		// like TeamCity, the regex must match the whole value, not only a part of it
+		regex, err := regexp.Compile(`^(?:` + spec.Regex + `)$`)
+		if err != nil {
+			return []Violation{{Name: name, Message: fmt.Sprintf("invalid validation regex %q: %s", spec.Regex, err), Warning: true}}
+		}
+
+		if !regex.MatchString(value) {
+			message := fmt.Sprintf("value %q does not match %s", value, spec.Regex)
+			if spec.ValidationMessage != "" {
+				message = fmt.Sprintf("%s (%s)", message, spec.ValidationMessage)
+			}
+
+			return []Violation{{Name: name, Message: message}}
+		}
+	}
+
+	return nil
+}
+
+// CheckViolations returns an error listing the violations that fail validation. Warnings fail only in strict mode.
+func CheckViolations(violations []Violation, strict bool) error {
+	failed := []string{}
+
+	for _, violation := range violations {
+		if !violation.Warning || strict {
+			failed = append(failed, violation.String())
+		}
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("invalid properties:\n  %s", strings.Join(failed, "\n  "))
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
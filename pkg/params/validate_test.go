@@ -0,0 +1,97 @@
+package params_test
+
+import (
+	"testing"
+
+	"bbox/pkg/params"
+	"bbox/pkg/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func typedParameter(name, value, rawSpec string) types.Parameter {
+	parameter := types.Parameter{Name: name, Value: value}
+	parameter.Type = &struct {
+		RawValue string `json:"rawValue"`
+	}{RawValue: rawSpec}
+
+	return parameter
+}
+
+func TestValidateProperties(t *testing.T) {
+	t.Parallel()
+
+	parameters := []types.Parameter{
+		{Name: "env.PLAIN", Value: "default"},
+		typedParameter("env.ENVIRONMENT", "dev", "select data_1='dev' data_2='Production => prod'"),
+		typedParameter("env.REGIONS", "", "select data_1='us' data_2='eu' multiple='true' valueSeparator=','"),
+		typedParameter("env.VERSION", "", "text validationMode='regex' regexp='^\\d+\\.\\d+$' validationMessage='use major.minor'"),
+		typedParameter("env.TICKET", "", "text validationMode='not_empty'"),
+		typedParameter("env.BUILD", "", "text validationMode='regex' regexp='\\d+'"),
+	}
+
+	testCases := []struct {
+		name       string
+		properties map[string]string
+		expected   []params.Violation
+	}{
+		{
+			name: "valid properties",
+			properties: map[string]string{
+				"env.PLAIN":       "anything",
+				"env.ENVIRONMENT": "prod",
+				"env.REGIONS":     "us,eu",
+				"env.VERSION":     "1.2",
+				"env.TICKET":      "OPS-1",
+				"env.BUILD":       "42",
+			},
+			expected: []params.Violation{},
+		},
+		{
+			name: "invalid properties",
+			properties: map[string]string{
+				"env.TYPO":        "value",
+				"env.ENVIRONMENT": "Production",
+				"env.REGIONS":     "us,asia",
+				"env.VERSION":     "latest",
+				"env.BUILD":       "abc1",
+			},
+			expected: []params.Violation{
+				{Name: "env.BUILD", Message: `value "abc1" does not match \d+`},
+				{Name: "env.ENVIRONMENT", Message: `invalid value "Production", allowed values: dev, prod`},
+				{Name: "env.REGIONS", Message: `invalid value "asia", allowed values: us, eu`},
+				{Name: "env.TICKET", Message: "missing required parameter"},
+				{Name: "env.TYPO", Message: "unknown parameter, it will be added to the build as a new custom parameter", Warning: true},
+				{Name: "env.VERSION", Message: `value "latest" does not match ^\d+\.\d+$ (use major.minor)`},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, params.ValidateProperties(tc.properties, parameters))
+		})
+	}
+}
+
+func TestCheckViolations(t *testing.T) {
+	t.Parallel()
+
+	warning := params.Violation{Name: "env.TYPO", Message: "unknown parameter", Warning: true}
+	failure := params.Violation{Name: "env.TICKET", Message: "missing required parameter"}
+
+	assert.NoError(t, params.CheckViolations([]params.Violation{warning}, false))
+
+	err := params.CheckViolations([]params.Violation{warning}, true)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "env.TYPO: unknown parameter")
+
+	err = params.CheckViolations([]params.Violation{warning, failure}, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "env.TICKET: missing required parameter")
+	assert.NotContains(t, err.Error(), "env.TYPO")
+}
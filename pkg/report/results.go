@@ -0,0 +1,72 @@
+package report
+
+import (
+	"io"
+	"strconv"
+	"time"
+
+	"bbox/pkg/types"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// constituentPrefix is prepended to the name of the constituent builds of a composite build, listed under it.
+const constituentPrefix = "└ "
+
+// ResultsTable renders the results of triggered builds, listing the constituent builds of composite builds under them.
+func ResultsTable(w io.Writer, results []types.BuildResult) {
+	table := tablewriter.NewWriter(w)
+	table.SetRowLine(true)
+	table.SetHeader([]string{"Build Name", "Branch Name", "Build Status", "Duration", "Artifacts Downloaded", "Error", "Web URL"})
+	table.SetHeaderColor(tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiCyanColor}, tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiCyanColor}, tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiCyanColor}, tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiCyanColor}, tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiCyanColor}, tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiCyanColor}, tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiCyanColor})
+	table.SetBorders(tablewriter.Border{Left: false, Top: true, Right: false, Bottom: true})
+
+	var data [][]string
+
+	for _, result := range results {
+		data = append(data, resultRow(result, ""))
+
+		for _, constituent := range result.Constituents {
+			data = append(data, resultRow(constituent, constituentPrefix))
+		}
+	}
+
+	for _, row := range data {
+		status := types.BuildStatus(row[2])
+		statusColor := tablewriter.FgHiRedColor
+
+		switch {
+		case status.IsSuccessful():
+			statusColor = tablewriter.FgHiGreenColor
+		case status.WasCanceled():
+			statusColor = tablewriter.FgHiYellowColor
+		}
+
+		err := row[5]
+		errorColor := tablewriter.FgHiRedColor
+
+		if err == "None" {
+			errorColor = tablewriter.FgWhiteColor
+		}
+
+		// color row cells
+		table.Rich(row, []tablewriter.Colors{{}, {}, {tablewriter.Bold, statusColor}, {}, {}, {tablewriter.Bold, errorColor}, {}})
+	}
+
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.Render()
+}
+
+func resultRow(result types.BuildResult, prefix string) []string {
+	errorMessage := "None"
+	if result.Error != nil {
+		errorMessage = result.Error.Error()
+	}
+
+	duration := "-"
+	if result.Duration > 0 {
+		duration = result.Duration.Round(time.Second).String()
+	}
+
+	return []string{prefix + result.BuildName, result.BranchName, string(result.BuildStatus), duration, strconv.FormatBool(result.DownloadedArtifacts), errorMessage, result.WebURL}
+}
@@ -0,0 +1,71 @@
+package types
+
+// BuildStatus is the status of a build as reported by TeamCity, or as derived by bbox for builds that did not finish normally.
+type BuildStatus string
+
+const (
+	BuildStatusSuccess BuildStatus = "SUCCESS"
+	BuildStatusFailure BuildStatus = "FAILURE"
+	BuildStatusError   BuildStatus = "ERROR"
+	BuildStatusUnknown BuildStatus = "UNKNOWN"
+
+	// BuildStatusCanceled is derived from the canceled info of a build, TeamCity reports canceled builds as UNKNOWN or FAILURE
+	BuildStatusCanceled BuildStatus = "CANCELED"
+	// BuildStatusFailedToStart is derived from the failedToStart flag of a build
+	BuildStatusFailedToStart BuildStatus = "FAILED_TO_START"
+	// BuildStatusNotTriggered is used for builds that bbox failed to trigger
+	BuildStatusNotTriggered BuildStatus = "NOT_TRIGGERED"
+)
+
+// IsSuccessful returns true if the build succeeded.
+func (s BuildStatus) IsSuccessful() bool {
+	return s == BuildStatusSuccess
+}
+
+// WasCanceled returns true if the build was canceled.
+func (s BuildStatus) WasCanceled() bool {
+	return s == BuildStatusCanceled
+}
+
+// BuildState is the lifecycle state of a build.
+type BuildState string
+
+const (
+	BuildStateQueued   BuildState = "queued"
+	BuildStateRunning  BuildState = "running"
+	BuildStateFinished BuildState = "finished"
+	// BuildStateDeleted is the state of a build removed from the queue or from the history
+	BuildStateDeleted BuildState = "deleted"
+	BuildStateUnknown BuildState = "unknown"
+)
+
+// IsTerminal returns true if the build will not change state anymore.
+func (s BuildState) IsTerminal() bool {
+	return s == BuildStateFinished || s == BuildStateDeleted
+}
+
+// CanceledInfo describes who canceled a build and why.
+type CanceledInfo struct {
+	Timestamp string `json:"timestamp"`
+	Text      string `json:"text"`
+	User      struct {
+		Username string `json:"username"`
+		Name     string `json:"name"`
+	} `json:"user"`
+}
+
+// buildResult derives the result of a build from its status and state, reporting canceled builds and builds that failed to start as such.
+func buildResult(status BuildStatus, state BuildState, canceledInfo *CanceledInfo, failedToStart bool) BuildStatus {
+	switch {
+	case canceledInfo != nil:
+		return BuildStatusCanceled
+	case failedToStart:
+		return BuildStatusFailedToStart
+	case state == BuildStateDeleted:
+		return BuildStatusCanceled
+	case status == "":
+		return BuildStatusUnknown
+	default:
+		return status
+	}
+}
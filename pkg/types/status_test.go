@@ -0,0 +1,68 @@
+package types_test
+
+import (
+	"testing"
+
+	"bbox/pkg/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildStatusResponseResult(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		response types.BuildStatusResponse
+		expected types.BuildStatus
+	}{
+		{
+			name:     "success",
+			response: types.BuildStatusResponse{Status: types.BuildStatusSuccess, State: types.BuildStateFinished},
+			expected: types.BuildStatusSuccess,
+		},
+		{
+			name:     "failure",
+			response: types.BuildStatusResponse{Status: types.BuildStatusFailure, State: types.BuildStateFinished},
+			expected: types.BuildStatusFailure,
+		},
+		{
+			name:     "canceled while running",
+			response: types.BuildStatusResponse{Status: types.BuildStatusFailure, State: types.BuildStateFinished, CanceledInfo: &types.CanceledInfo{}},
+			expected: types.BuildStatusCanceled,
+		},
+		{
+			name:     "removed from the queue",
+			response: types.BuildStatusResponse{State: types.BuildStateDeleted},
+			expected: types.BuildStatusCanceled,
+		},
+		{
+			name:     "failed to start",
+			response: types.BuildStatusResponse{Status: types.BuildStatusUnknown, State: types.BuildStateFinished, FailedToStart: true},
+			expected: types.BuildStatusFailedToStart,
+		},
+		{
+			name:     "still queued",
+			response: types.BuildStatusResponse{State: types.BuildStateQueued},
+			expected: types.BuildStatusUnknown,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, tc.response.Result())
+		})
+	}
+}
+
+func TestBuildStateIsTerminal(t *testing.T) {
+	t.Parallel()
+
+	assert.False(t, types.BuildStateQueued.IsTerminal())
+	assert.False(t, types.BuildStateRunning.IsTerminal())
+	assert.True(t, types.BuildStateFinished.IsTerminal())
+	assert.True(t, types.BuildStateDeleted.IsTerminal())
+}
@@ -1,65 +1,126 @@
 package types
 
+import (
+	"time"
+)
+
 type BuildStatusResponse struct {
-	ID        int    `json:"id"`
-	Status    string `json:"status"`
-	State     string `json:"state"`
+	ID            int           `json:"id"`
+	Status        BuildStatus   `json:"status"`
+	State         BuildState    `json:"state"`
+	CanceledInfo  *CanceledInfo `json:"canceledInfo,omitempty"`
+	FailedToStart bool          `json:"failedToStart"`
+	BuildTypeID   string        `json:"buildTypeId"`
+	BranchName    string        `json:"branchName"`
+	WebURL        string        `json:"webUrl"`
+	Number        string        `json:"number"`
+	StartDate     string        `json:"startDate"`
+	FinishDate    string        `json:"finishDate"`
+	WaitReason    string        `json:"waitReason"`
+	// PercentageComplete is only reported while the build is running
+	PercentageComplete int          `json:"percentageComplete"`
+	RunningInfo        *RunningInfo `json:"running-info,omitempty"`
+	// Composite is true for composite builds, whose constituent builds are their snapshot dependencies
+	Composite bool      `json:"composite"`
+	BuildType BuildType `json:"buildType"`
 	Artifacts struct {
 		Href string `json:"href"`
 	} `json:"artifacts"`
-	SnapshotDependencies struct {
-		Count int `json:"count"`
-		Build []struct {
-			ID                  int    `json:"id"`
-			BuildTypeID         string `json:"buildTypeId"`
-			State               string `json:"state"`
-			BranchName          string `json:"branchName"`
-			Href                string `json:"href"`
-			WebURL              string `json:"webUrl"`
-			Customized          bool   `json:"customized"`
-			MatrixConfiguration struct {
-				Enabled bool `json:"enabled"`
-			} `json:"matrixConfiguration"`
-		} `json:"build"`
-	} `json:"snapshot-dependencies"`
+	SnapshotDependencies Dependencies `json:"snapshot-dependencies"`
+	// ArtifactDependencies are only set when requested in the fields of the request
+	ArtifactDependencies Dependencies `json:"artifact-dependencies"`
+}
+
+// Dependencies are the builds a build depends on, e.g. its snapshot dependencies.
+type Dependencies struct {
+	Count int               `json:"count"`
+	Build []DependencyBuild `json:"build"`
+}
+
+// DependencyBuild is a build another build depends on.
+type DependencyBuild struct {
+	ID                  int        `json:"id"`
+	BuildTypeID         string     `json:"buildTypeId"`
+	State               BuildState `json:"state"`
+	BranchName          string     `json:"branchName"`
+	DefaultBranch       bool       `json:"defaultBranch"`
+	Href                string     `json:"href"`
+	WebURL              string     `json:"webUrl"`
+	Customized          bool       `json:"customized"`
+	MatrixConfiguration struct {
+		Enabled bool `json:"enabled"`
+	} `json:"matrixConfiguration"`
+}
+
+// RunningInfo is the progress of a running build.
+type RunningInfo struct {
+	PercentageComplete    int    `json:"percentageComplete"`
+	ElapsedSeconds        int    `json:"elapsedSeconds"`
+	EstimatedTotalSeconds int    `json:"estimatedTotalSeconds"`
+	LeftSeconds           int    `json:"leftSeconds"`
+	CurrentStageText      string `json:"currentStageText"`
+	Outdated              bool   `json:"outdated"`
+	ProbablyHanging       bool   `json:"probablyHanging"`
+}
+
+// Result returns the status of the build, or CANCELED and FAILED_TO_START for builds that did not finish normally.
+func (bsr BuildStatusResponse) Result() BuildStatus {
+	return buildResult(bsr.Status, bsr.State, bsr.CanceledInfo, bsr.FailedToStart)
+}
+
+// Duration returns how long the build ran, or 0 if it did not start or finish.
+func (bsr BuildStatusResponse) Duration() time.Duration {
+	return buildDuration(bsr.StartDate, bsr.FinishDate)
+}
+
+func buildDuration(startDate, finishDate string) time.Duration {
+	start, err := time.Parse(TeamCityTimeLayout, startDate)
+	if err != nil {
+		return 0
+	}
+
+	finish, err := time.Parse(TeamCityTimeLayout, finishDate)
+	if err != nil {
+		return 0
+	}
+
+	return finish.Sub(start)
 }
 
 type BuildResult struct {
 	BuildName           string
 	WebURL              string
 	BranchName          string
-	BuildStatus         string
+	BuildStatus         BuildStatus
+	Duration            time.Duration
 	DownloadedArtifacts bool
 	Error               error
+	// Constituents are the results of the constituent builds of a composite build
+	Constituents []BuildResult
 }
 
 // BuildParameters Definition to hold each combination.
 type BuildParameters struct {
 	BuildTypeID       string
+	BuildTypeQuery    string
 	BranchName        string
 	DownloadArtifacts bool
 	PropertiesFlag    map[string]string
+	// ArtifactsPath is the directory the artifacts of the combination are downloaded to, instead of the artifacts path and layout of multi-trigger
+	ArtifactsPath string
 }
 
 type TriggerBuildWithParametersResponse struct {
-	ID          int    `json:"id"`
-	BuildTypeID string `json:"buildTypeId"`
-	State       string `json:"state"`
-	Composite   bool   `json:"composite"`
-	Href        string `json:"href"`
-	WebURL      string `json:"webUrl"`
-	BuildType   struct {
-		ID          string `json:"id"`
-		Name        string `json:"name"`
-		Description string `json:"description"`
-		ProjectName string `json:"projectName"`
-		ProjectID   string `json:"projectId"`
-		Href        string `json:"href"`
-		WebURL      string `json:"webUrl"`
-	} `json:"buildType"`
-	WaitReason string `json:"waitReason"`
-	QueuedDate string `json:"queuedDate"`
-	Triggered  struct {
+	ID          int       `json:"id"`
+	BuildTypeID string    `json:"buildTypeId"`
+	State       string    `json:"state"`
+	Composite   bool      `json:"composite"`
+	Href        string    `json:"href"`
+	WebURL      string    `json:"webUrl"`
+	BuildType   BuildType `json:"buildType"`
+	WaitReason  string    `json:"waitReason"`
+	QueuedDate  string    `json:"queuedDate"`
+	Triggered   struct {
 		Type string `json:"type"`
 		Date string `json:"date"`
 		User struct {
@@ -69,21 +130,7 @@ type TriggerBuildWithParametersResponse struct {
 			Href     string `json:"href"`
 		} `json:"user"`
 	} `json:"triggered"`
-	SnapshotDependencies struct {
-		Count int `json:"count"`
-		Build []struct {
-			ID                  int    `json:"id"`
-			BuildTypeID         string `json:"buildTypeId"`
-			State               string `json:"state"`
-			BranchName          string `json:"branchName"`
-			DefaultBranch       bool   `json:"defaultBranch"`
-			Href                string `json:"href"`
-			WebURL              string `json:"webUrl"`
-			MatrixConfiguration struct {
-				Enabled bool `json:"enabled"`
-			} `json:"matrixConfiguration"`
-		} `json:"build"`
-	} `json:"snapshot-dependencies"`
+	SnapshotDependencies Dependencies `json:"snapshot-dependencies"`
 }
 
 type BuildType struct {
@@ -94,17 +141,275 @@ type BuildType struct {
 	ProjectID   string `json:"projectId"`
 	Href        string `json:"href"`
 	WebURL      string `json:"webUrl"`
+	// SnapshotDependencies and ArtifactDependencies are only set when requested in the fields of the request
+	SnapshotDependencies *BuildTypeDependencies `json:"snapshot-dependencies,omitempty"`
+	ArtifactDependencies *BuildTypeDependencies `json:"artifact-dependencies,omitempty"`
+}
+
+// BuildTypeDependencies are the snapshot or artifact dependencies of a build type, listed by TeamCity under "snapshot-dependency" or "artifact-dependency".
+type BuildTypeDependencies struct {
+	Count              int                   `json:"count"`
+	SnapshotDependency []BuildTypeDependency `json:"snapshot-dependency,omitempty"`
+	ArtifactDependency []BuildTypeDependency `json:"artifact-dependency,omitempty"`
+}
+
+// BuildTypeDependency is a dependency of a build type on its source build type.
+type BuildTypeDependency struct {
+	SourceBuildType BuildType `json:"source-buildType"`
+}
+
+// SourceBuildTypes returns the build types depended on.
+func (d *BuildTypeDependencies) SourceBuildTypes() []BuildType {
+	if d == nil {
+		return nil
+	}
+
+	sources := make([]BuildType, 0, len(d.SnapshotDependency)+len(d.ArtifactDependency))
+	for _, dependency := range d.SnapshotDependency {
+		sources = append(sources, dependency.SourceBuildType)
+	}
+
+	for _, dependency := range d.ArtifactDependency {
+		sources = append(sources, dependency.SourceBuildType)
+	}
+
+	return sources
 }
 
 type ArtifactChildren struct {
-	Count int `json:"count"`
-	File  []struct {
-		Name             string `json:"name"`
-		Size             int    `json:"size"`
-		ModificationTime string `json:"modificationTime"`
-		Href             string `json:"href"`
-		Content          struct {
-			Href string `json:"href"`
-		} `json:"content"`
-	} `json:"file"`
+	Count int            `json:"count"`
+	File  []ArtifactFile `json:"file"`
+}
+
+// ArtifactFile is a file or directory in the artifacts of a build.
+type ArtifactFile struct {
+	Name string `json:"name"`
+	// FullName is the path of the file relative to the artifacts root, e.g. "dist/app.jar"
+	FullName         string `json:"fullName"`
+	Size             int64  `json:"size"`
+	ModificationTime string `json:"modificationTime"`
+	Href             string `json:"href"`
+	// Content is set for files, and Children for directories and archives
+	Content struct {
+		Href string `json:"href"`
+	} `json:"content"`
+	Children struct {
+		Href string `json:"href"`
+	} `json:"children"`
+}
+
+// IsDir returns true if the artifact is a directory.
+func (f ArtifactFile) IsDir() bool {
+	return f.Content.Href == "" && f.Children.Href != ""
+}
+
+// ArtifactsManifest is the record of the artifacts downloaded from a build, written next to the downloaded files.
+type ArtifactsManifest struct {
+	BuildID      int            `json:"buildId"`
+	BuildTypeID  string         `json:"buildTypeId"`
+	DownloadedAt time.Time      `json:"downloadedAt"`
+	Files        []ManifestFile `json:"files"`
+}
+
+// ManifestFile is a downloaded artifact file in an ArtifactsManifest.
+type ManifestFile struct {
+	// Path is the slash separated path of the downloaded file, relative to the manifest
+	Path string `json:"path"`
+	// ArtifactPath is the path of the file in the artifacts of the build, which differs from Path for artifact rules with a destination.
+	// It is empty for the archive of all artifacts, kept when downloading them without extracting them
+	ArtifactPath string `json:"artifactPath"`
+	Size         int64  `json:"size"`
+	SHA256       string `json:"sha256"`
+	URL          string `json:"url,omitempty"`
+}
+
+type BuildTypesResponse struct {
+	Count      int         `json:"count"`
+	BuildTypes []BuildType `json:"buildType"`
+}
+
+// FullName returns the build type path as shown in the TeamCity UI, e.g. "Project / Sub / Build name".
+func (bt BuildType) FullName() string {
+	if bt.ProjectName == "" {
+		return bt.Name
+	}
+
+	return bt.ProjectName + " / " + bt.Name
+}
+
+type Branch struct {
+	Name    string `json:"name"`
+	Default bool   `json:"default"`
+}
+
+type BranchesResponse struct {
+	Count    int      `json:"count"`
+	Branches []Branch `json:"branch"`
+}
+
+// Parameter is a build type parameter, with its raw type spec if it was declared as a typed parameter.
+type Parameter struct {
+	Name      string `json:"name"`
+	Value     string `json:"value"`
+	Inherited bool   `json:"inherited"`
+	Type      *struct {
+		RawValue string `json:"rawValue"`
+	} `json:"type,omitempty"`
+}
+
+type ParametersResponse struct {
+	Count      int         `json:"count"`
+	Parameters []Parameter `json:"property"`
+}
+
+// ParameterSpec is the parsed type spec of a typed build type parameter.
+type ParameterSpec struct {
+	Type              string
+	Label             string
+	Description       string
+	Display           string
+	Required          bool
+	Regex             string
+	ValidationMessage string
+	Options           []ParameterOption
+	Multiple          bool
+	ValueSeparator    string
+	CheckedValue      string
+	UncheckedValue    string
+}
+
+type ParameterOption struct {
+	Label string
+	Value string
+}
+
+// TeamCityTimeLayout is the layout of dates in the TeamCity REST API and locators.
+const TeamCityTimeLayout = "20060102T150405-0700"
+
+// Build is a build as listed by the builds endpoint.
+type Build struct {
+	ID            int           `json:"id"`
+	BuildTypeID   string        `json:"buildTypeId"`
+	Number        string        `json:"number"`
+	Status        BuildStatus   `json:"status"`
+	State         BuildState    `json:"state"`
+	CanceledInfo  *CanceledInfo `json:"canceledInfo,omitempty"`
+	FailedToStart bool          `json:"failedToStart,omitempty"`
+	BranchName    string        `json:"branchName"`
+	StatusText    string        `json:"statusText"`
+	WebURL        string        `json:"webUrl"`
+	QueuedDate    string        `json:"queuedDate"`
+	StartDate     string        `json:"startDate"`
+	FinishDate    string        `json:"finishDate"`
+	BuildType     BuildType     `json:"buildType"`
+	Triggered     struct {
+		Type string `json:"type"`
+		User struct {
+			Username string `json:"username"`
+			Name     string `json:"name"`
+		} `json:"user"`
+	} `json:"triggered"`
+}
+
+// Result returns the status of the build, or CANCELED and FAILED_TO_START for builds that did not finish normally.
+func (b Build) Result() BuildStatus {
+	return buildResult(b.Status, b.State, b.CanceledInfo, b.FailedToStart)
+}
+
+// Duration returns how long the build ran, or 0 if it did not start or finish.
+func (b Build) Duration() time.Duration {
+	return buildDuration(b.StartDate, b.FinishDate)
+}
+
+type BuildsResponse struct {
+	Count    int     `json:"count"`
+	NextHref string  `json:"nextHref"`
+	Builds   []Build `json:"build"`
+}
+
+// BuildLocator filters builds. Empty fields are not filtered on.
+type BuildLocator struct {
+	BuildTypeID string
+	ProjectID   string
+	// Branch is the branch name, builds of all branches are listed if empty
+	Branch string
+	// Revision is the VCS revision the builds were built from, e.g. a commit SHA
+	Revision  string
+	Status    string
+	State     string
+	User      string
+	Tags      []string
+	SinceDate time.Time
+	UntilDate time.Time
+	// SinceBuildID only lists the builds started after this build, and UntilBuildID the builds started up to and including it
+	SinceBuildID int
+	UntilBuildID int
+	Running      *bool
+	Canceled     *bool
+	Pinned       *bool
+	// Count is the maximum number of builds, all matching builds are listed if 0
+	Count int
+}
+
+// Change is a VCS change (commit) known to TeamCity.
+type Change struct {
+	ID       int    `json:"id"`
+	Version  string `json:"version"`
+	Username string `json:"username"`
+	Date     string `json:"date"`
+	Comment  string `json:"comment"`
+	WebURL   string `json:"webUrl"`
+	// VcsRootInstance is the VCS root the change was detected in
+	VcsRootInstance struct {
+		ID        string `json:"id"`
+		VcsRootID string `json:"vcs-root-id"`
+		Name      string `json:"name"`
+	} `json:"vcsRootInstance"`
+	Files struct {
+		File []ChangeFile `json:"file"`
+	} `json:"files"`
+}
+
+// ChangeFile is a file modified by a VCS change.
+type ChangeFile struct {
+	File         string `json:"file"`
+	RelativeFile string `json:"relative-file"`
+	// ChangeType is added, edited, removed, copied or unchanged
+	ChangeType string `json:"changeType"`
+}
+
+type ChangesResponse struct {
+	Count    int      `json:"count"`
+	NextHref string   `json:"nextHref"`
+	Changes  []Change `json:"change"`
+}
+
+const (
+	DependencySnapshot = "snapshot"
+	DependencyArtifact = "artifact"
+)
+
+// DependencyGraph is the snapshot and artifact dependency graph of a build or a build type.
+type DependencyGraph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a build type, or an actual build of it when the graph is of a build.
+type GraphNode struct {
+	ID          string      `json:"id"`
+	Label       string      `json:"label"`
+	BuildTypeID string      `json:"buildTypeId"`
+	BuildID     int         `json:"buildId,omitempty"`
+	Number      string      `json:"number,omitempty"`
+	Status      BuildStatus `json:"status,omitempty"`
+	State       BuildState  `json:"state,omitempty"`
+	WebURL      string      `json:"webUrl,omitempty"`
+}
+
+// GraphEdge is a dependency of the From node on the To node, of type snapshot or artifact.
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Type string `json:"type"`
 }
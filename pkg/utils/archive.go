@@ -0,0 +1,188 @@
+package utils
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	log "github.com/sirupsen/logrus"
+)
+
+// ExtractFunc extracts an archive to destDir, applying the limits and policies of opts, and returns the paths of the extracted files.
+type ExtractFunc func(archivePath, destDir string, opts ExtractOptions) ([]string, error)
+
+var (
+	archiveFormatsMu sync.RWMutex
+	// archiveFormats are the extractors of the supported archives, by file name suffix
+	archiveFormats = map[string]ExtractFunc{
+		".zip":     UnzipFileWithOptions,
+		".tar":     ExtractTarFile,
+		".tar.gz":  ExtractTarGzFile,
+		".tgz":     ExtractTarGzFile,
+		".tar.zst": ExtractTarZstFile,
+		".tzst":    ExtractTarZstFile,
+	}
+)
+
+// RegisterArchiveFormat registers the extractor of archives whose file name ends with suffix, e.g. ".tar.xz", replacing the one registered before.
+func RegisterArchiveFormat(suffix string, extract ExtractFunc) {
+	archiveFormatsMu.Lock()
+	defer archiveFormatsMu.Unlock()
+
+	archiveFormats[strings.ToLower(suffix)] = extract
+}
+
+// ArchiveFormats returns the file name suffixes of the supported archives, sorted.
+func ArchiveFormats() []string {
+	archiveFormatsMu.RLock()
+	defer archiveFormatsMu.RUnlock()
+
+	suffixes := make([]string, 0, len(archiveFormats))
+	for suffix := range archiveFormats {
+		suffixes = append(suffixes, suffix)
+	}
+
+	sort.Strings(suffixes)
+
+	return suffixes
+}
+
+// ArchiveExtractor returns the extractor of an archive by its file name, matching the longest registered suffix, so "a.tar.gz" is not extracted as a ".gz".
+func ArchiveExtractor(name string) (ExtractFunc, bool) {
+	archiveFormatsMu.RLock()
+	defer archiveFormatsMu.RUnlock()
+
+	suffix := archiveSuffix(name)
+	if suffix == "" {
+		return nil, false
+	}
+
+	return archiveFormats[suffix], true
+}
+
+// ArchiveBaseName returns the file name of an archive without its archive suffix, e.g. "bundle" for "bundle.tar.gz".
+func ArchiveBaseName(name string) string {
+	archiveFormatsMu.RLock()
+	defer archiveFormatsMu.RUnlock()
+
+	return name[:len(name)-len(archiveSuffix(name))]
+}
+
+// archiveSuffix returns the longest registered archive suffix name ends with, or an empty string, with archiveFormatsMu held.
+func archiveSuffix(name string) string {
+	name = strings.ToLower(name)
+
+	var match string
+	for suffix := range archiveFormats {
+		if strings.HasSuffix(name, suffix) && len(suffix) > len(match) {
+			match = suffix
+		}
+	}
+
+	return match
+}
+
+// ExtractArchive extracts an archive of any registered format to destDir, and returns the paths of the extracted files.
+func ExtractArchive(archivePath, destDir string, opts ExtractOptions) ([]string, error) {
+	extract, ok := ArchiveExtractor(archivePath)
+	if !ok {
+		return nil, fmt.Errorf("unsupported archive %s, expected one of: %s", archivePath, strings.Join(ArchiveFormats(), ", "))
+	}
+
+	return extract(archivePath, destDir, opts)
+}
+
+// ExtractTarFile extracts an uncompressed tar archive to destDir, see ExtractTar.
+func ExtractTarFile(tarFilePath, destDir string, opts ExtractOptions) ([]string, error) {
+	return extractTarFile(tarFilePath, destDir, opts, func(r io.Reader) (io.ReadCloser, error) {
+		return io.NopCloser(r), nil
+	})
+}
+
+// ExtractTarGzFile extracts a gzip compressed tar archive to destDir, see ExtractTar.
+func ExtractTarGzFile(tarFilePath, destDir string, opts ExtractOptions) ([]string, error) {
+	return extractTarFile(tarFilePath, destDir, opts, func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	})
+}
+
+// ExtractTarZstFile extracts a zstandard compressed tar archive to destDir, see ExtractTar.
+func ExtractTarZstFile(tarFilePath, destDir string, opts ExtractOptions) ([]string, error) {
+	return extractTarFile(tarFilePath, destDir, opts, func(r io.Reader) (io.ReadCloser, error) {
+		decoder, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+
+		return decoder.IOReadCloser(), nil
+	})
+}
+
+func extractTarFile(tarFilePath, destDir string, opts ExtractOptions, decompress func(io.Reader) (io.ReadCloser, error)) ([]string, error) {
+	log.Debugf("Extracting file %s to %s", tarFilePath, destDir)
+
+	f, err := os.Open(tarFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening tar file: %w", err)
+	}
+	defer f.Close()
+
+	r, err := decompress(f)
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing %s: %w", tarFilePath, err)
+	}
+	defer r.Close()
+
+	return ExtractTar(r, destDir, opts)
+}
+
+// ExtractTar extracts a tar stream to destDir with the same protections as UnzipFileWithOptions, and returns the paths of the extracted files.
+// Hard links, devices and other special files are skipped with a warning.
+func ExtractTar(r io.Reader, destDir string, opts ExtractOptions) ([]string, error) {
+	extractor, err := NewExtractor(destDir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	tr := tar.NewReader(r)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return extractor.Files(), fmt.Errorf("error reading tar: %w", err)
+		}
+
+		err = extractTarEntry(extractor, tr, header)
+		if err != nil {
+			return extractor.Files(), err
+		}
+	}
+
+	return extractor.Files(), extractor.Finish()
+}
+
+func extractTarEntry(extractor *Extractor, tr *tar.Reader, header *tar.Header) error {
+	switch header.Typeflag {
+	case tar.TypeDir:
+		return extractor.Dir(header.Name)
+	case tar.TypeReg:
+		return extractor.File(header.Name, tr, os.FileMode(header.Mode).Perm())
+	case tar.TypeSymlink:
+		return extractor.Symlink(header.Name, header.Linkname)
+	case tar.TypeXGlobalHeader:
+		return nil
+	default:
+		log.Warnf("skipping %s in archive, tar entries of type %q are not supported", header.Name, header.Typeflag)
+		return nil
+	}
+}
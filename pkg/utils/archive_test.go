@@ -0,0 +1,211 @@
+package utils_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"bbox/pkg/utils"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tarEntry struct {
+	name     string
+	content  string
+	typeflag byte
+	linkname string
+}
+
+// writeTar writes a tar archive with the entries, compressed according to the suffix of name, and returns its path.
+func writeTar(t *testing.T, name string, entries ...tarEntry) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tarWriter := tar.NewWriter(&buf)
+
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Typeflag: entry.typeflag, Linkname: entry.linkname, Mode: 0o644, Size: int64(len(entry.content))}
+		if entry.typeflag == 0 {
+			header.Typeflag = tar.TypeReg
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			header.Size = 0
+		}
+
+		require.NoError(t, tarWriter.WriteHeader(header))
+
+		_, err := tarWriter.Write([]byte(entry.content[:header.Size]))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tarWriter.Close())
+
+	tarPath := filepath.Join(t.TempDir(), name)
+
+	file, err := os.Create(tarPath)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var w io.WriteCloser
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".gz", ".tgz":
+		w = gzip.NewWriter(file)
+	case ".zst", ".tzst":
+		w, err = zstd.NewWriter(file)
+		require.NoError(t, err)
+	default:
+		_, err = io.Copy(file, &buf)
+		require.NoError(t, err)
+
+		return tarPath
+	}
+
+	_, err = io.Copy(w, &buf)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	return tarPath
+}
+
+func TestExtractArchive(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{"bundle.tar", "bundle.tar.gz", "bundle.tgz", "bundle.tar.zst", "BUNDLE.TZST"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			archive := writeTar(t, name,
+				tarEntry{name: "dist/", typeflag: tar.TypeDir},
+				tarEntry{name: "dist/app.jar", content: "jar"},
+				tarEntry{name: "README.md", content: "readme"},
+			)
+
+			dest := t.TempDir()
+
+			files, err := utils.ExtractArchive(archive, dest, utils.DefaultExtractOptions)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"dist/app.jar", "README.md"}, files)
+
+			content, err := os.ReadFile(filepath.Join(dest, "dist", "app.jar"))
+			require.NoError(t, err)
+			assert.Equal(t, "jar", string(content))
+		})
+	}
+}
+
+func TestExtractArchiveZip(t *testing.T) {
+	t.Parallel()
+
+	files, err := utils.ExtractArchive(writeZip(t, zipEntry{name: "app.jar", content: "jar"}), t.TempDir(), utils.DefaultExtractOptions)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app.jar"}, files)
+}
+
+func TestExtractArchiveUnsupported(t *testing.T) {
+	t.Parallel()
+
+	_, err := utils.ExtractArchive("bundle.rar", t.TempDir(), utils.DefaultExtractOptions)
+	assert.ErrorContains(t, err, "unsupported archive bundle.rar")
+}
+
+func TestExtractTarRejectsUnsafeEntries(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		entries []tarEntry
+		opts    utils.ExtractOptions
+		err     error
+	}{
+		{
+			name:    "parent directory",
+			entries: []tarEntry{{name: "../evil.txt", content: "pwned"}},
+			err:     utils.ErrUnsafeArchivePath,
+		},
+		{
+			name:    "absolute",
+			entries: []tarEntry{{name: "/tmp/evil.txt", content: "pwned"}},
+			err:     utils.ErrUnsafeArchivePath,
+		},
+		{
+			name:    "symlink outside",
+			entries: []tarEntry{{name: "link", typeflag: tar.TypeSymlink, linkname: "../../etc"}},
+			opts:    utils.ExtractOptions{Symlinks: utils.SymlinkAllowInside},
+			err:     utils.ErrUnsafeArchivePath,
+		},
+		{
+			name:    "too large",
+			entries: []tarEntry{{name: "big.bin", content: "0123456789"}},
+			opts:    utils.ExtractOptions{MaxTotalSize: 5},
+			err:     utils.ErrArchiveTooLarge,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			parent, dest := extractDirs(t)
+
+			_, err := utils.ExtractArchive(writeTar(t, "archive.tgz", tc.entries...), dest, tc.opts)
+			assert.ErrorIs(t, err, tc.err)
+			assert.NoFileExists(t, filepath.Join(parent, "evil.txt"))
+		})
+	}
+}
+
+func TestExtractTarSkipsHardLinks(t *testing.T) {
+	t.Parallel()
+
+	dest := t.TempDir()
+
+	files, err := utils.ExtractArchive(writeTar(t, "archive.tar",
+		tarEntry{name: "app.jar", content: "jar"},
+		tarEntry{name: "copy.jar", typeflag: tar.TypeLink, linkname: "app.jar"},
+	), dest, utils.DefaultExtractOptions)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app.jar"}, files)
+	assert.NoFileExists(t, filepath.Join(dest, "copy.jar"))
+}
+
+func TestArchiveExtractor(t *testing.T) {
+	t.Parallel()
+
+	_, ok := utils.ArchiveExtractor("dist/bundle.tar.gz")
+	assert.True(t, ok)
+
+	_, ok = utils.ArchiveExtractor("dist/app.jar")
+	assert.False(t, ok)
+
+	assert.Equal(t, "bundle", utils.ArchiveBaseName("bundle.tar.gz"))
+	assert.Equal(t, "bundle", utils.ArchiveBaseName("bundle.TGZ"))
+	assert.Equal(t, "app.jar", utils.ArchiveBaseName("app.jar"))
+}
+
+func TestRegisterArchiveFormat(t *testing.T) {
+	t.Parallel()
+
+	var extracted string
+
+	utils.RegisterArchiveFormat(".bbox-test", func(archivePath, destDir string, opts utils.ExtractOptions) ([]string, error) {
+		extracted = archivePath
+		return nil, nil
+	})
+
+	_, err := utils.ExtractArchive("bundle.bbox-test", t.TempDir(), utils.DefaultExtractOptions)
+	require.NoError(t, err)
+	assert.Equal(t, "bundle.bbox-test", extracted)
+	assert.Contains(t, utils.ArchiveFormats(), ".bbox-test")
+}
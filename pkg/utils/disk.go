@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// FreeDiskSpace returns the number of bytes available to the current user on the file system of path.
+// The path does not need to exist yet, the space is looked up for its closest existing parent directory.
+func FreeDiskSpace(path string) (uint64, error) {
+	dir, err := existingParent(path)
+	if err != nil {
+		return 0, err
+	}
+
+	free, err := freeDiskSpace(dir)
+	if err != nil {
+		return 0, fmt.Errorf("error getting free disk space of %s: %w", dir, err)
+	}
+
+	return free, nil
+}
+
+// existingParent returns path if it exists, or its closest parent directory that exists.
+func existingParent(path string) (string, error) {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("error resolving %s: %w", path, err)
+	}
+
+	for {
+		_, err = os.Stat(dir)
+		if err == nil {
+			return dir, nil
+		}
+
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", err
+		}
+
+		dir = parent
+	}
+}
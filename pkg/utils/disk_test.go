@@ -0,0 +1,23 @@
+package utils_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"bbox/pkg/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFreeDiskSpace(t *testing.T) {
+	t.Parallel()
+
+	free, err := utils.FreeDiskSpace(t.TempDir())
+	require.NoError(t, err)
+	assert.Positive(t, free)
+
+	missing, err := utils.FreeDiskSpace(filepath.Join(t.TempDir(), "not", "created", "yet"))
+	require.NoError(t, err, "the free space of a missing directory is the one of its closest parent")
+	assert.Positive(t, missing)
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package utils
+
+import "golang.org/x/sys/unix"
+
+func freeDiskSpace(dir string) (uint64, error) {
+	var stat unix.Statfs_t
+
+	err := unix.Statfs(dir, &stat)
+	if err != nil {
+		return 0, err
+	}
+
+	// Bavail are the blocks available to unprivileged users, unlike Bfree
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
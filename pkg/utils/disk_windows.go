@@ -0,0 +1,22 @@
+//go:build windows
+
+package utils
+
+import "golang.org/x/sys/windows"
+
+func freeDiskSpace(dir string) (uint64, error) {
+	path, err := windows.UTF16PtrFromString(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	// the bytes available to the caller honor disk quotas, unlike the total free bytes
+	var freeBytesAvailable, totalBytes, totalFreeBytes uint64
+
+	err = windows.GetDiskFreeSpaceEx(path, &freeBytesAvailable, &totalBytes, &totalFreeBytes)
+	if err != nil {
+		return 0, err
+	}
+
+	return freeBytesAvailable, nil
+}
@@ -0,0 +1,354 @@
+package utils
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrUnsafeArchivePath is returned for archive entries that would be written outside of the destination directory.
+	ErrUnsafeArchivePath = errors.New("unsafe path in archive")
+	// ErrArchiveTooLarge is returned when the extracted size of an archive exceeds ExtractOptions.MaxTotalSize.
+	ErrArchiveTooLarge = errors.New("archive is too large")
+	// ErrTooManyFiles is returned when an archive has more files than ExtractOptions.MaxFiles.
+	ErrTooManyFiles = errors.New("archive has too many files")
+	// ErrSymlinkNotAllowed is returned for symlinks in archives extracted with the SymlinkReject policy.
+	ErrSymlinkNotAllowed = errors.New("symlinks are not allowed in archive")
+)
+
+// SymlinkPolicy is how symlinks in archives are extracted.
+type SymlinkPolicy string
+
+const (
+	// SymlinkSkip skips symlinks with a warning.
+	SymlinkSkip SymlinkPolicy = "skip"
+	// SymlinkReject fails the extraction of archives containing symlinks.
+	SymlinkReject SymlinkPolicy = "reject"
+	// SymlinkAllowInside creates symlinks that resolve inside the destination directory, and fails on any other.
+	SymlinkAllowInside SymlinkPolicy = "allow-inside"
+)
+
+// maxSymlinkTargetLength is the maximum length of a symlink target read from an archive.
+const maxSymlinkTargetLength = 4096
+
+// ExtractOptions are the limits and policies applied when extracting an archive.
+// Limits of 0 or less are not enforced.
+type ExtractOptions struct {
+	Symlinks SymlinkPolicy
+	// MaxTotalSize is the maximum number of bytes extracted from an archive, as a protection against zip bombs
+	MaxTotalSize int64
+	// MaxFiles is the maximum number of files and symlinks in an archive
+	MaxFiles int
+}
+
+// DefaultExtractOptions skips symlinks and allows archives of up to 20 GiB in 200000 files.
+var DefaultExtractOptions = ExtractOptions{
+	Symlinks:     SymlinkSkip,
+	MaxTotalSize: 20 << 30,
+	MaxFiles:     200_000,
+}
+
+// UnzipFileWithOptions extracts a zip archive to destDir, applying the limits and policies of opts, and returns the paths of the extracted files.
+// Entries escaping destDir are rejected, files are written atomically with sanitized modes, and symlinks are handled according to opts.Symlinks.
+func UnzipFileWithOptions(zipFilePath, destDir string, opts ExtractOptions) ([]string, error) {
+	log.Debugf("Unzipping file %s to %s", zipFilePath, destDir)
+
+	r, err := zip.OpenReader(zipFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening zip file: %w", err)
+	}
+	defer r.Close()
+
+	// the declared sizes fail early, the actual sizes are enforced while extracting
+	var declaredSize uint64
+	for _, f := range r.File {
+		declaredSize += f.UncompressedSize64
+	}
+
+	if opts.MaxTotalSize > 0 && declaredSize > uint64(opts.MaxTotalSize) {
+		return nil, fmt.Errorf("%w: %s extracted, the limit is %s", ErrArchiveTooLarge, FormatBytes(int64(declaredSize)), FormatBytes(opts.MaxTotalSize))
+	}
+
+	extractor, err := NewExtractor(destDir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range r.File {
+		err = extractZipEntry(extractor, f)
+		if err != nil {
+			return extractor.Files(), err
+		}
+	}
+
+	return extractor.Files(), extractor.Finish()
+}
+
+func extractZipEntry(extractor *Extractor, f *zip.File) error {
+	mode := f.Mode()
+
+	switch {
+	case f.FileInfo().IsDir():
+		return extractor.Dir(f.Name)
+	case mode&os.ModeSymlink != 0:
+		target, err := readZipEntry(f, maxSymlinkTargetLength)
+		if err != nil {
+			return err
+		}
+
+		return extractor.Symlink(f.Name, target)
+	case mode.IsRegular():
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("error opening file: %w", err)
+		}
+		defer rc.Close()
+
+		return extractor.File(f.Name, rc, mode)
+	default:
+		log.Warnf("skipping %s in archive, %s files are not supported", f.Name, mode.Type())
+		return nil
+	}
+}
+
+func readZipEntry(f *zip.File, maxLength int64) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", fmt.Errorf("error opening file: %w", err)
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(io.LimitReader(rc, maxLength))
+	if err != nil {
+		return "", fmt.Errorf("error reading %s: %w", f.Name, err)
+	}
+
+	return string(content), nil
+}
+
+// Extractor writes the entries of an archive to a destination directory, rejecting entries escaping it and enforcing the limits of its ExtractOptions.
+// Symlinks are created by Finish, once all other entries are written, so no entry is ever written through a symlink of the archive.
+type Extractor struct {
+	destDir  string
+	opts     ExtractOptions
+	written  int64
+	files    int
+	symlinks []symlink
+	// extracted are the paths of the extracted files, relative to destDir
+	extracted []string
+}
+
+type symlink struct {
+	path   string
+	target string
+}
+
+// NewExtractor creates an Extractor writing to destDir, creating it if needed.
+func NewExtractor(destDir string, opts ExtractOptions) (*Extractor, error) {
+	absDestDir, err := filepath.Abs(destDir)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving %s: %w", destDir, err)
+	}
+
+	err = os.MkdirAll(absDestDir, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("error creating dir: %w", err)
+	}
+
+	return &Extractor{destDir: absDestDir, opts: opts}, nil
+}
+
+// Dir creates the directory of an archive entry.
+func (e *Extractor) Dir(name string) error {
+	path, err := e.path(name)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(path, 0o755)
+	if err != nil {
+		return fmt.Errorf("error creating dir: %w", err)
+	}
+
+	return nil
+}
+
+// File atomically writes the content of an archive entry, with mode 0755 if the entry is executable and 0644 otherwise.
+func (e *Extractor) File(name string, r io.Reader, mode os.FileMode) error {
+	path, err := e.path(name)
+	if err != nil {
+		return err
+	}
+
+	if path == e.destDir {
+		return fmt.Errorf("%w: %q", ErrUnsafeArchivePath, name)
+	}
+
+	e.files++
+	if e.opts.MaxFiles > 0 && e.files > e.opts.MaxFiles {
+		return fmt.Errorf("%w: the limit is %d", ErrTooManyFiles, e.opts.MaxFiles)
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return fmt.Errorf("error creating dir: %w", err)
+	}
+
+	fileMode := os.FileMode(0o644)
+	if mode&0o111 != 0 {
+		fileMode = 0o755
+	}
+
+	err = WriteFileAtomic(path, &sizeLimitReader{r: r, extractor: e}, fileMode)
+	if err != nil {
+		return fmt.Errorf("error extracting %s: %w", name, err)
+	}
+
+	rel, _ := filepath.Rel(e.destDir, path)
+	e.extracted = append(e.extracted, filepath.ToSlash(rel))
+
+	return nil
+}
+
+// Files returns the slash separated paths of the files extracted so far, relative to the destination directory.
+func (e *Extractor) Files() []string {
+	return e.extracted
+}
+
+// Symlink handles a symlink of the archive according to the symlink policy.
+// Allowed symlinks must be relative, and are only created by Finish.
+func (e *Extractor) Symlink(name, target string) error {
+	switch e.opts.Symlinks {
+	case SymlinkReject:
+		return fmt.Errorf("%w: %s -> %s", ErrSymlinkNotAllowed, name, target)
+	case SymlinkAllowInside:
+		path, err := e.path(name)
+		if err != nil {
+			return err
+		}
+
+		target = filepath.FromSlash(target)
+		if filepath.IsAbs(target) || filepath.VolumeName(target) != "" || !isWithin(e.destDir, filepath.Join(filepath.Dir(path), target)) {
+			return fmt.Errorf("%w: symlink %s -> %s points outside of the destination", ErrUnsafeArchivePath, name, target)
+		}
+
+		e.files++
+		if e.opts.MaxFiles > 0 && e.files > e.opts.MaxFiles {
+			return fmt.Errorf("%w: the limit is %d", ErrTooManyFiles, e.opts.MaxFiles)
+		}
+
+		e.symlinks = append(e.symlinks, symlink{path: path, target: target})
+
+		return nil
+	default:
+		log.Warnf("skipping symlink %s -> %s in archive", name, target)
+		return nil
+	}
+}
+
+// Finish creates the allowed symlinks of the archive, and verifies that they resolve inside the destination directory.
+// Symlinks escaping it through other symlinks are removed and fail the extraction, dangling ones are removed with a warning.
+func (e *Extractor) Finish() error {
+	for _, link := range e.symlinks {
+		err := os.MkdirAll(filepath.Dir(link.path), 0o755)
+		if err != nil {
+			return fmt.Errorf("error creating dir: %w", err)
+		}
+
+		err = os.Symlink(link.target, link.path)
+		if err != nil {
+			return fmt.Errorf("error creating symlink: %w", err)
+		}
+	}
+
+	if len(e.symlinks) == 0 {
+		return nil
+	}
+
+	resolvedDestDir, err := filepath.EvalSymlinks(e.destDir)
+	if err != nil {
+		return fmt.Errorf("error resolving %s: %w", e.destDir, err)
+	}
+
+	var errs []error
+
+	for _, link := range e.symlinks {
+		resolved, err := filepath.EvalSymlinks(link.path)
+		if err != nil {
+			log.Warnf("removing dangling symlink %s -> %s", link.path, link.target)
+			os.Remove(link.path)
+
+			continue
+		}
+
+		if !isWithin(resolvedDestDir, resolved) {
+			os.Remove(link.path)
+			errs = append(errs, fmt.Errorf("%w: symlink %s -> %s resolves outside of the destination", ErrUnsafeArchivePath, link.path, link.target))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// path returns the path an archive entry is extracted to, failing for absolute paths, paths escaping the destination and paths through symlinks.
+func (e *Extractor) path(name string) (string, error) {
+	// archives created on Windows may use backslashes as separators
+	name = filepath.FromSlash(strings.ReplaceAll(name, `\`, "/"))
+
+	if filepath.IsAbs(name) || filepath.VolumeName(name) != "" || strings.HasPrefix(name, string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %q is absolute", ErrUnsafeArchivePath, name)
+	}
+
+	path := filepath.Join(e.destDir, name)
+	if !isWithin(e.destDir, path) {
+		return "", fmt.Errorf("%w: %q escapes the destination", ErrUnsafeArchivePath, name)
+	}
+
+	// never write through a symlink, e.g. one left by a previous extraction
+	rel, _ := filepath.Rel(e.destDir, filepath.Dir(path))
+	current := e.destDir
+
+	if rel != "." {
+		for _, part := range strings.Split(rel, string(filepath.Separator)) {
+			current = filepath.Join(current, part)
+
+			info, err := os.Lstat(current)
+			if err == nil && info.Mode()&os.ModeSymlink != 0 {
+				return "", fmt.Errorf("%w: %q is written through the symlink %s", ErrUnsafeArchivePath, name, current)
+			}
+		}
+	}
+
+	return path, nil
+}
+
+// sizeLimitReader counts the bytes extracted, and fails with ErrArchiveTooLarge once they exceed the limit of the extractor.
+type sizeLimitReader struct {
+	r         io.Reader
+	extractor *Extractor
+}
+
+func (l *sizeLimitReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.extractor.written += int64(n)
+
+	if limit := l.extractor.opts.MaxTotalSize; limit > 0 && l.extractor.written > limit {
+		return n, fmt.Errorf("%w: more than %s extracted", ErrArchiveTooLarge, FormatBytes(limit))
+	}
+
+	return n, err
+}
+
+// isWithin returns true if path is dir or inside of it.
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,224 @@
+package utils_test
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"bbox/pkg/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type zipEntry struct {
+	name    string
+	content string
+	mode    os.FileMode
+}
+
+// writeZip writes a zip archive with the entries, which may be malicious, and returns its path.
+func writeZip(t *testing.T, entries ...zipEntry) string {
+	t.Helper()
+
+	zipPath := filepath.Join(t.TempDir(), "archive.zip")
+
+	file, err := os.Create(zipPath)
+	require.NoError(t, err)
+	defer file.Close()
+
+	zipWriter := zip.NewWriter(file)
+
+	for _, entry := range entries {
+		header := &zip.FileHeader{Name: entry.name, Method: zip.Deflate}
+		if entry.mode != 0 {
+			header.SetMode(entry.mode)
+		}
+
+		writer, err := zipWriter.CreateHeader(header)
+		require.NoError(t, err)
+
+		_, err = writer.Write([]byte(entry.content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, zipWriter.Close())
+
+	return zipPath
+}
+
+// extractDirs returns a destination directory inside of a parent directory, to detect files written next to the destination.
+func extractDirs(t *testing.T) (parent, dest string) {
+	t.Helper()
+
+	parent = t.TempDir()
+
+	return parent, filepath.Join(parent, "dest")
+}
+
+func TestUnzipFileRejectsPathTraversal(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name  string
+		entry string
+	}{
+		{name: "parent directory", entry: "../evil.txt"},
+		{name: "nested parent directory", entry: "dist/../../evil.txt"},
+		{name: "backslashes", entry: `..\evil.txt`},
+		{name: "absolute", entry: "/tmp/evil.txt"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			parent, dest := extractDirs(t)
+			zipPath := writeZip(t, zipEntry{name: tc.entry, content: "pwned"})
+
+			err := utils.UnzipFile(zipPath, dest)
+			require.ErrorIs(t, err, utils.ErrUnsafeArchivePath)
+
+			assert.NoFileExists(t, filepath.Join(parent, "evil.txt"))
+		})
+	}
+}
+
+func TestUnzipFileSanitizesModes(t *testing.T) {
+	t.Parallel()
+
+	_, dest := extractDirs(t)
+	zipPath := writeZip(t,
+		zipEntry{name: "bin/run.sh", content: "#!/bin/sh", mode: 0o4777},
+		zipEntry{name: "README.md", content: "hello", mode: 0o600},
+	)
+
+	require.NoError(t, utils.UnzipFile(zipPath, dest))
+
+	info, err := os.Stat(filepath.Join(dest, "bin", "run.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o755), info.Mode())
+
+	info, err = os.Stat(filepath.Join(dest, "README.md"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o644), info.Mode())
+}
+
+func TestUnzipFileSymlinkPolicies(t *testing.T) {
+	t.Parallel()
+
+	insideLink := zipEntry{name: "current", content: "releases/1.0", mode: os.ModeSymlink | 0o777}
+	release := zipEntry{name: "releases/1.0/app.txt", content: "app"}
+
+	t.Run("skip", func(t *testing.T) {
+		t.Parallel()
+
+		_, dest := extractDirs(t)
+		require.NoError(t, utils.UnzipFile(writeZip(t, insideLink, release), dest))
+
+		assert.FileExists(t, filepath.Join(dest, "releases", "1.0", "app.txt"))
+		_, err := os.Lstat(filepath.Join(dest, "current"))
+		assert.True(t, os.IsNotExist(err), "symlinks are skipped by default")
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		t.Parallel()
+
+		_, dest := extractDirs(t)
+		_, err := utils.UnzipFileWithOptions(writeZip(t, insideLink, release), dest, utils.ExtractOptions{Symlinks: utils.SymlinkReject})
+		assert.ErrorIs(t, err, utils.ErrSymlinkNotAllowed)
+	})
+
+	t.Run("allow inside", func(t *testing.T) {
+		t.Parallel()
+
+		_, dest := extractDirs(t)
+		_, err := utils.UnzipFileWithOptions(writeZip(t, insideLink, release), dest, utils.ExtractOptions{Symlinks: utils.SymlinkAllowInside})
+		require.NoError(t, err)
+
+		content, err := os.ReadFile(filepath.Join(dest, "current", "app.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "app", string(content))
+	})
+
+	t.Run("allow inside rejects links outside", func(t *testing.T) {
+		t.Parallel()
+
+		for _, target := range []string{"../../etc", "/etc"} {
+			_, dest := extractDirs(t)
+			_, err := utils.UnzipFileWithOptions(writeZip(t, zipEntry{name: "etc", content: target, mode: os.ModeSymlink | 0o777}), dest, utils.ExtractOptions{Symlinks: utils.SymlinkAllowInside})
+			assert.ErrorIs(t, err, utils.ErrUnsafeArchivePath, target)
+		}
+	})
+
+	t.Run("allow inside rejects links escaping through other links", func(t *testing.T) {
+		t.Parallel()
+
+		parent, dest := extractDirs(t)
+		require.NoError(t, os.WriteFile(filepath.Join(parent, "secret.txt"), []byte("secret"), 0o600))
+
+		// x/up points to the destination, so x/up/.. is its parent although it looks like the destination itself
+		zipPath := writeZip(t,
+			zipEntry{name: "x/up", content: "..", mode: os.ModeSymlink | 0o777},
+			zipEntry{name: "escape", content: "x/up/../secret.txt", mode: os.ModeSymlink | 0o777},
+		)
+
+		_, err := utils.UnzipFileWithOptions(zipPath, dest, utils.ExtractOptions{Symlinks: utils.SymlinkAllowInside})
+		require.ErrorIs(t, err, utils.ErrUnsafeArchivePath)
+
+		_, err = os.Lstat(filepath.Join(dest, "escape"))
+		assert.True(t, os.IsNotExist(err), "the escaping symlink is removed")
+	})
+
+	t.Run("files are not written through links", func(t *testing.T) {
+		t.Parallel()
+
+		parent, dest := extractDirs(t)
+		require.NoError(t, os.MkdirAll(dest, 0o755))
+		require.NoError(t, os.Symlink(parent, filepath.Join(dest, "out")))
+
+		err := utils.UnzipFile(writeZip(t, zipEntry{name: "out/evil.txt", content: "pwned"}), dest)
+		require.ErrorIs(t, err, utils.ErrUnsafeArchivePath)
+
+		assert.NoFileExists(t, filepath.Join(parent, "evil.txt"))
+	})
+}
+
+func TestUnzipFileLimits(t *testing.T) {
+	t.Parallel()
+
+	bomb := zipEntry{name: "zeros.bin", content: strings.Repeat("0", 1<<20)}
+
+	t.Run("total size", func(t *testing.T) {
+		t.Parallel()
+
+		_, dest := extractDirs(t)
+		_, err := utils.UnzipFileWithOptions(writeZip(t, bomb), dest, utils.ExtractOptions{MaxTotalSize: 1 << 10})
+		require.ErrorIs(t, err, utils.ErrArchiveTooLarge)
+
+		assert.NoFileExists(t, filepath.Join(dest, "zeros.bin"))
+	})
+
+	t.Run("file count", func(t *testing.T) {
+		t.Parallel()
+
+		_, dest := extractDirs(t)
+		zipPath := writeZip(t, zipEntry{name: "a.txt"}, zipEntry{name: "b.txt"}, zipEntry{name: "c.txt"})
+
+		_, err := utils.UnzipFileWithOptions(zipPath, dest, utils.ExtractOptions{MaxFiles: 2})
+		assert.ErrorIs(t, err, utils.ErrTooManyFiles)
+	})
+
+	t.Run("within limits", func(t *testing.T) {
+		t.Parallel()
+
+		_, dest := extractDirs(t)
+		_, err := utils.UnzipFileWithOptions(writeZip(t, bomb), dest, utils.ExtractOptions{MaxTotalSize: 2 << 20, MaxFiles: 1})
+		require.NoError(t, err)
+
+		assert.FileExists(t, filepath.Join(dest, "zeros.bin"))
+	})
+}
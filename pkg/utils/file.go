@@ -1,13 +1,14 @@
 package utils
 
 import (
-	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
-
-	log "github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
 )
 
 // WriteContentToFile writes the provided content to a file at the specified path.
@@ -23,49 +24,113 @@ func CreateDir(path string) error {
 	return os.MkdirAll(dirPath, os.ModePerm)
 }
 
+// UnzipFile extracts a zip archive to destDir with the DefaultExtractOptions.
 func UnzipFile(zipFilePath, destDir string) error {
-	log.Debugf("Unzipping file %s to %s", zipFilePath, destDir)
+	_, err := UnzipFileWithOptions(zipFilePath, destDir, DefaultExtractOptions)
+
+	return err
+}
 
-	r, err := zip.OpenReader(zipFilePath)
+// WriteFileAtomic writes the content of r to a temporary file next to path, which is renamed to path once complete.
+// A failed write does not leave a partial file behind.
+func WriteFileAtomic(path string, r io.Reader, mode os.FileMode) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.part")
 	if err != nil {
-		return fmt.Errorf("error opening zip file: %w", err)
+		return fmt.Errorf("error creating temporary file: %w", err)
+	}
+
+	_, err = io.Copy(tmpFile, r)
+
+	// Close the file without defer to handle the error
+	closeErr := tmpFile.Close()
+	if err == nil {
+		err = closeErr
 	}
-	defer r.Close()
-
-	for _, f := range r.File {
-		fpath := filepath.Join(destDir, f.Name)
-
-		if f.FileInfo().IsDir() {
-			err := os.MkdirAll(fpath, os.ModePerm)
-			if err != nil {
-				return fmt.Errorf("error creating dir: %w", err)
-			}
-		} else {
-			if err := CreateDir(fpath); err != nil {
-				return fmt.Errorf("error creating dir: %w", err)
-			}
-
-			outFile, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
-			if err != nil {
-				return fmt.Errorf("error opening file: %w", err)
-			}
-
-			rc, err := f.Open()
-			if err != nil {
-				return fmt.Errorf("error opening file: %w", err)
-			}
-
-			_, err = io.Copy(outFile, rc)
-
-			// Close the file without defer to handle the error
-			outFile.Close()
-			rc.Close()
-
-			if err != nil {
-				return fmt.Errorf("error copying file: %w", err)
-			}
-		}
+
+	if err == nil {
+		err = os.Chmod(tmpFile.Name(), mode)
+	}
+
+	if err == nil {
+		err = os.Rename(tmpFile.Name(), path)
+	}
+
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return err
 	}
 
 	return nil
 }
+
+// FileSHA256 returns the hex encoded SHA-256 digest and the size of a file.
+func FileSHA256(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", size, fmt.Errorf("error reading %s: %w", path, err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// FormatBytes formats a number of bytes with a binary unit, e.g. "1.5 GiB".
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// byteUnits are the units accepted by ParseBytes, decimal and binary.
+var byteUnits = map[string]int64{
+	"":    1,
+	"B":   1,
+	"KB":  1000,
+	"MB":  1000 * 1000,
+	"GB":  1000 * 1000 * 1000,
+	"TB":  1000 * 1000 * 1000 * 1000,
+	"KIB": 1 << 10,
+	"MIB": 1 << 20,
+	"GIB": 1 << 30,
+	"TIB": 1 << 40,
+}
+
+// ParseBytes parses a size with an optional decimal or binary unit, e.g. "500MB", "1.5 GiB" or "1024".
+func ParseBytes(s string) (int64, error) {
+	value := strings.TrimSpace(s)
+
+	i := strings.IndexFunc(value, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(value)
+	}
+
+	number, err := strconv.ParseFloat(value[:i], 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	unit, ok := byteUnits[strings.ToUpper(strings.TrimSpace(value[i:]))]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q, expected a unit like MB or GiB", s)
+	}
+
+	return int64(number * float64(unit)), nil
+}
@@ -35,3 +35,45 @@ func TestUnzipFile(t *testing.T) {
 		assert.NoError(t, err)
 	}
 }
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, "512 B", utils.FormatBytes(512))
+	assert.Equal(t, "1.5 KiB", utils.FormatBytes(1536))
+	assert.Equal(t, "5.0 MiB", utils.FormatBytes(5<<20))
+	assert.Equal(t, "4.0 GiB", utils.FormatBytes(4<<30))
+}
+
+func TestParseBytes(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		value    string
+		expected int64
+		wantErr  bool
+	}{
+		{value: "1024", expected: 1024},
+		{value: "500MB", expected: 500_000_000},
+		{value: "1.5 GiB", expected: 3 << 29},
+		{value: "10gib", expected: 10 << 30},
+		{value: "2 TB", expected: 2_000_000_000_000},
+		{value: "", wantErr: true},
+		{value: "GiB", wantErr: true},
+		{value: "10 parsecs", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.value, func(t *testing.T) {
+			t.Parallel()
+
+			size, err := utils.ParseBytes(tc.value)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, size)
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package utils
+
+import (
+	"os"
+
+	"golang.org/x/term"
+)
+
+// IsTerminal returns true if the given file is attached to a terminal.
+func IsTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// IsInteractiveTerminal returns true if both stdin and stdout are attached to a terminal.
+func IsInteractiveTerminal() bool {
+	return IsTerminal(os.Stdin) && IsTerminal(os.Stdout)
+}
@@ -23,7 +23,7 @@ type MockBuildService struct {
 	mock.Mock
 }
 
-func (m *MockBuildService) WaitForBuild(buildName string, buildNumber int, timeout time.Duration) (types.BuildStatusResponse, error) {
+func (m *MockBuildService) WaitForBuild(buildName string, buildNumber int, timeout time.Duration, opts ...teamcity.WaitOption) (types.BuildStatusResponse, error) {
 	args := m.Called(buildName, buildNumber, timeout)
 
 	_, err := m.GetBuildStatus(buildNumber)
@@ -43,6 +43,31 @@ func (m *MockBuildService) GetBuildStatus(buildID int) (types.BuildStatusRespons
 	return args.Get(0).(types.BuildStatusResponse), args.Error(1)
 }
 
+func (m *MockBuildService) GetResultingProperties(buildID int, filters ...string) (map[string]string, error) {
+	args := m.Called(buildID, filters)
+	return args.Get(0).(map[string]string), args.Error(1)
+}
+
+func (m *MockBuildService) ListBuilds(locator types.BuildLocator) ([]types.Build, error) {
+	args := m.Called(locator)
+	return args.Get(0).([]types.Build), args.Error(1)
+}
+
+func (m *MockBuildService) GetConstituentBuilds(buildID int) ([]types.BuildStatusResponse, error) {
+	args := m.Called(buildID)
+	return args.Get(0).([]types.BuildStatusResponse), args.Error(1)
+}
+
+func (m *MockBuildService) GetBuildDependencies(buildID int) (types.BuildStatusResponse, error) {
+	args := m.Called(buildID)
+	return args.Get(0).(types.BuildStatusResponse), args.Error(1)
+}
+
+func (m *MockBuildService) CancelBuild(buildID int, comment string) (bool, error) {
+	args := m.Called(buildID, comment)
+	return args.Bool(0), args.Error(1)
+}
+
 type MockArtifactsService struct {
 	mock.Mock
 }
@@ -71,11 +96,80 @@ func (m *MockArtifactsService) GetArtifactContentByPath(path string) ([]byte, er
 	return args.Get(0).([]byte), args.Error(1)
 }
 
-func (m *MockArtifactsService) DownloadAndUnzipArtifacts(buildID int, buildTypeID, destPath string) error {
+func (m *MockArtifactsService) DownloadAndUnzipArtifacts(buildID int, buildTypeID, destPath string) ([]string, error) {
 	args := m.Called(buildID, buildTypeID, destPath)
 	_, err := m.GetAllBuildTypeArtifacts(buildID, buildTypeID)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	files, _ := args.Get(0).([]string)
+	return files, args.Error(1)
+}
+
+func (m *MockArtifactsService) DownloadArtifactContentByPath(path, destFile string) (int64, error) {
+	args := m.Called(path, destFile)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockArtifactsService) DownloadAllBuildTypeArtifacts(buildID int, buildTypeID, destFile string) (int64, error) {
+	args := m.Called(buildID, buildTypeID, destFile)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockArtifactsService) ListArtifacts(buildID int) ([]types.ArtifactFile, error) {
+	args := m.Called(buildID)
+	return args.Get(0).([]types.ArtifactFile), args.Error(1)
+}
+
+func (m *MockArtifactsService) DownloadArtifact(file types.ArtifactFile, destFile string, retries int) error {
+	args := m.Called(file, destFile, retries)
 	return args.Error(0)
 }
+
+type MockChangeService struct {
+	mock.Mock
+}
+
+func (m *MockChangeService) GetChanges(locator *teamcity.Locator) ([]types.Change, error) {
+	args := m.Called(locator)
+	return args.Get(0).([]types.Change), args.Error(1)
+}
+
+type MockBuildTypeService struct {
+	mock.Mock
+}
+
+func (m *MockBuildTypeService) GetBuildTypes(locator *teamcity.Locator) ([]types.BuildType, error) {
+	args := m.Called(locator)
+	return args.Get(0).([]types.BuildType), args.Error(1)
+}
+
+func (m *MockBuildTypeService) GetBranches(buildTypeID string) ([]types.Branch, error) {
+	args := m.Called(buildTypeID)
+	return args.Get(0).([]types.Branch), args.Error(1)
+}
+
+func (m *MockBuildTypeService) GetParameters(buildTypeID string) ([]types.Parameter, error) {
+	args := m.Called(buildTypeID)
+	return args.Get(0).([]types.Parameter), args.Error(1)
+}
+
+func (m *MockBuildTypeService) ValidateProperties(buildTypeID string, properties map[string]string, strict bool) error {
+	args := m.Called(buildTypeID, properties, strict)
+	return args.Error(0)
+}
+
+func (m *MockBuildTypeService) FindBuildTypes(query string) ([]types.BuildType, error) {
+	args := m.Called(query)
+	return args.Get(0).([]types.BuildType), args.Error(1)
+}
+
+func (m *MockBuildTypeService) ResolveBuildTypeID(query string) (string, error) {
+	args := m.Called(query)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockBuildTypeService) GetBuildTypeDependencies(buildTypeID string) (types.BuildType, error) {
+	args := m.Called(buildTypeID)
+	return args.Get(0).(types.BuildType), args.Error(1)
+}
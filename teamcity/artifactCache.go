@@ -0,0 +1,103 @@
+package teamcity
+
+import (
+	"path/filepath"
+
+	"bbox/pkg/cache"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// cacheForServer returns the artifact cache of the builds of the TeamCity server of the client, as build IDs are only unique per server.
+func cacheForServer(c *Client, artifactCache *cache.ArtifactCache) *cache.ArtifactCache {
+	if artifactCache == nil || c.baseURL == nil {
+		return artifactCache
+	}
+
+	return artifactCache.ForServer(c.baseURL.String())
+}
+
+// restoreBuildFromCache restores all artifacts of a build to destPath if they are cached, and returns their paths.
+func restoreBuildFromCache(artifactCache *cache.ArtifactCache, buildID int, destPath string) ([]string, bool) {
+	if artifactCache == nil {
+		return nil, false
+	}
+
+	artifactPaths, ok := artifactCache.Complete(buildID)
+	if !ok {
+		return nil, false
+	}
+
+	for _, artifactPath := range artifactPaths {
+		if !filepath.IsLocal(filepath.FromSlash(artifactPath)) {
+			log.Warnf("not restoring artifact %s of build %d from the cache, it is outside of %s", artifactPath, buildID, destPath)
+			return nil, false
+		}
+
+		restored, err := artifactCache.Restore(buildID, artifactPath, filepath.Join(destPath, filepath.FromSlash(artifactPath)))
+		if err != nil {
+			log.Warnf("error restoring artifacts of build %d from the cache: %s", buildID, err)
+		}
+
+		if !restored {
+			return nil, false
+		}
+	}
+
+	log.Infof("restored %d artifacts of build %d from the cache", len(artifactPaths), buildID)
+
+	return artifactPaths, true
+}
+
+// restoreFromCache restores an artifact file of a build to destFile if it is cached.
+func restoreFromCache(artifactCache *cache.ArtifactCache, buildID int, artifactPath, destFile string) bool {
+	if artifactCache == nil {
+		return false
+	}
+
+	restored, err := artifactCache.Restore(buildID, artifactPath, destFile)
+	if err != nil {
+		log.Warnf("error restoring artifact %s of build %d from the cache: %s", artifactPath, buildID, err)
+		return false
+	}
+
+	if restored {
+		log.Debugf("restored artifact %s of build %d from the cache", artifactPath, buildID)
+	}
+
+	return restored
+}
+
+// storeInCache adds the downloaded artifact files of a build to the cache.
+// If all artifacts of the build are set, the build is marked complete, so it can be restored without listing its artifacts.
+// Caching is best effort, errors are logged and do not fail the download.
+func storeInCache(artifactCache *cache.ArtifactCache, buildID int, destPath string, downloaded, all []downloadedArtifact) {
+	if artifactCache == nil {
+		return
+	}
+
+	for _, file := range downloaded {
+		_, err := artifactCache.Store(buildID, file.artifactPath, filepath.Join(destPath, filepath.FromSlash(file.path)))
+		if err != nil {
+			log.Warnf("error caching artifact %s of build %d: %s", file.artifactPath, buildID, err)
+			return
+		}
+	}
+
+	if len(all) > 0 {
+		artifactPaths := make([]string, 0, len(all))
+		for _, file := range all {
+			artifactPaths = append(artifactPaths, file.artifactPath)
+		}
+
+		err := artifactCache.MarkComplete(buildID, artifactPaths)
+		if err != nil {
+			log.Warnf("error caching artifacts of build %d: %s", buildID, err)
+		}
+	}
+
+	_, err := artifactCache.Prune(artifactCache.MaxSize())
+	if err != nil {
+		log.Warnf("error pruning the artifact cache: %s", err)
+	}
+}
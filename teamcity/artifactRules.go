@@ -0,0 +1,203 @@
+package teamcity
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	artifactRuleSeparator = "=>"
+	artifactRuleInclude   = "+:"
+	artifactRuleExclude   = "-:"
+)
+
+// ArtifactRule selects the artifacts matching a glob pattern, and where to download them to, in the TeamCity artifact rule syntax:
+// "dist/**/*.jar => lib/" downloads the jars under dist to lib, keeping their paths relative to dist.
+type ArtifactRule struct {
+	Pattern string
+	// Dest is the directory the matching artifacts are downloaded to, relative to the artifacts path.
+	// Without a destination, the artifacts keep their full path.
+	Dest    string
+	hasDest bool
+}
+
+// ParseArtifactRule parses an artifact rule in the form "pattern [=> dest]".
+// Patterns match the full path of the artifacts, with "*" and "?" matching within a directory and "**" matching any number of directories.
+// Destinations outside of the artifacts path, e.g. "../lib", are rejected.
+func ParseArtifactRule(rule string) (ArtifactRule, error) {
+	pattern, dest, hasDest := strings.Cut(rule, artifactRuleSeparator)
+	pattern = strings.Trim(strings.TrimSpace(pattern), "/")
+
+	if pattern == "" {
+		return ArtifactRule{}, fmt.Errorf("artifact rule %q has no pattern", rule)
+	}
+
+	if err := validateGlob(pattern); err != nil {
+		return ArtifactRule{}, fmt.Errorf("invalid artifact rule %q: %w", rule, err)
+	}
+
+	dest = strings.Trim(strings.TrimSpace(dest), "/")
+
+	if dest != "" && !filepath.IsLocal(filepath.FromSlash(dest)) {
+		return ArtifactRule{}, fmt.Errorf("invalid artifact rule %q: destination %q is outside of the artifacts path", rule, dest)
+	}
+
+	return ArtifactRule{
+		Pattern: pattern,
+		Dest:    dest,
+		hasDest: hasDest,
+	}, nil
+}
+
+// Match returns the destination of an artifact, relative to the artifacts path, if it matches the rule.
+func (r ArtifactRule) Match(artifactPath string) (string, bool) {
+	if !matchGlob(r.Pattern, artifactPath) {
+		return "", false
+	}
+
+	if !r.hasDest {
+		return artifactPath, true
+	}
+
+	// like TeamCity, the directories of the pattern before its first wildcard are not kept
+	relative := strings.TrimPrefix(strings.TrimPrefix(artifactPath, globBase(r.Pattern)), "/")
+
+	return path.Join(r.Dest, relative), true
+}
+
+// ArtifactFilter selects the artifacts of a build to download.
+// The zero value selects all artifacts.
+type ArtifactFilter struct {
+	include []ArtifactRule
+	exclude []string
+}
+
+// NewArtifactFilter creates an ArtifactFilter from include rules and exclude glob patterns.
+// Include rules prefixed with "-:" are exclude patterns, as in TeamCity artifact rules, and a "+:" prefix is ignored.
+func NewArtifactFilter(include, exclude []string) (ArtifactFilter, error) {
+	filter := ArtifactFilter{}
+	var errs []error
+
+	for _, rule := range include {
+		rule = strings.TrimSpace(rule)
+
+		if pattern, ok := strings.CutPrefix(rule, artifactRuleExclude); ok {
+			exclude = append(exclude, pattern)
+			continue
+		}
+
+		parsed, err := ParseArtifactRule(strings.TrimPrefix(rule, artifactRuleInclude))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		filter.include = append(filter.include, parsed)
+	}
+
+	for _, pattern := range exclude {
+		pattern = strings.Trim(strings.TrimSpace(pattern), "/")
+
+		if err := validateGlob(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("invalid artifact exclude pattern %q: %w", pattern, err))
+			continue
+		}
+
+		filter.exclude = append(filter.exclude, pattern)
+	}
+
+	return filter, errors.Join(errs...)
+}
+
+// IsEmpty returns true if the filter selects all artifacts.
+func (f ArtifactFilter) IsEmpty() bool {
+	return len(f.include) == 0 && len(f.exclude) == 0
+}
+
+// Destination returns the destination of an artifact, relative to the artifacts path, if the filter selects it.
+// The first matching include rule gives the destination.
+func (f ArtifactFilter) Destination(artifactPath string) (string, bool) {
+	for _, pattern := range f.exclude {
+		if matchGlob(pattern, artifactPath) {
+			return "", false
+		}
+	}
+
+	if len(f.include) == 0 {
+		return artifactPath, true
+	}
+
+	for _, rule := range f.include {
+		if dest, ok := rule.Match(artifactPath); ok {
+			return dest, true
+		}
+	}
+
+	return "", false
+}
+
+// matchGlob reports whether the slash separated name matches the pattern, where "**" matches any number of path segments.
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			pattern = pattern[1:]
+			if len(pattern) == 0 {
+				return true
+			}
+
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern, name[i:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
+
+func validateGlob(pattern string) error {
+	for _, segment := range strings.Split(pattern, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// globBase returns the directories of a pattern before its first wildcard, or the directory of the pattern if it has none.
+func globBase(pattern string) string {
+	segments := strings.Split(pattern, "/")
+
+	for i, segment := range segments {
+		if strings.ContainsAny(segment, "*?[") {
+			return strings.Join(segments[:i], "/")
+		}
+	}
+
+	if dir := path.Dir(pattern); dir != "." {
+		return dir
+	}
+
+	return ""
+}
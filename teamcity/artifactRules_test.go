@@ -0,0 +1,125 @@
+package teamcity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchGlob(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{pattern: "app.jar", name: "app.jar", expected: true},
+		{pattern: "*.jar", name: "app.jar", expected: true},
+		{pattern: "*.jar", name: "dist/app.jar", expected: false},
+		{pattern: "dist/*.jar", name: "dist/app.jar", expected: true},
+		{pattern: "**/*.jar", name: "app.jar", expected: true},
+		{pattern: "**/*.jar", name: "dist/lib/app.jar", expected: true},
+		{pattern: "dist/**/*.jar", name: "dist/app.jar", expected: true},
+		{pattern: "dist/**/*.jar", name: "dist/a/b/app.jar", expected: true},
+		{pattern: "dist/**/*.jar", name: "build/dist/app.jar", expected: false},
+		{pattern: "dist/**", name: "dist/a/b/c.txt", expected: true},
+		{pattern: "dist/**", name: "distribution/c.txt", expected: false},
+		{pattern: "logs/test-?.log", name: "logs/test-1.log", expected: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.pattern+" "+tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, matchGlob(tc.pattern, tc.name))
+		})
+	}
+}
+
+func TestArtifactRuleMatch(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		rule         string
+		artifactPath string
+		expectedDest string
+		expectedOK   bool
+	}{
+		{name: "without destination keeps the path", rule: "dist/**/*.jar", artifactPath: "dist/lib/app.jar", expectedDest: "dist/lib/app.jar", expectedOK: true},
+		{name: "destination strips the base of the pattern", rule: "dist/**/*.jar => lib/", artifactPath: "dist/a/app.jar", expectedDest: "lib/a/app.jar", expectedOK: true},
+		{name: "file to destination", rule: "reports/summary.html => out", artifactPath: "reports/summary.html", expectedDest: "out/summary.html", expectedOK: true},
+		{name: "empty destination is the artifacts path", rule: "dist/*.zip =>", artifactPath: "dist/app.zip", expectedDest: "app.zip", expectedOK: true},
+		{name: "no match", rule: "dist/**/*.jar => lib", artifactPath: "dist/app.war", expectedOK: false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			rule, err := ParseArtifactRule(tc.rule)
+			require.NoError(t, err)
+
+			dest, ok := rule.Match(tc.artifactPath)
+			assert.Equal(t, tc.expectedOK, ok)
+			assert.Equal(t, tc.expectedDest, dest)
+		})
+	}
+}
+
+func TestParseArtifactRuleErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := ParseArtifactRule(" => lib")
+	assert.ErrorContains(t, err, "has no pattern")
+
+	_, err = ParseArtifactRule("dist/[a.jar")
+	assert.ErrorContains(t, err, "invalid artifact rule")
+
+	for _, rule := range []string{"**/*.jar => ../../x", "**/*.jar => lib/../..", "**/*.jar => .."} {
+		_, err = ParseArtifactRule(rule)
+		assert.ErrorContains(t, err, "is outside of the artifacts path", rule)
+	}
+}
+
+func TestArtifactFilter(t *testing.T) {
+	t.Parallel()
+
+	filter, err := NewArtifactFilter([]string{"+:dist/**/*.jar => lib", "reports/**", "-:**/*-sources.jar"}, []string{"reports/tmp/**"})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		artifactPath string
+		expectedDest string
+		expectedOK   bool
+	}{
+		{artifactPath: "dist/app.jar", expectedDest: "lib/app.jar", expectedOK: true},
+		{artifactPath: "dist/app-sources.jar", expectedOK: false},
+		{artifactPath: "reports/index.html", expectedDest: "reports/index.html", expectedOK: true},
+		{artifactPath: "reports/tmp/trace.log", expectedOK: false},
+		{artifactPath: "logs/build.log", expectedOK: false},
+	}
+
+	for _, tc := range testCases {
+		dest, ok := filter.Destination(tc.artifactPath)
+		assert.Equal(t, tc.expectedOK, ok, tc.artifactPath)
+		assert.Equal(t, tc.expectedDest, dest, tc.artifactPath)
+	}
+
+	excludeOnly, err := NewArtifactFilter(nil, []string{"**/*.map"})
+	require.NoError(t, err)
+
+	dest, ok := excludeOnly.Destination("static/app.js")
+	assert.True(t, ok)
+	assert.Equal(t, "static/app.js", dest)
+
+	_, ok = excludeOnly.Destination("static/app.js.map")
+	assert.False(t, ok)
+
+	assert.True(t, ArtifactFilter{}.IsEmpty())
+	assert.False(t, excludeOnly.IsEmpty())
+}
@@ -1,20 +1,34 @@
 package teamcity
 
 import (
+	"bbox/pkg/cache"
 	"bbox/pkg/types"
 	"bbox/pkg/utils"
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 
+	"github.com/alitto/pond"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 )
 
+const artifactFilesFields = "file(name,fullName,size,modificationTime,content(href),children(href))"
+
+// ErrNoArtifactsMatch is returned when none of the artifacts of a build match the artifact filter and ArtifactOptions.RequireMatch is set.
+var ErrNoArtifactsMatch = errors.New("no artifacts match the artifact filter")
+
 type ArtifactsService struct {
 	client *Client
 }
@@ -43,7 +57,7 @@ func (as *ArtifactsService) BuildHasArtifact(buildID int) bool {
 
 // GetArtifactChildren returns the children of an artifact if any.
 func (as *ArtifactsService) GetArtifactChildren(buildID int) (types.ArtifactChildren, error) {
-	getURL := fmt.Sprintf("httpAuth/app/rest/builds/id:%d/%s", buildID, "artifacts/children/")
+	getURL := "httpAuth/app/rest/builds/" + NewLocator().AddInt("id", buildID).PathSegment() + "/artifacts/children/"
 	log.Debug("getting build children from: ", getURL)
 
 	req, err := as.client.NewRequestWrapper("GET", getURL, nil)
@@ -79,104 +93,512 @@ func (as *ArtifactsService) GetArtifactChildren(buildID int) (types.ArtifactChil
 	return artifactChildren, nil
 }
 
+// ListArtifacts returns all artifact files of a build, walking its artifact directories recursively.
+// Archives are listed as files, their content is not walked.
+func (as *ArtifactsService) ListArtifacts(buildID int) ([]types.ArtifactFile, error) {
+	files := []types.ArtifactFile{}
+	pending := []string{""}
+
+	for len(pending) > 0 {
+		dir := pending[0]
+		pending = pending[1:]
+
+		getURL := "app/rest/builds/" + NewLocator().AddInt("id", buildID).PathSegment() + "/artifacts/children/" + escapeArtifactPath(dir)
+
+		req, err := as.client.NewRequestWrapper("GET", withQuery(getURL, nil, artifactFilesFields), nil)
+		if err != nil {
+			return files, fmt.Errorf("error creating request: %w", err)
+		}
+
+		resp, err := as.client.client.Do(req)
+		if err != nil {
+			return files, fmt.Errorf("error listing artifacts of %q: %w", dir, err)
+		}
+
+		var children types.ArtifactChildren
+
+		if resp.StatusCode == http.StatusOK {
+			err = json.NewDecoder(resp.Body).Decode(&children)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return files, fmt.Errorf("failed to list artifacts of %q, status code: %d", dir, resp.StatusCode)
+		}
+
+		if err != nil {
+			return files, fmt.Errorf("error decoding response body: %w", err)
+		}
+
+		for _, child := range children.File {
+			if child.IsDir() {
+				pending = append(pending, child.FullName)
+				continue
+			}
+
+			files = append(files, child)
+		}
+	}
+
+	return files, nil
+}
+
+// DownloadArtifact streams the content of an artifact file to destFile, creating its directory, and retries failed downloads up to retries times.
+// Retries resume the partial download with HTTP Range requests, and the size of the download is verified against the size of the artifact.
+// The file is written next to destFile first and renamed once complete, so a failed download does not leave a partial file behind.
+func (as *ArtifactsService) DownloadArtifact(file types.ArtifactFile, destFile string, retries int) error {
+	_, err := as.downloadToFileResumable(strings.TrimPrefix(file.Content.Href, "/"), "artifact "+file.FullName, destFile, file.Size, retries)
+
+	return err
+}
+
+// escapeArtifactPath escapes every segment of an artifact path for use in a URL path.
+func escapeArtifactPath(artifactPath string) string {
+	segments := strings.Split(artifactPath, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return strings.Join(segments, "/")
+}
+
 // GetArtifactContentByPath GetArtifactContent returns the content of an artifact.
+// It buffers the whole artifact in memory, use DownloadArtifactContentByPath for large artifacts.
 func (as *ArtifactsService) GetArtifactContentByPath(path string) ([]byte, error) {
-	req, err := as.client.NewRequestWrapper("GET", path, nil)
+	var content bytes.Buffer
+
+	_, err := as.stream(path, "artifact "+path, &content)
 	if err != nil {
-		return []byte{}, err
+		return nil, fmt.Errorf("error getting artifact content: %w", err)
 	}
 
-	resp, err := as.client.client.Do(req)
+	return content.Bytes(), nil
+}
+
+// DownloadArtifactContentByPath streams the content of an artifact to destFile, and returns its size.
+func (as *ArtifactsService) DownloadArtifactContentByPath(path, destFile string) (int64, error) {
+	return as.downloadToFile(path, "artifact "+path, destFile)
+}
+
+// GetAllBuildTypeArtifacts returns all artifacts from a buildID and buildTypeId as a zip file.
+// It buffers the whole zip in memory, use DownloadAllBuildTypeArtifacts for large artifacts.
+func (as *ArtifactsService) GetAllBuildTypeArtifacts(buildID int, buildTypeID string) ([]byte, error) {
+	var content bytes.Buffer
+
+	_, err := as.stream(allArtifactsURL(buildID, buildTypeID), fmt.Sprintf("artifacts of build %d", buildID), &content)
 	if err != nil {
-		return nil, fmt.Errorf("error getting artifact content: %w", err)
+		return nil, fmt.Errorf("error getting all artifacts for buildID %d: %w", buildID, err)
 	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			log.Errorf("error closing response body: %s", err)
-		}
-	}(resp.Body)
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("statusCode: %d", resp.StatusCode)
+	return content.Bytes(), nil
+}
+
+// DownloadAllBuildTypeArtifacts streams all artifacts from a buildID and buildTypeId as a zip file to destFile, and returns its size.
+func (as *ArtifactsService) DownloadAllBuildTypeArtifacts(buildID int, buildTypeID, destFile string) (int64, error) {
+	size, err := as.downloadToFile(allArtifactsURL(buildID, buildTypeID), fmt.Sprintf("artifacts of build %d", buildID), destFile)
+	if err != nil {
+		return size, fmt.Errorf("error getting all artifacts for buildID %d: %w", buildID, err)
 	}
 
-	err = resp.Body.Close()
+	return size, nil
+}
+
+func allArtifactsURL(buildID int, buildTypeID string) string {
+	return "downloadArtifacts.html?" + url.Values{"buildId": {strconv.Itoa(buildID)}, "buildTypeId": {buildTypeID}}.Encode()
+}
+
+// DownloadAndUnzipArtifacts downloads all artifacts  to given path and unzips them, returning the paths of the extracted files relative to destPath.
+// The artifacts zip is streamed to a temporary file in destPath, so its size is not limited by the available memory.
+func (as *ArtifactsService) DownloadAndUnzipArtifacts(buildID int, buildTypeID, destPath string) ([]string, error) {
+	// create uuid for temporary artifacts zip file, to prevent overwriting
+	fileID := uuid.New().String()
+	artifactsZip := filepath.Join(destPath, fileID+"-artifacts.zip")
+
+	log.WithField("artifactsPath", destPath).Debug("writing Artifacts to path")
+
+	size, err := as.DownloadAllBuildTypeArtifacts(buildID, buildTypeID, artifactsZip)
 	if err != nil {
-		log.Errorf("error closing response body: %s", err)
+		log.Errorf("error getting artifacts content: %s", err)
+		return nil, fmt.Errorf("error getting artifacts content: %w", err)
+	}
+	// if size of content is 0, then no artifacts were found
+	if size == 0 {
+		os.Remove(artifactsZip)
+		return nil, errors.New("artifacts not found")
+	}
+
+	files, err := utils.UnzipFileWithOptions(artifactsZip, destPath, utils.DefaultExtractOptions)
+	if err != nil {
+		// never leave the zip behind, e.g. when the disk filled up while extracting it
+		os.Remove(artifactsZip)
+
+		log.Errorf("error unzipping artifacts: %s", err)
+		return files, fmt.Errorf("error unzipping artifacts: %w", err)
+	}
+
+	err = os.Remove(artifactsZip)
+	if err != nil {
+		log.Errorf("error deleting zip: %s", err)
+		return files, fmt.Errorf("error deleting zip: %w", err)
 	}
 
-	return io.ReadAll(resp.Body)
+	return files, nil
 }
 
-// GetAllBuildTypeArtifacts returns all artifacts from a buildID and buildTypeId as a zip file.
-func (as *ArtifactsService) GetAllBuildTypeArtifacts(buildID int, buildTypeID string) ([]byte, error) {
-	getURL := fmt.Sprintf("downloadArtifacts.html?buildId=%d&buildTypeId=%s", buildID, buildTypeID)
+// DefaultDownloadParallelism is the number of artifact files downloaded at the same time by default.
+const DefaultDownloadParallelism = 4
+
+// ArtifactOptions select the artifacts downloaded from a build, how they are downloaded and how they are verified.
+type ArtifactOptions struct {
+	// Filter selects the artifacts to download, all artifacts are downloaded if it is empty
+	Filter ArtifactFilter
+	// PerFile downloads the artifacts file by file instead of as a single zip, which is always the case with a filter
+	PerFile bool
+	// Parallelism is the number of files downloaded at the same time, 1 if not positive
+	Parallelism int
+	// Retries is the number of times the download of a file is retried, resuming it where it failed
+	Retries int
+	// VerifyChecksums is the path of a checksum file in the artifacts of the build, e.g. "SHA256SUMS", the downloaded files are verified against
+	VerifyChecksums string
+	// Cache restores the artifacts downloaded before, and stores the downloaded ones, no cache is used if nil
+	Cache *cache.ArtifactCache
+	// ExtractNested extracts the archives found in the downloaded artifacts, e.g. "dist/bundle.tgz" to "dist/bundle/"
+	ExtractNested bool
+	// NoExtract keeps the archive of all artifacts as downloaded, instead of extracting and deleting it
+	NoExtract bool
+	// Claims fails the download of artifacts written to the same files as the artifacts of another build, collisions are not detected if nil
+	Claims *ArtifactClaims
+	// MaxSize is the maximum total size of the selected artifacts, in bytes, not enforced if 0 or less
+	MaxSize int64
+	// RequireMatch fails the download with ErrNoArtifactsMatch if no artifact matches the filter, which is only logged as a warning otherwise
+	RequireMatch bool
+}
 
-	req, err := as.client.NewRequestWrapper("GET", getURL, nil)
+// Validate returns an error for options that cannot be combined.
+func (o ArtifactOptions) Validate() error {
+	if !o.NoExtract {
+		return nil
+	}
+
+	switch {
+	case o.ExtractNested:
+		return errors.New("artifacts cannot be extracted when keeping the archive of all artifacts")
+	case o.PerFile || !o.Filter.IsEmpty():
+		return errors.New("the archive of all artifacts cannot be kept when downloading them file by file")
+	case o.VerifyChecksums != "":
+		return errors.New("checksums cannot be verified when keeping the archive of all artifacts")
+	case o.Cache != nil:
+		return errors.New("the archive of all artifacts cannot be cached")
+	}
+
+	return nil
+}
+
+// downloadedArtifact is an artifact file downloaded from a build.
+type downloadedArtifact struct {
+	// artifactPath is the path of the file in the artifacts of the build, empty for the archive of all artifacts
+	artifactPath string
+	// path is the slash separated path of the downloaded file, relative to the download directory
+	path string
+}
+
+// DownloadBuildArtifacts downloads the artifacts of a build selected by the options to destPath, and writes a manifest of the downloaded files next to them.
+// All artifacts are downloaded as a single archive by default. With a filter or PerFile, each selected file is streamed to its destination,
+// in parallel, and its download is retried and resumed on failures.
+// If a checksum file is set, the downloaded files are verified against it, failing with ErrChecksumMismatch.
+// The verified archives found in the artifacts are then extracted with ExtractNested.
+// Nothing is downloaded if no artifact matches the filter, which fails with ErrNoArtifactsMatch only with RequireMatch.
+func DownloadBuildArtifacts(c *Client, buildID int, buildTypeID, destPath string, opts ArtifactOptions) error {
+	err := opts.Validate()
 	if err != nil {
-		return []byte{}, err
+		return err
 	}
 
-	resp, err := as.client.client.Do(req)
+	opts.Cache = cacheForServer(c, opts.Cache)
+
+	downloaded, err := downloadBuildArtifacts(c, buildID, buildTypeID, destPath, opts)
+	if errors.Is(err, ErrNoArtifactsMatch) && !opts.RequireMatch {
+		log.Warnf("build %d: %s", buildID, err)
+		return nil
+	}
 
 	if err != nil {
-		return nil, fmt.Errorf("error getting all artifacts for buildID %d: %w", buildID, err)
+		return err
 	}
 
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
+	manifest, err := WriteArtifactsManifest(c, buildID, buildTypeID, destPath, downloaded)
+	if err != nil {
+		return err
+	}
+
+	if opts.VerifyChecksums != "" {
+		err = VerifyChecksums(c, buildID, opts.VerifyChecksums, opts.Filter, manifest)
 		if err != nil {
-			log.Errorf("error closing response body: %s", err)
+			return err
 		}
-	}(resp.Body)
+	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("statusCode: %d", resp.StatusCode)
+	if opts.ExtractNested {
+		return extractNestedArchives(destPath, downloaded)
 	}
 
-	return io.ReadAll(resp.Body)
+	return nil
 }
 
-// DownloadAndUnzipArtifacts downloads all artifacts  to given path and unzips them.
-func (as *ArtifactsService) DownloadAndUnzipArtifacts(buildID int, buildTypeID, destPath string) error {
-	content, err := as.GetAllBuildTypeArtifacts(buildID, buildTypeID)
+func downloadBuildArtifacts(c *Client, buildID int, buildTypeID, destPath string, opts ArtifactOptions) ([]downloadedArtifact, error) {
+	if opts.NoExtract || (opts.Filter.IsEmpty() && !opts.PerFile) {
+		return downloadAllArtifacts(c, buildID, buildTypeID, destPath, opts)
+	}
+
+	files, err := c.Artifacts.ListArtifacts(buildID)
 	if err != nil {
-		log.Errorf("error getting artifacts content: %s", err)
-		return fmt.Errorf("error getting artifacts content: %w", err)
+		return nil, fmt.Errorf("error listing artifacts: %w", err)
 	}
-	// if size of content is 0, then no artifacts were found
-	if len(content) == 0 {
-		return errors.New("artifacts not found")
+
+	var selected []downloadedArtifact
+	var selectedFiles []types.ArtifactFile
+
+	for _, file := range files {
+		dest, ok := opts.Filter.Destination(file.FullName)
+		if !ok {
+			continue
+		}
+
+		// the artifact paths come from the server, nothing may be written outside of destPath
+		if !filepath.IsLocal(filepath.FromSlash(dest)) {
+			return nil, fmt.Errorf("artifact %s resolves to %s outside of %s", file.FullName, dest, destPath)
+		}
+
+		selected = append(selected, downloadedArtifact{artifactPath: file.FullName, path: dest})
+		selectedFiles = append(selectedFiles, file)
+	}
+
+	if len(selected) == 0 {
+		if opts.Filter.IsEmpty() {
+			return nil, errors.New("artifacts not found")
+		}
+
+		return nil, fmt.Errorf("%w: none of the %d artifacts match", ErrNoArtifactsMatch, len(files))
 	}
 
-	err = utils.CreateDir(destPath)
+	err = checkArtifactsSize(buildID, selectedFiles, opts.MaxSize)
 	if err != nil {
-		log.Errorf("error creating dir %s: %s", destPath, err)
-		return fmt.Errorf("error creating dir: %w", err)
+		return nil, err
 	}
-	// create uuid for temporary artifacts zip file, to prevent overwriting
-	fileID := uuid.New().String()
-	artifactsZip := filepath.Join(destPath, fileID+"-artifacts.zip")
 
-	log.WithField("artifactsPath", destPath).Debug("writing Artifacts to path")
+	if opts.Claims != nil {
+		paths := make([]string, 0, len(selected))
+		for _, artifact := range selected {
+			paths = append(paths, artifact.path)
+		}
+
+		err = opts.Claims.Claim(buildID, destPath, paths)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the cached files are restored without downloading them
+	var needed int64
+	for _, file := range selectedFiles {
+		if opts.Cache != nil {
+			if _, cached := opts.Cache.Lookup(buildID, file.FullName); cached {
+				continue
+			}
+		}
+
+		needed += file.Size
+	}
+
+	err = checkDiskSpace(buildID, destPath, needed)
+	if err != nil {
+		return nil, err
+	}
+
+	parallelism := opts.Parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	pool := pond.New(parallelism, len(selected))
+	defer pool.StopAndWait()
+
+	var mu sync.Mutex // Protects the results during concurrent downloads.
+	var downloaded, fetched []downloadedArtifact
+	var downloadErrors []error
+
+	for i := range selected {
+		artifact, file := selected[i], selectedFiles[i] // Local scope redeclaration for closure
+		pool.Submit(func() {
+			destFile := filepath.Join(destPath, filepath.FromSlash(artifact.path))
+
+			if restoreFromCache(opts.Cache, buildID, file.FullName, destFile) {
+				mu.Lock()
+				downloaded = append(downloaded, artifact)
+				mu.Unlock()
+
+				return
+			}
+
+			log.WithField("size", file.Size).Debugf("downloading artifact %s to %s", file.FullName, destFile)
+
+			err := c.Artifacts.DownloadArtifact(file, destFile, opts.Retries)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				downloadErrors = append(downloadErrors, err)
+				return
+			}
+
+			downloaded = append(downloaded, artifact)
+			fetched = append(fetched, artifact)
+		})
+	}
+
+	pool.StopAndWait()
+
+	// keep the manifest stable regardless of the order the downloads finished in
+	sort.Slice(downloaded, func(i, j int) bool {
+		return downloaded[i].path < downloaded[j].path
+	})
+
+	var all []downloadedArtifact
+	if opts.Filter.IsEmpty() && len(downloadErrors) == 0 {
+		all = downloaded
+	}
+
+	storeInCache(opts.Cache, buildID, destPath, fetched, all)
+
+	if len(downloadErrors) > 0 {
+		return downloaded, fmt.Errorf("error downloading %d of %d artifacts: %w", len(downloadErrors), len(selected), errors.Join(downloadErrors...))
+	}
+
+	if len(fetched) < len(downloaded) {
+		log.Infof("restored %d of %d artifacts from the cache", len(downloaded)-len(fetched), len(downloaded))
+	}
 
-	err = utils.WriteContentToFile(artifactsZip, content)
+	log.Infof("downloaded %d of %d artifacts", len(downloaded), len(files))
+
+	return downloaded, nil
+}
+
+// ArtifactsArchiveName is the file name of the archive of all artifacts of a build kept with NoExtract.
+func ArtifactsArchiveName(buildTypeID string, buildID int) string {
+	return fmt.Sprintf("%s-%d-artifacts.zip", buildTypeID, buildID)
+}
+
+// downloadAllArtifacts downloads all artifacts of a build as a single zip, which is extracted to destPath unless NoExtract is set.
+// The artifacts are listed first, to claim their files and to verify that they fit the size limit and the free disk space before downloading any of them.
+func downloadAllArtifacts(c *Client, buildID int, buildTypeID, destPath string, opts ArtifactOptions) ([]downloadedArtifact, error) {
+	files, err := c.Artifacts.ListArtifacts(buildID)
 	if err != nil {
-		log.Errorf("error writing content to file: %s", err)
-		return fmt.Errorf("error writing content to file: %w", err)
+		return nil, fmt.Errorf("error listing artifacts: %w", err)
 	}
 
-	err = utils.UnzipFile(artifactsZip, destPath)
+	err = checkArtifactsSize(buildID, files, opts.MaxSize)
 	if err != nil {
-		log.Errorf("error unzipping artifacts: %s", err)
-		return fmt.Errorf("error unzipping artifacts: %w", err)
+		return nil, err
 	}
 
-	err = os.Remove(artifactsZip)
+	if opts.NoExtract {
+		err = checkDiskSpace(buildID, destPath, artifactsSize(files))
+		if err != nil {
+			return nil, err
+		}
+
+		return downloadArtifactsArchive(c, buildID, buildTypeID, destPath)
+	}
+
+	if opts.Claims != nil {
+		paths := make([]string, 0, len(files))
+		for _, file := range files {
+			paths = append(paths, file.FullName)
+		}
+
+		err = opts.Claims.Claim(buildID, destPath, paths)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	paths, cached := restoreBuildFromCache(opts.Cache, buildID, destPath)
+	if !cached {
+		// the zip and the files extracted from it are on disk at the same time
+		err = checkDiskSpace(buildID, destPath, 2*artifactsSize(files))
+		if err != nil {
+			return nil, err
+		}
+
+		paths, err = c.Artifacts.DownloadAndUnzipArtifacts(buildID, buildTypeID, destPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	downloaded := make([]downloadedArtifact, 0, len(paths))
+	for _, artifactPath := range paths {
+		downloaded = append(downloaded, downloadedArtifact{artifactPath: artifactPath, path: artifactPath})
+	}
+
+	if !cached {
+		storeInCache(opts.Cache, buildID, destPath, downloaded, downloaded)
+	}
+
+	return downloaded, nil
+}
+
+// downloadArtifactsArchive downloads the archive of all artifacts of a build to destPath without extracting it.
+func downloadArtifactsArchive(c *Client, buildID int, buildTypeID, destPath string) ([]downloadedArtifact, error) {
+	name := ArtifactsArchiveName(buildTypeID, buildID)
+	archive := filepath.Join(destPath, name)
+
+	err := os.MkdirAll(destPath, 0o755)
 	if err != nil {
-		log.Errorf("error deleting zip: %s", err)
-		return fmt.Errorf("error deleting zip: %w", err)
+		return nil, fmt.Errorf("error creating dir: %w", err)
+	}
+
+	size, err := c.Artifacts.DownloadAllBuildTypeArtifacts(buildID, buildTypeID, archive)
+	if err != nil {
+		return nil, fmt.Errorf("error getting artifacts content: %w", err)
+	}
+	// if size of content is 0, then no artifacts were found
+	if size == 0 {
+		os.Remove(archive)
+		return nil, errors.New("artifacts not found")
+	}
+
+	log.Infof("downloaded the artifacts of build %d to %s", buildID, archive)
+
+	return []downloadedArtifact{{path: name}}, nil
+}
+
+// extractNestedArchives extracts the archives among the downloaded artifacts next to them, in a directory named after the archive.
+// Archives are kept, and the archives they contain are not extracted.
+func extractNestedArchives(destPath string, downloaded []downloadedArtifact) error {
+	for _, file := range downloaded {
+		if _, ok := utils.ArchiveExtractor(file.path); !ok {
+			continue
+		}
+
+		dir, name := path.Split(file.path)
+
+		baseName := utils.ArchiveBaseName(name)
+		if baseName == "" {
+			log.Warnf("skipping nested archive %s without a name", file.path)
+			continue
+		}
+
+		archive := filepath.Join(destPath, filepath.FromSlash(file.path))
+		extractDir := filepath.Join(destPath, filepath.FromSlash(dir), baseName)
+
+		files, err := utils.ExtractArchive(archive, extractDir, utils.DefaultExtractOptions)
+		if err != nil {
+			return fmt.Errorf("error extracting nested archive %s: %w", file.path, err)
+		}
+
+		log.Infof("extracted %d files of nested archive %s to %s", len(files), file.path, extractDir)
 	}
 
 	return nil
@@ -0,0 +1,517 @@
+package teamcity_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"bbox/pkg/cache"
+	"bbox/pkg/types"
+	"bbox/pkg/utils/testutils"
+	"bbox/teamcity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// writeArtifact returns a mock run function writing content to the destination file of DownloadArtifact.
+func writeArtifact(content string) func(mock.Arguments) {
+	return func(args mock.Arguments) {
+		destFile := args.String(1)
+		_ = os.MkdirAll(filepath.Dir(destFile), 0o755)
+		_ = os.WriteFile(destFile, []byte(content), 0o644)
+	}
+}
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+
+	return hex.EncodeToString(sum[:])
+}
+
+func TestDownloadBuildArtifactsFiltered(t *testing.T) {
+	mockArtifactsService := new(testutils.MockArtifactsService)
+	client := &teamcity.Client{Artifacts: mockArtifactsService}
+
+	jar := types.ArtifactFile{Name: "app.jar", FullName: "dist/lib/app.jar", Size: 5}
+	sources := types.ArtifactFile{Name: "app-sources.jar", FullName: "dist/lib/app-sources.jar"}
+	bundle := types.ArtifactFile{Name: "bundle.zip", FullName: "bundle.zip", Size: 4 << 30}
+
+	destPath := t.TempDir()
+
+	mockArtifactsService.On("ListArtifacts", 10).Return([]types.ArtifactFile{jar, sources, bundle}, nil)
+	mockArtifactsService.On("DownloadArtifact", jar, filepath.Join(destPath, "jars", "lib", "app.jar"), 0).Return(nil).Run(writeArtifact("jar"))
+
+	filter, err := teamcity.NewArtifactFilter([]string{"dist/**/*.jar => jars"}, []string{"**/*-sources.jar"})
+	require.NoError(t, err)
+
+	err = teamcity.DownloadBuildArtifacts(client, 10, "Backend_Build", destPath, teamcity.ArtifactOptions{Filter: filter})
+	require.NoError(t, err)
+
+	mockArtifactsService.AssertNumberOfCalls(t, "DownloadArtifact", 1)
+	mockArtifactsService.AssertNotCalled(t, "DownloadAndUnzipArtifacts")
+
+	content, err := os.ReadFile(filepath.Join(destPath, "bbox-manifest-10.json"))
+	require.NoError(t, err)
+
+	var manifest types.ArtifactsManifest
+	require.NoError(t, json.Unmarshal(content, &manifest))
+
+	assert.Equal(t, 10, manifest.BuildID)
+	assert.Equal(t, "Backend_Build", manifest.BuildTypeID)
+	assert.Equal(t, []types.ManifestFile{{
+		Path:         "jars/lib/app.jar",
+		ArtifactPath: "dist/lib/app.jar",
+		Size:         3,
+		SHA256:       sha256Hex("jar"),
+	}}, manifest.Files)
+}
+
+func TestDownloadBuildArtifactsVerifyChecksums(t *testing.T) {
+	jar := types.ArtifactFile{Name: "app.jar", FullName: "dist/app.jar"}
+	sums := types.ArtifactFile{Name: "SHA256SUMS", FullName: "dist/SHA256SUMS"}
+
+	testCases := []struct {
+		name      string
+		checksums string
+		wantErr   error
+		errSubstr string
+	}{
+		{
+			name:      "matching checksum",
+			checksums: sha256Hex("jar") + "  app.jar\n",
+		},
+		{
+			name:      "binary mode checksum with files outside the filter",
+			checksums: sha256Hex("map") + " *app.jar.map\n" + sha256Hex("jar") + " *./app.jar\n",
+		},
+		{
+			name:      "listed file not downloaded",
+			checksums: sha256Hex("other") + "  other.jar\n" + sha256Hex("jar") + "  app.jar\n",
+			wantErr:   teamcity.ErrChecksumMismatch,
+			errSubstr: "1 artifacts listed in dist/SHA256SUMS were not downloaded: dist/other.jar",
+		},
+		{
+			name:      "mismatching checksum",
+			checksums: sha256Hex("tampered") + "  app.jar\n",
+			wantErr:   teamcity.ErrChecksumMismatch,
+		},
+		{
+			name:      "not listed",
+			checksums: sha256Hex("map") + "  app.jar.map\n",
+			errSubstr: "none of the 2 downloaded artifacts are listed",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockArtifactsService := new(testutils.MockArtifactsService)
+			client := &teamcity.Client{Artifacts: mockArtifactsService}
+
+			destPath := t.TempDir()
+
+			mockArtifactsService.On("ListArtifacts", 10).Return([]types.ArtifactFile{jar, sums}, nil)
+			mockArtifactsService.On("DownloadArtifact", jar, filepath.Join(destPath, "app.jar"), 0).Return(nil).Run(writeArtifact("jar"))
+			mockArtifactsService.On("DownloadArtifact", sums, filepath.Join(destPath, "SHA256SUMS"), 0).Return(nil).Run(writeArtifact(tc.checksums))
+			mockArtifactsService.On("GetArtifactContentByPath", "app/rest/builds/id:10/artifacts/content/dist/SHA256SUMS").Return([]byte(tc.checksums), nil)
+
+			filter, err := teamcity.NewArtifactFilter([]string{"dist/* => ."}, []string{"**/*.map"})
+			require.NoError(t, err)
+
+			err = teamcity.DownloadBuildArtifacts(client, 10, "Backend_Build", destPath, teamcity.ArtifactOptions{Filter: filter, VerifyChecksums: "dist/SHA256SUMS"})
+
+			if tc.wantErr == nil && tc.errSubstr == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+			}
+
+			if tc.errSubstr != "" {
+				assert.ErrorContains(t, err, tc.errSubstr)
+			}
+		})
+	}
+}
+
+func TestDownloadBuildArtifactsPerFile(t *testing.T) {
+	mockArtifactsService := new(testutils.MockArtifactsService)
+	client := &teamcity.Client{Artifacts: mockArtifactsService}
+
+	files := []types.ArtifactFile{
+		{Name: "b.txt", FullName: "dist/b.txt"},
+		{Name: "a.txt", FullName: "a.txt"},
+		{Name: "c.txt", FullName: "dist/sub/c.txt"},
+	}
+
+	destPath := t.TempDir()
+
+	mockArtifactsService.On("ListArtifacts", 10).Return(files, nil)
+	for _, file := range files {
+		mockArtifactsService.On("DownloadArtifact", file, filepath.Join(destPath, filepath.FromSlash(file.FullName)), 3).Return(nil).Run(writeArtifact(file.Name))
+	}
+
+	err := teamcity.DownloadBuildArtifacts(client, 10, "Backend_Build", destPath, teamcity.ArtifactOptions{PerFile: true, Parallelism: 2, Retries: 3})
+	require.NoError(t, err)
+
+	mockArtifactsService.AssertNumberOfCalls(t, "DownloadArtifact", 3)
+	mockArtifactsService.AssertNotCalled(t, "DownloadAndUnzipArtifacts")
+
+	content, err := os.ReadFile(filepath.Join(destPath, "bbox-manifest-10.json"))
+	require.NoError(t, err)
+
+	var manifest types.ArtifactsManifest
+	require.NoError(t, json.Unmarshal(content, &manifest))
+
+	var paths []string
+	for _, file := range manifest.Files {
+		paths = append(paths, file.Path)
+	}
+
+	assert.Equal(t, []string{"a.txt", "dist/b.txt", "dist/sub/c.txt"}, paths, "the manifest is sorted regardless of the download order")
+}
+
+func TestDownloadBuildArtifactsPerFileErrors(t *testing.T) {
+	mockArtifactsService := new(testutils.MockArtifactsService)
+	client := &teamcity.Client{Artifacts: mockArtifactsService}
+
+	ok := types.ArtifactFile{Name: "ok.txt", FullName: "ok.txt"}
+	broken := types.ArtifactFile{Name: "broken.txt", FullName: "broken.txt"}
+
+	destPath := t.TempDir()
+
+	mockArtifactsService.On("ListArtifacts", 10).Return([]types.ArtifactFile{ok, broken}, nil)
+	mockArtifactsService.On("DownloadArtifact", ok, filepath.Join(destPath, "ok.txt"), 0).Return(nil).Run(writeArtifact("ok"))
+	mockArtifactsService.On("DownloadArtifact", broken, filepath.Join(destPath, "broken.txt"), 0).Return(errors.New("connection reset"))
+
+	err := teamcity.DownloadBuildArtifacts(client, 10, "Backend_Build", destPath, teamcity.ArtifactOptions{PerFile: true, Parallelism: 4})
+	assert.ErrorContains(t, err, "error downloading 1 of 2 artifacts: connection reset")
+
+	mockArtifactsService.AssertNumberOfCalls(t, "DownloadArtifact", 2)
+}
+
+func TestDownloadBuildArtifactsOutsideOfDestPath(t *testing.T) {
+	mockArtifactsService := new(testutils.MockArtifactsService)
+	client := &teamcity.Client{Artifacts: mockArtifactsService}
+
+	// a malicious or broken server returning an artifact path escaping the download directory
+	escaping := types.ArtifactFile{Name: "evil.sh", FullName: "../../evil.sh"}
+	mockArtifactsService.On("ListArtifacts", 10).Return([]types.ArtifactFile{{Name: "app.jar", FullName: "app.jar"}, escaping}, nil)
+
+	err := teamcity.DownloadBuildArtifacts(client, 10, "Backend_Build", t.TempDir(), teamcity.ArtifactOptions{PerFile: true})
+	assert.ErrorContains(t, err, "artifact ../../evil.sh resolves to ../../evil.sh outside of")
+
+	mockArtifactsService.AssertNotCalled(t, "DownloadArtifact")
+
+	_, err = teamcity.NewArtifactFilter([]string{"**/*.jar => ../lib"}, nil)
+	assert.ErrorContains(t, err, "outside of the artifacts path")
+}
+
+func TestDownloadBuildArtifactsNoMatch(t *testing.T) {
+	mockArtifactsService := new(testutils.MockArtifactsService)
+	client := &teamcity.Client{Artifacts: mockArtifactsService}
+
+	mockArtifactsService.On("ListArtifacts", 10).Return([]types.ArtifactFile{{FullName: "bundle.zip"}}, nil)
+
+	filter, err := teamcity.NewArtifactFilter([]string{"**/*.jar"}, nil)
+	require.NoError(t, err)
+
+	destPath := t.TempDir()
+
+	err = teamcity.DownloadBuildArtifacts(client, 10, "Backend_Build", destPath, teamcity.ArtifactOptions{Filter: filter})
+	require.NoError(t, err, "only a warning unless a match is required")
+
+	entries, err := os.ReadDir(destPath)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "nothing is downloaded")
+
+	err = teamcity.DownloadBuildArtifacts(client, 10, "Backend_Build", destPath, teamcity.ArtifactOptions{Filter: filter, RequireMatch: true})
+	require.ErrorIs(t, err, teamcity.ErrNoArtifactsMatch)
+	assert.ErrorContains(t, err, "none of the 1 artifacts match")
+}
+
+func TestDownloadBuildArtifactsWithoutFilter(t *testing.T) {
+	mockArtifactsService := new(testutils.MockArtifactsService)
+	client := &teamcity.Client{Artifacts: mockArtifactsService}
+
+	mockArtifactsService.On("ListArtifacts", 10).Return([]types.ArtifactFile{{Name: "app.jar", FullName: "app.jar", Size: 3}}, nil)
+	mockArtifactsService.On("GetAllBuildTypeArtifacts", 10, "Backend_Build").Return([]byte("zip"), nil)
+	mockArtifactsService.On("DownloadAndUnzipArtifacts", 10, "Backend_Build", "out").Return(nil, nil)
+
+	err := teamcity.DownloadBuildArtifacts(client, 10, "Backend_Build", "out", teamcity.ArtifactOptions{})
+	require.NoError(t, err)
+
+	mockArtifactsService.AssertNotCalled(t, "DownloadArtifact")
+}
+
+func TestDownloadBuildArtifactsCache(t *testing.T) {
+	mockArtifactsService := new(testutils.MockArtifactsService)
+	client := &teamcity.Client{Artifacts: mockArtifactsService}
+
+	artifactCache, err := cache.OpenArtifactCache(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	files := []types.ArtifactFile{
+		{Name: "a.txt", FullName: "a.txt"},
+		{Name: "b.txt", FullName: "dist/b.txt"},
+	}
+
+	mockArtifactsService.On("ListArtifacts", 10).Return(files, nil)
+	mockArtifactsService.On("DownloadArtifact", mock.Anything, mock.Anything, 0).Return(nil).Run(func(args mock.Arguments) {
+		writeArtifact(args.Get(0).(types.ArtifactFile).Name)(args)
+	})
+
+	opts := teamcity.ArtifactOptions{PerFile: true, Parallelism: 2, Cache: artifactCache}
+
+	err = teamcity.DownloadBuildArtifacts(client, 10, "Backend_Build", t.TempDir(), opts)
+	require.NoError(t, err)
+	mockArtifactsService.AssertNumberOfCalls(t, "DownloadArtifact", 2)
+
+	destPath := t.TempDir()
+
+	err = teamcity.DownloadBuildArtifacts(client, 10, "Backend_Build", destPath, opts)
+	require.NoError(t, err)
+	mockArtifactsService.AssertNumberOfCalls(t, "DownloadArtifact", 2)
+
+	content, err := os.ReadFile(filepath.Join(destPath, "dist", "b.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "b.txt", string(content), "the artifact is restored from the cache")
+
+	// all artifacts of the build are cached, so the zip is not downloaded either
+	destPath = t.TempDir()
+
+	err = teamcity.DownloadBuildArtifacts(client, 10, "Backend_Build", destPath, teamcity.ArtifactOptions{Cache: artifactCache})
+	require.NoError(t, err)
+	mockArtifactsService.AssertNotCalled(t, "DownloadAndUnzipArtifacts")
+	assert.FileExists(t, filepath.Join(destPath, "a.txt"))
+	assert.FileExists(t, filepath.Join(destPath, "bbox-manifest-10.json"))
+}
+
+func TestArtifactOptionsValidate(t *testing.T) {
+	t.Parallel()
+
+	filter, err := teamcity.NewArtifactFilter([]string{"**/*.jar"}, nil)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name string
+		opts teamcity.ArtifactOptions
+		err  string
+	}{
+		{name: "defaults", opts: teamcity.ArtifactOptions{}},
+		{name: "extract nested", opts: teamcity.ArtifactOptions{ExtractNested: true, PerFile: true}},
+		{name: "no extract", opts: teamcity.ArtifactOptions{NoExtract: true}},
+		{name: "no extract with extract nested", opts: teamcity.ArtifactOptions{NoExtract: true, ExtractNested: true}, err: "cannot be extracted"},
+		{name: "no extract per file", opts: teamcity.ArtifactOptions{NoExtract: true, PerFile: true}, err: "file by file"},
+		{name: "no extract with filter", opts: teamcity.ArtifactOptions{NoExtract: true, Filter: filter}, err: "file by file"},
+		{name: "no extract with checksums", opts: teamcity.ArtifactOptions{NoExtract: true, VerifyChecksums: "SHA256SUMS"}, err: "checksums cannot be verified"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.opts.Validate()
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestDownloadBuildArtifactsNoExtract(t *testing.T) {
+	mockArtifactsService := new(testutils.MockArtifactsService)
+	client := &teamcity.Client{Artifacts: mockArtifactsService}
+
+	destPath := t.TempDir()
+	archive := filepath.Join(destPath, "Backend_Build-10-artifacts.zip")
+
+	mockArtifactsService.On("ListArtifacts", 10).Return([]types.ArtifactFile{{Name: "app.jar", FullName: "app.jar", Size: 3}}, nil)
+	mockArtifactsService.On("DownloadAllBuildTypeArtifacts", 10, "Backend_Build", archive).Return(int64(3), nil).Run(func(args mock.Arguments) {
+		_ = os.WriteFile(args.String(2), []byte("zip"), 0o644)
+	})
+
+	err := teamcity.DownloadBuildArtifacts(client, 10, "Backend_Build", destPath, teamcity.ArtifactOptions{NoExtract: true})
+	require.NoError(t, err)
+
+	mockArtifactsService.AssertNotCalled(t, "DownloadAndUnzipArtifacts")
+	assert.FileExists(t, archive, "the archive is kept")
+
+	content, err := os.ReadFile(filepath.Join(destPath, "bbox-manifest-10.json"))
+	require.NoError(t, err)
+
+	var manifest types.ArtifactsManifest
+	require.NoError(t, json.Unmarshal(content, &manifest))
+	require.Len(t, manifest.Files, 1)
+	assert.Equal(t, types.ManifestFile{Path: "Backend_Build-10-artifacts.zip", Size: 3, SHA256: sha256Hex("zip")}, manifest.Files[0])
+}
+
+// tgz returns a gzip compressed tar archive of a single file.
+func tgz(t *testing.T, name, content string) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	gzipWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	require.NoError(t, tarWriter.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(content))}))
+	_, err := tarWriter.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, tarWriter.Close())
+	require.NoError(t, gzipWriter.Close())
+
+	return buf.String()
+}
+
+func TestDownloadBuildArtifactsExtractNested(t *testing.T) {
+	mockArtifactsService := new(testutils.MockArtifactsService)
+	client := &teamcity.Client{Artifacts: mockArtifactsService}
+
+	bundle := types.ArtifactFile{Name: "bundle.tgz", FullName: "dist/bundle.tgz"}
+	jar := types.ArtifactFile{Name: "app.jar", FullName: "dist/app.jar"}
+
+	destPath := t.TempDir()
+
+	mockArtifactsService.On("ListArtifacts", 10).Return([]types.ArtifactFile{bundle, jar}, nil)
+	mockArtifactsService.On("DownloadArtifact", bundle, filepath.Join(destPath, "dist", "bundle.tgz"), 0).Return(nil).Run(writeArtifact(tgz(t, "bin/tool", "tool")))
+	mockArtifactsService.On("DownloadArtifact", jar, filepath.Join(destPath, "dist", "app.jar"), 0).Return(nil).Run(writeArtifact("jar"))
+
+	err := teamcity.DownloadBuildArtifacts(client, 10, "Backend_Build", destPath, teamcity.ArtifactOptions{PerFile: true, ExtractNested: true})
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(filepath.Join(destPath, "dist", "bundle", "bin", "tool"))
+	require.NoError(t, err)
+	assert.Equal(t, "tool", string(content))
+	assert.FileExists(t, filepath.Join(destPath, "dist", "bundle.tgz"), "nested archives are kept")
+}
+
+func TestDownloadBuildArtifactsCollision(t *testing.T) {
+	mockArtifactsService := new(testutils.MockArtifactsService)
+	client := &teamcity.Client{Artifacts: mockArtifactsService}
+
+	app := types.ArtifactFile{Name: "app.jar", FullName: "dist/app.jar"}
+	destPath := t.TempDir()
+
+	mockArtifactsService.On("ListArtifacts", 10).Return([]types.ArtifactFile{app}, nil)
+	mockArtifactsService.On("ListArtifacts", 11).Return([]types.ArtifactFile{app}, nil)
+	mockArtifactsService.On("DownloadArtifact", app, mock.Anything, 0).Return(nil).Run(writeArtifact("jar"))
+
+	opts := teamcity.ArtifactOptions{PerFile: true, Claims: teamcity.NewArtifactClaims()}
+
+	err := teamcity.DownloadBuildArtifacts(client, 10, "Backend_Build", destPath, opts)
+	require.NoError(t, err)
+
+	err = teamcity.DownloadBuildArtifacts(client, 10, "Backend_Build", destPath, opts)
+	require.NoError(t, err, "a build can download its artifacts again")
+
+	err = teamcity.DownloadBuildArtifacts(client, 11, "Backend_Build", destPath, opts)
+	assert.ErrorIs(t, err, teamcity.ErrArtifactCollision)
+	assert.ErrorContains(t, err, "of build 11 is also downloaded by build 10")
+	mockArtifactsService.AssertNumberOfCalls(t, "DownloadArtifact", 2)
+
+	// the files of the archive are claimed before it is downloaded
+	err = teamcity.DownloadBuildArtifacts(client, 11, "Backend_Build", destPath, teamcity.ArtifactOptions{Claims: opts.Claims})
+	assert.ErrorIs(t, err, teamcity.ErrArtifactCollision)
+	mockArtifactsService.AssertNotCalled(t, "DownloadAndUnzipArtifacts")
+
+	err = teamcity.DownloadBuildArtifacts(client, 11, "Backend_Build", filepath.Join(destPath, "11"), opts)
+	require.NoError(t, err, "builds do not collide in different directories")
+	assert.FileExists(t, filepath.Join(destPath, "11", "dist", "app.jar"))
+}
+
+func TestDownloadBuildArtifactsMaxSize(t *testing.T) {
+	testCases := []struct {
+		name string
+		opts teamcity.ArtifactOptions
+	}{
+		{name: "zip", opts: teamcity.ArtifactOptions{MaxSize: 1024}},
+		{name: "per file", opts: teamcity.ArtifactOptions{PerFile: true, MaxSize: 1024}},
+		{name: "no extract", opts: teamcity.ArtifactOptions{NoExtract: true, MaxSize: 1024}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockArtifactsService := new(testutils.MockArtifactsService)
+			client := &teamcity.Client{Artifacts: mockArtifactsService}
+
+			mockArtifactsService.On("ListArtifacts", 10).Return([]types.ArtifactFile{
+				{Name: "app.jar", FullName: "dist/app.jar", Size: 1000},
+				{Name: "lib.jar", FullName: "dist/lib.jar", Size: 1000},
+			}, nil)
+
+			err := teamcity.DownloadBuildArtifacts(client, 10, "Backend_Build", t.TempDir(), tc.opts)
+			assert.ErrorIs(t, err, teamcity.ErrArtifactsTooLarge)
+			assert.ErrorContains(t, err, "the 2 artifacts of build 10 are 2.0 KiB, the limit is 1.0 KiB")
+
+			mockArtifactsService.AssertNotCalled(t, "DownloadAndUnzipArtifacts")
+			mockArtifactsService.AssertNotCalled(t, "DownloadAllBuildTypeArtifacts")
+			mockArtifactsService.AssertNotCalled(t, "DownloadArtifact")
+		})
+	}
+}
+
+func TestDownloadBuildArtifactsMaxSizeFiltered(t *testing.T) {
+	mockArtifactsService := new(testutils.MockArtifactsService)
+	client := &teamcity.Client{Artifacts: mockArtifactsService}
+
+	app := types.ArtifactFile{Name: "app.jar", FullName: "dist/app.jar", Size: 3}
+	destPath := t.TempDir()
+
+	mockArtifactsService.On("ListArtifacts", 10).Return([]types.ArtifactFile{app, {Name: "app.map", FullName: "dist/app.map", Size: 1 << 30}}, nil)
+	mockArtifactsService.On("DownloadArtifact", app, filepath.Join(destPath, "dist", "app.jar"), 0).Return(nil).Run(writeArtifact("jar"))
+
+	filter, err := teamcity.NewArtifactFilter(nil, []string{"**/*.map"})
+	require.NoError(t, err)
+
+	err = teamcity.DownloadBuildArtifacts(client, 10, "Backend_Build", destPath, teamcity.ArtifactOptions{Filter: filter, MaxSize: 1024})
+	require.NoError(t, err, "only the selected artifacts count against the limit")
+}
+
+func TestDownloadBuildArtifactsInsufficientDiskSpace(t *testing.T) {
+	testCases := []struct {
+		name string
+		opts teamcity.ArtifactOptions
+	}{
+		{name: "zip", opts: teamcity.ArtifactOptions{}},
+		{name: "per file", opts: teamcity.ArtifactOptions{PerFile: true}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockArtifactsService := new(testutils.MockArtifactsService)
+			client := &teamcity.Client{Artifacts: mockArtifactsService}
+
+			// no runner has an exbibyte to spare
+			mockArtifactsService.On("ListArtifacts", 10).Return([]types.ArtifactFile{{Name: "huge.bin", FullName: "huge.bin", Size: 1 << 60}}, nil)
+
+			destPath := t.TempDir()
+
+			err := teamcity.DownloadBuildArtifacts(client, 10, "Backend_Build", destPath, tc.opts)
+			assert.ErrorIs(t, err, teamcity.ErrInsufficientDiskSpace)
+			assert.ErrorContains(t, err, "downloading the artifacts of build 10 to "+destPath+" needs")
+
+			mockArtifactsService.AssertNotCalled(t, "DownloadAndUnzipArtifacts")
+			mockArtifactsService.AssertNotCalled(t, "DownloadArtifact")
+
+			entries, err := os.ReadDir(destPath)
+			require.NoError(t, err)
+			assert.Empty(t, entries, "nothing is written")
+		})
+	}
+}
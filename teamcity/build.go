@@ -6,21 +6,30 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"strings"
 	"time"
 
+	"bbox/pkg/params"
 	"bbox/pkg/types"
 
 	"github.com/avast/retry-go/v4"
 	log "github.com/sirupsen/logrus"
 )
 
+const (
+	buildsPageSize          = 100
+	buildDependenciesFields = "id,buildTypeId,number,status,state,canceledInfo(timestamp),failedToStart,webUrl,buildType(id,name,projectName,projectId),snapshot-dependencies(count,build(id)),artifact-dependencies(count,build(id))"
+	buildsFields            = "count,nextHref,build(id,buildTypeId,number,status,state,branchName,statusText,canceledInfo(timestamp,text,user(username,name)),failedToStart,webUrl,queuedDate,startDate,finishDate,buildType(id,name,projectName,projectId),triggered(type,user(username,name)))"
+)
+
 type BuildService struct {
 	client *Client
 }
 
 // GetBuildStatus returns the status of a build.
 func (bs *BuildService) GetBuildStatus(buildID int) (types.BuildStatusResponse, error) {
-	getURL := fmt.Sprintf("%s/id:%d", "app/rest/builds", buildID)
+	getURL := "app/rest/builds/" + NewLocator().AddInt("id", buildID).PathSegment()
 
 	req, err := bs.client.NewRequestWrapper("GET", getURL, nil)
 	if err != nil {
@@ -48,11 +57,221 @@ func (bs *BuildService) GetBuildStatus(buildID int) (types.BuildStatusResponse,
 	return *bsr, nil
 }
 
+// GetResultingProperties returns the resulting properties of a finished build, filtered by the given glob patterns, e.g. "env.*".
+func (bs *BuildService) GetResultingProperties(buildID int, filters ...string) (map[string]string, error) {
+	getURL := "app/rest/builds/" + NewLocator().AddInt("id", buildID).PathSegment() + "/resulting-properties"
+
+	req, err := bs.client.NewRequestWrapper("GET", getURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	resp, err := bs.client.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error getting resulting properties for buildID %d: %w", buildID, err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			log.Errorf("error closing response body: %s", err)
+		}
+	}(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get resulting properties for buildID %d, status code: %d", buildID, resp.StatusCode)
+	}
+
+	var propertiesResponse types.ParametersResponse
+
+	err = json.NewDecoder(resp.Body).Decode(&propertiesResponse)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	properties := make(map[string]string, len(propertiesResponse.Parameters))
+	for _, property := range propertiesResponse.Parameters {
+		properties[property.Name] = property.Value
+	}
+
+	return params.FilterProperties(properties, filters)
+}
+
+// GetConstituentBuilds returns the status of the constituent builds of a composite build, which are its snapshot dependencies.
+func (bs *BuildService) GetConstituentBuilds(buildID int) ([]types.BuildStatusResponse, error) {
+	composite, err := bs.GetBuildStatus(buildID)
+	if err != nil {
+		return nil, err
+	}
+
+	constituents := make([]types.BuildStatusResponse, 0, len(composite.SnapshotDependencies.Build))
+	for _, dependency := range composite.SnapshotDependencies.Build {
+		constituent, err := bs.GetBuildStatus(dependency.ID)
+		if err != nil {
+			return constituents, fmt.Errorf("error getting constituent build %d of composite build %d: %w", dependency.ID, buildID, err)
+		}
+
+		constituents = append(constituents, constituent)
+	}
+
+	return constituents, nil
+}
+
+// GetBuildDependencies returns a build with the IDs of the builds it has snapshot and artifact dependencies on.
+func (bs *BuildService) GetBuildDependencies(buildID int) (types.BuildStatusResponse, error) {
+	getURL := withQuery("app/rest/builds/"+NewLocator().AddInt("id", buildID).PathSegment(), nil, buildDependenciesFields)
+
+	req, err := bs.client.NewRequestWrapper("GET", getURL, nil)
+	if err != nil {
+		return types.BuildStatusResponse{}, fmt.Errorf("error creating request: %w", err)
+	}
+
+	resp, err := bs.client.client.Do(req)
+	if err != nil {
+		return types.BuildStatusResponse{}, fmt.Errorf("error getting dependencies of build %d: %w", buildID, err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			log.Errorf("error closing response body: %s", err)
+		}
+	}(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return types.BuildStatusResponse{}, fmt.Errorf("failed to get dependencies of build %d, status code: %d", buildID, resp.StatusCode)
+	}
+
+	var build types.BuildStatusResponse
+	err = json.NewDecoder(resp.Body).Decode(&build)
+	if err != nil {
+		return types.BuildStatusResponse{}, fmt.Errorf("error decoding response body: %w", err)
+	}
+
+	return build, nil
+}
+
+// ListBuilds returns the builds matching the locator, following the pagination of the builds endpoint.
+func (bs *BuildService) ListBuilds(locator types.BuildLocator) ([]types.Build, error) {
+	pageSize := buildsPageSize
+	if locator.Count > 0 && locator.Count < pageSize {
+		pageSize = locator.Count
+	}
+
+	nextURL := withQuery("app/rest/builds", buildsLocator(locator, pageSize), buildsFields)
+	builds := []types.Build{}
+
+	for nextURL != "" {
+		req, err := bs.client.NewRequestWrapper("GET", nextURL, nil)
+		if err != nil {
+			return builds, fmt.Errorf("error creating request: %w", err)
+		}
+
+		resp, err := bs.client.client.Do(req)
+		if err != nil {
+			return builds, fmt.Errorf("error executing request to list builds: %w", err)
+		}
+
+		var buildsResponse types.BuildsResponse
+
+		if resp.StatusCode == http.StatusOK {
+			err = json.NewDecoder(resp.Body).Decode(&buildsResponse)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return builds, fmt.Errorf("failed to list builds, status code: %d", resp.StatusCode)
+		}
+
+		if err != nil {
+			return builds, fmt.Errorf("error decoding response body: %w", err)
+		}
+
+		builds = append(builds, buildsResponse.Builds...)
+
+		if locator.Count > 0 && len(builds) >= locator.Count {
+			return builds[:locator.Count], nil
+		}
+
+		// nextHref is relative to the server root, which may differ from the root of the base URL
+		nextURL = strings.TrimPrefix(buildsResponse.NextHref, "/")
+	}
+
+	return builds, nil
+}
+
+// buildsLocator builds the TeamCity locator of the builds endpoint for a BuildLocator.
+func buildsLocator(locator types.BuildLocator, pageSize int) *Locator {
+	buildsLocator := NewLocator()
+
+	if locator.BuildTypeID != "" {
+		buildsLocator.AddLocator("buildType", IDLocator(locator.BuildTypeID))
+	}
+
+	if locator.ProjectID != "" {
+		buildsLocator.AddLocator("affectedProject", IDLocator(locator.ProjectID))
+	}
+
+	if locator.Branch != "" {
+		buildsLocator.AddLocator("branch", NewLocator().Add("name", locator.Branch))
+	} else {
+		buildsLocator.AddLocator("branch", NewLocator().Add("default", "any"))
+	}
+
+	if locator.Revision != "" {
+		buildsLocator.AddLocator("revision", NewLocator().Add("version", locator.Revision))
+	}
+
+	if locator.Status != "" {
+		buildsLocator.Add("status", locator.Status)
+	}
+
+	if locator.State != "" {
+		buildsLocator.Add("state", locator.State)
+	}
+
+	if locator.User != "" {
+		buildsLocator.AddLocator("user", NewLocator().Add("username", locator.User))
+	}
+
+	for _, tag := range locator.Tags {
+		buildsLocator.Add("tag", tag)
+	}
+
+	if !locator.SinceDate.IsZero() {
+		buildsLocator.AddTime("sinceDate", locator.SinceDate)
+	}
+
+	if !locator.UntilDate.IsZero() {
+		buildsLocator.AddTime("untilDate", locator.UntilDate)
+	}
+
+	if locator.SinceBuildID != 0 {
+		buildsLocator.AddLocator("sinceBuild", NewLocator().AddInt("id", locator.SinceBuildID))
+	}
+
+	if locator.UntilBuildID != 0 {
+		buildsLocator.AddLocator("untilBuild", NewLocator().AddInt("id", locator.UntilBuildID))
+	}
+
+	if locator.Running != nil {
+		buildsLocator.AddBool("running", *locator.Running)
+	}
+
+	if locator.Canceled != nil {
+		buildsLocator.AddBool("canceled", *locator.Canceled)
+	}
+
+	if locator.Pinned != nil {
+		buildsLocator.AddBool("pinned", *locator.Pinned)
+	}
+
+	return buildsLocator.AddInt("count", pageSize)
+}
+
 // TriggerBuild triggers a build with parameters.
-func (bs *BuildService) TriggerBuild(buildTypeID, branchName string, params map[string]string) (types.TriggerBuildWithParametersResponse, error) {
+func (bs *BuildService) TriggerBuild(buildTypeID, branchName string, buildProperties map[string]string) (types.TriggerBuildWithParametersResponse, error) {
 	// Build the request payload with supplied parameters
 	properties := []map[string]string{}
-	for name, value := range params {
+	for name, value := range buildProperties {
 		properties = append(properties, map[string]string{"name": name, "value": value})
 	}
 
@@ -106,10 +325,86 @@ func (bs *BuildService) TriggerBuild(buildTypeID, branchName string, params map[
 	return triggerBuildResponse, nil
 }
 
+// CancelBuild cancels a queued or running build, returning false if it already finished.
+func (bs *BuildService) CancelBuild(buildID int, comment string) (bool, error) {
+	status, err := bs.GetBuildStatus(buildID)
+	if err != nil {
+		return false, err
+	}
+
+	// queued builds are removed from the queue, running builds are stopped
+	var cancelURL string
+
+	switch status.State {
+	case types.BuildStateQueued:
+		cancelURL = "app/rest/buildQueue/" + NewLocator().AddInt("id", buildID).PathSegment()
+	case types.BuildStateRunning:
+		cancelURL = "app/rest/builds/" + NewLocator().AddInt("id", buildID).PathSegment()
+	default:
+		return false, nil
+	}
+
+	data := map[string]interface{}{
+		"comment":        comment,
+		"readdIntoQueue": false,
+	}
+
+	req, err := bs.client.NewRequestWrapper("POST", cancelURL, data)
+	if err != nil {
+		return false, fmt.Errorf("error creating request: %w", err)
+	}
+
+	resp, err := bs.client.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("error executing request to cancel build %d: %w", buildID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return false, fmt.Errorf("failed to cancel build %d, status code: %d, response: %s", buildID, resp.StatusCode, string(bodyBytes))
+	}
+
+	return true, nil
+}
+
+// WaitOption configures how WaitForBuild waits for a build.
+type WaitOption func(options *waitOptions)
+
+type waitOptions struct {
+	ctx      context.Context
+	progress func(status types.BuildStatusResponse)
+}
+
+// WithContext stops waiting when ctx is done, e.g. when bbox is interrupted.
+func WithContext(ctx context.Context) WaitOption {
+	return func(options *waitOptions) {
+		options.ctx = ctx
+	}
+}
+
+// WithProgress calls progress with the status of the build every time it is checked.
+func WithProgress(progress func(status types.BuildStatusResponse)) WaitOption {
+	return func(options *waitOptions) {
+		options.progress = progress
+	}
+}
+
 // WaitForBuild waits for a build to finish.
-func (bs *BuildService) WaitForBuild(buildName string, buildNumber int, timeout time.Duration) (types.BuildStatusResponse, error) {
+func (bs *BuildService) WaitForBuild(buildName string, buildNumber int, timeout time.Duration, opts ...WaitOption) (types.BuildStatusResponse, error) {
 	var status types.BuildStatusResponse
 
+	options := &waitOptions{ctx: context.Background()}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	// progress is reported by the caller when requested, which may be rendering it
+	logProgress := log.Infof
+	if options.progress != nil {
+		logProgress = log.Debugf
+	}
+
 	baseDelay := 5 * time.Second // Initial delay of 5 seconds
 	maxDelay := 20 * time.Second // Maximum delay
 
@@ -118,7 +413,7 @@ func (bs *BuildService) WaitForBuild(buildName string, buildNumber int, timeout
 	var err error
 	var errBuildNotFinished = errors.New("build status is not finished")
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(options.ctx, timeout)
 	defer cancel()
 
 	err = retry.Do(
@@ -132,7 +427,22 @@ func (bs *BuildService) WaitForBuild(buildName string, buildNumber int, timeout
 
 			log.Debugf("%s state is: %s", buildName, status.State)
 
-			if status.State != "finished" {
+			if options.progress != nil {
+				options.progress(status)
+			}
+
+			if status.Composite {
+				finished := 0
+				for _, constituent := range status.SnapshotDependencies.Build {
+					if constituent.State.IsTerminal() {
+						finished++
+					}
+				}
+
+				logProgress("composite build %s: %d/%d constituent builds finished", buildName, finished, len(status.SnapshotDependencies.Build))
+			}
+
+			if !status.State.IsTerminal() {
 				return errBuildNotFinished
 			}
 
@@ -151,12 +461,17 @@ func (bs *BuildService) WaitForBuild(buildName string, buildNumber int, timeout
 				delay = maxDelay
 			}
 
-			log.Infof("build %s has not finished yet, rechecking in %d seconds", buildName, time.Duration(delay.Seconds()))
+			logProgress("build %s has not finished yet, rechecking in %d seconds", buildName, time.Duration(delay.Seconds()))
 
 			return delay
 		}),
 	)
 
+	// the build is still running when waiting is stopped, unlike when it times out
+	if ctxErr := options.ctx.Err(); ctxErr != nil {
+		return status, fmt.Errorf("stopped waiting for build %s: %w", buildName, ctxErr)
+	}
+
 	if err != nil && !errors.Is(err, errBuildNotFinished) {
 		return status, fmt.Errorf("error waiting for build %s: %w", buildName, err)
 	}
@@ -0,0 +1,367 @@
+package teamcity
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"bbox/pkg/cache"
+	"bbox/pkg/params"
+	"bbox/pkg/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	buildTypeFields              = "buildType(id,name,description,projectName,projectId,href,webUrl)"
+	buildTypeDependenciesFields  = "id,name,projectName,projectId,webUrl,snapshot-dependencies(count,snapshot-dependency(source-buildType(id,name,projectName,projectId,webUrl))),artifact-dependencies(count,artifact-dependency(source-buildType(id,name,projectName,projectId,webUrl)))"
+	buildTypeResolutionsCacheTTL = 24 * time.Hour
+)
+
+type BuildTypeService service
+
+// AmbiguousBuildTypeError is returned when a build type query matches more than one build type.
+type AmbiguousBuildTypeError struct {
+	Query      string
+	Candidates []types.BuildType
+}
+
+func (e *AmbiguousBuildTypeError) Error() string {
+	candidates := make([]string, 0, len(e.Candidates))
+	for _, candidate := range e.Candidates {
+		candidates = append(candidates, fmt.Sprintf("  - %s (%s)", candidate.FullName(), candidate.ID))
+	}
+
+	return fmt.Sprintf("build type %q is ambiguous, candidates:\n%s", e.Query, strings.Join(candidates, "\n"))
+}
+
+// GetBuildTypes returns all build types matching the given locator, or all build types if the locator is empty.
+func (bts *BuildTypeService) GetBuildTypes(locator *Locator) ([]types.BuildType, error) {
+	getURL := withQuery("app/rest/buildTypes", locator, buildTypeFields)
+
+	req, err := bts.client.NewRequestWrapper("GET", getURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	response, err := bts.client.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error executing request to get build types: %w", err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			log.Errorf("error closing response body: %s", err)
+		}
+	}(response.Body)
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get build types, status code: %d", response.StatusCode)
+	}
+
+	var buildTypesResponse types.BuildTypesResponse
+	err = json.NewDecoder(response.Body).Decode(&buildTypesResponse)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding response body: %w", err)
+	}
+
+	return buildTypesResponse.BuildTypes, nil
+}
+
+// GetBranches returns all the branches known to a build type.
+func (bts *BuildTypeService) GetBranches(buildTypeID string) ([]types.Branch, error) {
+	getURL := withQuery("app/rest/buildTypes/"+IDLocator(buildTypeID).PathSegment()+"/branches", NewLocator().Add("policy", "ALL_BRANCHES"), "")
+
+	req, err := bts.client.NewRequestWrapper("GET", getURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	response, err := bts.client.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error executing request to get branches: %w", err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			log.Errorf("error closing response body: %s", err)
+		}
+	}(response.Body)
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get branches of %s, status code: %d", buildTypeID, response.StatusCode)
+	}
+
+	var branchesResponse types.BranchesResponse
+	err = json.NewDecoder(response.Body).Decode(&branchesResponse)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding response body: %w", err)
+	}
+
+	return branchesResponse.Branches, nil
+}
+
+// GetBuildTypeDependencies returns a build type with the build types it has snapshot and artifact dependencies on.
+func (bts *BuildTypeService) GetBuildTypeDependencies(buildTypeID string) (types.BuildType, error) {
+	getURL := withQuery("app/rest/buildTypes/"+IDLocator(buildTypeID).PathSegment(), nil, buildTypeDependenciesFields)
+
+	req, err := bts.client.NewRequestWrapper("GET", getURL, nil)
+	if err != nil {
+		return types.BuildType{}, fmt.Errorf("error creating request: %w", err)
+	}
+
+	response, err := bts.client.client.Do(req)
+	if err != nil {
+		return types.BuildType{}, fmt.Errorf("error executing request to get dependencies: %w", err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			log.Errorf("error closing response body: %s", err)
+		}
+	}(response.Body)
+
+	if response.StatusCode != http.StatusOK {
+		return types.BuildType{}, fmt.Errorf("failed to get dependencies of %s, status code: %d", buildTypeID, response.StatusCode)
+	}
+
+	var buildType types.BuildType
+	err = json.NewDecoder(response.Body).Decode(&buildType)
+	if err != nil {
+		return types.BuildType{}, fmt.Errorf("error decoding response body: %w", err)
+	}
+
+	return buildType, nil
+}
+
+// GetParameters returns the parameters of a build type, including their type specs.
+func (bts *BuildTypeService) GetParameters(buildTypeID string) ([]types.Parameter, error) {
+	getURL := withQuery("app/rest/buildTypes/"+IDLocator(buildTypeID).PathSegment()+"/parameters", nil, "property(name,value,inherited,type(rawValue))")
+
+	req, err := bts.client.NewRequestWrapper("GET", getURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	response, err := bts.client.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error executing request to get parameters: %w", err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			log.Errorf("error closing response body: %s", err)
+		}
+	}(response.Body)
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get parameters of %s, status code: %d", buildTypeID, response.StatusCode)
+	}
+
+	var parametersResponse types.ParametersResponse
+	err = json.NewDecoder(response.Body).Decode(&parametersResponse)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding response body: %w", err)
+	}
+
+	return parametersResponse.Parameters, nil
+}
+
+// ValidateProperties checks the properties against the parameters declared on the build type before triggering it.
+// Warnings, like unknown parameters, are logged and only fail the validation in strict mode.
+func (bts *BuildTypeService) ValidateProperties(buildTypeID string, properties map[string]string, strict bool) error {
+	parameters, err := bts.GetParameters(buildTypeID)
+	if err != nil {
+		if strict {
+			return fmt.Errorf("error getting parameters to validate properties: %w", err)
+		}
+
+		log.Warnf("skipping properties validation of %s: %s", buildTypeID, err)
+
+		return nil
+	}
+
+	violations := params.ValidateProperties(properties, parameters)
+
+	for _, violation := range violations {
+		if violation.Warning && !strict {
+			log.WithField("buildTypeID", buildTypeID).Warnf("property %s", violation)
+		}
+	}
+
+	err = params.CheckViolations(violations, strict)
+	if err != nil {
+		return fmt.Errorf("%s: %w", buildTypeID, err)
+	}
+
+	return nil
+}
+
+// FindBuildTypes returns the build types matching a query.
+// A query containing "/" is treated as a full path ("Project / Sub / Build name"), anything else as a fuzzy name query.
+func (bts *BuildTypeService) FindBuildTypes(query string) ([]types.BuildType, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, fmt.Errorf("build type query is empty")
+	}
+
+	if isBuildTypePath(query) {
+		segments := splitBuildTypePath(query)
+		if len(segments) == 0 {
+			return nil, fmt.Errorf("build type path %q has no segments", query)
+		}
+
+		candidates, err := bts.GetBuildTypes(NewLocator().Add("name", segments[len(segments)-1]))
+		if err != nil {
+			return nil, err
+		}
+
+		return matchBuildTypePath(segments, candidates), nil
+	}
+
+	candidates, err := bts.GetBuildTypes(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return matchBuildTypeQuery(query, candidates), nil
+}
+
+// ResolveBuildTypeID resolves a build type path or fuzzy name query to a single build type ID.
+// Resolutions are cached locally, so repeated queries do not hit TeamCity.
+func (bts *BuildTypeService) ResolveBuildTypeID(query string) (string, error) {
+	cacheKey := bts.client.baseURL.String() + "|" + query
+
+	resolutions, err := cache.LoadResolutions()
+	if err != nil {
+		log.Debugf("build type resolutions cache is unavailable: %s", err)
+	}
+
+	if resolutions != nil {
+		if buildTypeID, ok := resolutions.Get(cacheKey, buildTypeResolutionsCacheTTL); ok {
+			log.Debugf("resolved build type %q to %s from cache", query, buildTypeID)
+			return buildTypeID, nil
+		}
+	}
+
+	matches, err := bts.FindBuildTypes(query)
+	if err != nil {
+		return "", fmt.Errorf("error finding build type %q: %w", query, err)
+	}
+
+	buildType, err := selectBuildType(query, matches)
+	if err != nil {
+		return "", err
+	}
+
+	log.Debugf("resolved build type %q to %s", query, buildType.ID)
+
+	if resolutions != nil {
+		resolutions.Set(cacheKey, buildType.ID)
+		if err := resolutions.Save(); err != nil {
+			log.Debugf("error saving build type resolutions cache: %s", err)
+		}
+	}
+
+	return buildType.ID, nil
+}
+
+// selectBuildType picks the single build type matching the query, preferring an exact full path or name match.
+func selectBuildType(query string, matches []types.BuildType) (types.BuildType, error) {
+	switch len(matches) {
+	case 0:
+		return types.BuildType{}, fmt.Errorf("no build type matches %q", query)
+	case 1:
+		return matches[0], nil
+	}
+
+	var exact []types.BuildType
+	for _, match := range matches {
+		if strings.EqualFold(match.FullName(), query) || strings.EqualFold(match.Name, query) {
+			exact = append(exact, match)
+		}
+	}
+
+	if len(exact) == 1 {
+		return exact[0], nil
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].FullName() < matches[j].FullName()
+	})
+
+	return types.BuildType{}, &AmbiguousBuildTypeError{Query: query, Candidates: matches}
+}
+
+func isBuildTypePath(query string) bool {
+	return strings.Contains(query, "/")
+}
+
+// splitBuildTypePath splits "Project / Sub / Build name" into its trimmed segments.
+func splitBuildTypePath(path string) []string {
+	segments := []string{}
+	for _, segment := range strings.Split(path, "/") {
+		segment = strings.TrimSpace(segment)
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+
+	return segments
+}
+
+// matchBuildTypePath returns the candidates whose full path ends with the given path segments.
+func matchBuildTypePath(segments []string, candidates []types.BuildType) []types.BuildType {
+	matches := []types.BuildType{}
+
+	for _, candidate := range candidates {
+		candidateSegments := splitBuildTypePath(candidate.FullName())
+		if len(candidateSegments) < len(segments) {
+			continue
+		}
+
+		candidateSegments = candidateSegments[len(candidateSegments)-len(segments):]
+
+		matched := true
+		for i := range segments {
+			if !strings.EqualFold(segments[i], candidateSegments[i]) {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			matches = append(matches, candidate)
+		}
+	}
+
+	return matches
+}
+
+// matchBuildTypeQuery returns the candidates whose full path or ID contains every word of the query, ignoring case.
+func matchBuildTypeQuery(query string, candidates []types.BuildType) []types.BuildType {
+	words := strings.Fields(strings.ToLower(query))
+	matches := []types.BuildType{}
+
+	for _, candidate := range candidates {
+		haystack := strings.ToLower(candidate.FullName() + " " + candidate.ID)
+
+		matched := true
+		for _, word := range words {
+			if !strings.Contains(haystack, word) {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			matches = append(matches, candidate)
+		}
+	}
+
+	return matches
+}
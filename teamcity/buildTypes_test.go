@@ -0,0 +1,67 @@
+package teamcity
+
+import (
+	"errors"
+	"testing"
+
+	"bbox/pkg/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testBuildTypes = []types.BuildType{
+	{ID: "Backend_Api_Build", Name: "Build", ProjectName: "Backend / Api"},
+	{ID: "Backend_Api_Deploy", Name: "Deploy", ProjectName: "Backend / Api"},
+	{ID: "Backend_Worker_Build", Name: "Build", ProjectName: "Backend / Worker"},
+	{ID: "Frontend_Build", Name: "Build", ProjectName: "Frontend"},
+}
+
+func TestResolveBuildTypeMatches(t *testing.T) {
+	testCases := []struct {
+		name          string
+		query         string
+		expectedID    string
+		expectedError string
+		ambiguous     bool
+	}{
+		{name: "full path", query: "Backend / Api / Build", expectedID: "Backend_Api_Build"},
+		{name: "full path ignores case and spacing", query: "backend/api/ build", expectedID: "Backend_Api_Build"},
+		{name: "partial path", query: "Worker / Build", expectedID: "Backend_Worker_Build"},
+		{name: "fuzzy words", query: "api deploy", expectedID: "Backend_Api_Deploy"},
+		{name: "fuzzy by ID", query: "frontend_build", expectedID: "Frontend_Build"},
+		{name: "ambiguous path", query: "/ Build", ambiguous: true},
+		{name: "path must end with the build name", query: "Backend / Build", expectedError: "no build type matches"},
+		{name: "ambiguous fuzzy", query: "backend", ambiguous: true},
+		{name: "no match", query: "mobile", expectedError: "no build type matches"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var matches []types.BuildType
+			if isBuildTypePath(tc.query) {
+				matches = matchBuildTypePath(splitBuildTypePath(tc.query), testBuildTypes)
+			} else {
+				matches = matchBuildTypeQuery(tc.query, testBuildTypes)
+			}
+
+			buildType, err := selectBuildType(tc.query, matches)
+
+			switch {
+			case tc.ambiguous:
+				var ambiguousErr *AmbiguousBuildTypeError
+				require.True(t, errors.As(err, &ambiguousErr))
+				assert.Greater(t, len(ambiguousErr.Candidates), 1)
+			case tc.expectedError != "":
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedError)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, tc.expectedID, buildType.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package teamcity
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"bbox/pkg/types"
+)
+
+const changesFields = "count,nextHref,change(id,version,username,date,comment,webUrl,vcsRootInstance(id,vcs-root-id,name),files(file(file,relative-file,changeType)))"
+
+type ChangeService service
+
+// GetChanges returns all VCS changes matching the locator, following the pagination of the changes endpoint.
+func (cs *ChangeService) GetChanges(locator *Locator) ([]types.Change, error) {
+	nextURL := withQuery("app/rest/changes", locator, changesFields)
+	changes := []types.Change{}
+
+	for nextURL != "" {
+		req, err := cs.client.NewRequestWrapper("GET", nextURL, nil)
+		if err != nil {
+			return changes, fmt.Errorf("error creating request: %w", err)
+		}
+
+		resp, err := cs.client.client.Do(req)
+		if err != nil {
+			return changes, fmt.Errorf("error executing request to get changes: %w", err)
+		}
+
+		var changesResponse types.ChangesResponse
+
+		if resp.StatusCode == http.StatusOK {
+			err = json.NewDecoder(resp.Body).Decode(&changesResponse)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return changes, fmt.Errorf("failed to get changes, status code: %d", resp.StatusCode)
+		}
+
+		if err != nil {
+			return changes, fmt.Errorf("error decoding response body: %w", err)
+		}
+
+		changes = append(changes, changesResponse.Changes...)
+
+		// nextHref is relative to the server root, which may differ from the root of the base URL
+		nextURL = strings.TrimPrefix(changesResponse.NextHref, "/")
+	}
+
+	return changes, nil
+}
+
+// BuildChanges returns the VCS changes included in a build, newest first.
+// If sinceBuildID is set, the changes of all builds of the same Build Type and branch after it, up to the build, are returned, e.g. for the release notes between two releases.
+func BuildChanges(c *Client, buildID, sinceBuildID int) ([]types.Change, error) {
+	buildIDs := []int{buildID}
+
+	if sinceBuildID != 0 {
+		build, err := c.Build.GetBuildStatus(buildID)
+		if err != nil {
+			return nil, fmt.Errorf("error getting build %d: %w", buildID, err)
+		}
+
+		builds, err := c.Build.ListBuilds(types.BuildLocator{
+			BuildTypeID:  build.BuildTypeID,
+			Branch:       build.BranchName,
+			SinceBuildID: sinceBuildID,
+			UntilBuildID: buildID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error listing builds since build %d: %w", sinceBuildID, err)
+		}
+
+		for _, b := range builds {
+			if b.ID != buildID {
+				buildIDs = append(buildIDs, b.ID)
+			}
+		}
+	}
+
+	changes := []types.Change{}
+	seen := map[int]bool{}
+
+	for _, id := range buildIDs {
+		buildChanges, err := c.Change.GetChanges(NewLocator().AddLocator("build", NewLocator().AddInt("id", id)))
+		if err != nil {
+			return changes, fmt.Errorf("error getting changes of build %d: %w", id, err)
+		}
+
+		// skip changes already listed by a newer build
+		for _, change := range buildChanges {
+			if seen[change.ID] {
+				continue
+			}
+
+			seen[change.ID] = true
+			changes = append(changes, change)
+		}
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,52 @@
+package teamcity_test
+
+import (
+	"testing"
+
+	"bbox/pkg/types"
+	"bbox/pkg/utils/testutils"
+	"bbox/teamcity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func buildChangesLocator(buildID int) *teamcity.Locator {
+	return teamcity.NewLocator().AddLocator("build", teamcity.NewLocator().AddInt("id", buildID))
+}
+
+func TestBuildChanges(t *testing.T) {
+	mockBuildService := new(testutils.MockBuildService)
+	mockChangeService := new(testutils.MockChangeService)
+	client := &teamcity.Client{Build: mockBuildService, Change: mockChangeService}
+
+	mockChangeService.On("GetChanges", buildChangesLocator(30)).Return([]types.Change{{ID: 3, Version: "c3"}}, nil)
+
+	changes, err := teamcity.BuildChanges(client, 30, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []types.Change{{ID: 3, Version: "c3"}}, changes)
+
+	mockBuildService.AssertNotCalled(t, "ListBuilds")
+}
+
+func TestBuildChangesSinceBuild(t *testing.T) {
+	mockBuildService := new(testutils.MockBuildService)
+	mockChangeService := new(testutils.MockChangeService)
+	client := &teamcity.Client{Build: mockBuildService, Change: mockChangeService}
+
+	mockBuildService.On("GetBuildStatus", 30).Return(types.BuildStatusResponse{ID: 30, BuildTypeID: "Backend_Release", BranchName: "main"}, nil)
+	mockBuildService.On("ListBuilds", types.BuildLocator{
+		BuildTypeID:  "Backend_Release",
+		Branch:       "main",
+		SinceBuildID: 10,
+		UntilBuildID: 30,
+	}).Return([]types.Build{{ID: 30}, {ID: 20}}, nil)
+	mockChangeService.On("GetChanges", buildChangesLocator(30)).Return([]types.Change{{ID: 3, Version: "c3"}}, nil)
+	mockChangeService.On("GetChanges", buildChangesLocator(20)).Return([]types.Change{{ID: 2, Version: "c2"}, {ID: 3, Version: "c3"}}, nil)
+
+	changes, err := teamcity.BuildChanges(client, 30, 10)
+	require.NoError(t, err)
+
+	assert.Equal(t, []types.Change{{ID: 3, Version: "c3"}, {ID: 2, Version: "c2"}}, changes)
+	mockChangeService.AssertNumberOfCalls(t, "GetChanges", 2)
+}
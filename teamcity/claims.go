@@ -0,0 +1,60 @@
+package teamcity
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+)
+
+// ErrArtifactCollision is returned when the artifacts of two builds would be written to the same file.
+var ErrArtifactCollision = errors.New("artifact collision")
+
+// ArtifactClaims records the files the artifacts of concurrent builds are downloaded to, so a build never silently overwrites the artifacts of another.
+// It is safe for concurrent use.
+type ArtifactClaims struct {
+	mu sync.Mutex
+	// owners are the builds the files were claimed by, by absolute path
+	owners map[string]int
+}
+
+// NewArtifactClaims creates an empty ArtifactClaims.
+func NewArtifactClaims() *ArtifactClaims {
+	return &ArtifactClaims{owners: map[string]int{}}
+}
+
+// Claim records that the artifacts of a build are written to the slash separated paths relative to destPath.
+// It fails with ErrArtifactCollision if any of them was claimed by another build, in which case none of them is claimed.
+func (c *ArtifactClaims) Claim(buildID int, destPath string, paths []string) error {
+	absDestPath, err := filepath.Abs(destPath)
+	if err != nil {
+		return fmt.Errorf("error resolving %s: %w", destPath, err)
+	}
+
+	files := make([]string, 0, len(paths))
+	for _, path := range paths {
+		files = append(files, filepath.Join(absDestPath, filepath.FromSlash(path)))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var collisions []error
+
+	for i, file := range files {
+		owner, claimed := c.owners[file]
+		if claimed && owner != buildID {
+			collisions = append(collisions, fmt.Errorf("%w: %s of build %d is also downloaded by build %d", ErrArtifactCollision, filepath.Join(destPath, filepath.FromSlash(paths[i])), buildID, owner))
+		}
+	}
+
+	if len(collisions) > 0 {
+		return errors.Join(collisions...)
+	}
+
+	for _, file := range files {
+		c.owners[file] = buildID
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package teamcity
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"bbox/pkg/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ConstituentArtifactsPath returns the sub-directory of artifactsPath the artifacts of a constituent build are downloaded to.
+func ConstituentArtifactsPath(artifactsPath string, constituent types.BuildStatusResponse) string {
+	return filepath.Join(artifactsPath, constituent.BuildTypeID)
+}
+
+// ConstituentResults returns the results of the constituent builds of a finished composite build.
+// If downloadArtifacts is set, the artifacts of every successful constituent build selected by the options are downloaded to its own sub-directory of artifactsPath.
+// A constituent build none of whose artifacts match the filter is reported without downloaded artifacts, whether the options require a match or not.
+func ConstituentResults(c *Client, buildID int, artifactsPath string, downloadArtifacts bool, opts ArtifactOptions) ([]types.BuildResult, error) {
+	constituents, err := c.Build.GetConstituentBuilds(buildID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting constituent builds: %w", err)
+	}
+
+	results := make([]types.BuildResult, 0, len(constituents))
+	var downloadErrors []error
+
+	// artifacts are required from any of the constituent builds, not from each of them
+	opts.RequireMatch = true
+
+	for _, constituent := range constituents {
+		result := types.BuildResult{
+			BuildName:   constituent.BuildType.Name,
+			WebURL:      constituent.WebURL,
+			BranchName:  constituent.BranchName,
+			BuildStatus: constituent.Result(),
+			Duration:    constituent.Duration(),
+		}
+
+		if result.BuildName == "" {
+			result.BuildName = constituent.BuildTypeID
+		}
+
+		if downloadArtifacts && result.BuildStatus.IsSuccessful() && c.Artifacts.BuildHasArtifact(constituent.ID) {
+			path := ConstituentArtifactsPath(artifactsPath, constituent)
+			log.Infof("downloading Artifacts for %s to %s", result.BuildName, path)
+
+			err = DownloadBuildArtifacts(c, constituent.ID, constituent.BuildTypeID, path, opts)
+			if errors.Is(err, ErrNoArtifactsMatch) {
+				log.Warnf("%s: %s", result.BuildName, err)
+				results = append(results, result)
+				continue
+			}
+
+			if err != nil {
+				result.Error = fmt.Errorf("error downloading artifacts: %w", err)
+				downloadErrors = append(downloadErrors, fmt.Errorf("%s: %w", result.BuildName, result.Error))
+			}
+
+			result.DownloadedArtifacts = err == nil
+		}
+
+		results = append(results, result)
+	}
+
+	return results, errors.Join(downloadErrors...)
+}
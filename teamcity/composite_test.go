@@ -0,0 +1,128 @@
+package teamcity_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"bbox/pkg/types"
+	"bbox/pkg/utils/testutils"
+	"bbox/teamcity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConstituentResults(t *testing.T) {
+	mockBuildService := new(testutils.MockBuildService)
+	mockArtifactsService := new(testutils.MockArtifactsService)
+	client := &teamcity.Client{
+		Build:     mockBuildService,
+		Artifacts: mockArtifactsService,
+	}
+
+	api := types.BuildStatusResponse{
+		ID:          11,
+		BuildTypeID: "Backend_Api",
+		BuildType:   types.BuildType{Name: "Api"},
+		Status:      types.BuildStatusSuccess,
+		State:       types.BuildStateFinished,
+		WebURL:      "https://teamcity/build/11",
+		StartDate:   "20240501T080000+0000",
+		FinishDate:  "20240501T081530+0000",
+	}
+	worker := types.BuildStatusResponse{
+		ID:          12,
+		BuildTypeID: "Backend_Worker",
+		Status:      types.BuildStatusFailure,
+		State:       types.BuildStateFinished,
+	}
+	web := types.BuildStatusResponse{
+		ID:           13,
+		BuildTypeID:  "Frontend_Web",
+		BuildType:    types.BuildType{Name: "Web"},
+		Status:       types.BuildStatusSuccess,
+		State:        types.BuildStateFinished,
+		CanceledInfo: &types.CanceledInfo{},
+	}
+
+	artifactsPath := t.TempDir()
+
+	mockBuildService.On("GetConstituentBuilds", 10).Return([]types.BuildStatusResponse{api, worker, web}, nil)
+	mockArtifactsService.On("GetArtifactChildren", 11).Return(types.ArtifactChildren{Count: 1}, nil)
+	mockArtifactsService.On("BuildHasArtifact", 11).Return(true)
+	mockArtifactsService.On("GetAllBuildTypeArtifacts", 11, "Backend_Api").Return([]byte("zip"), nil)
+	mockArtifactsService.On("ListArtifacts", 11).Return([]types.ArtifactFile{{Name: "api.jar", FullName: "api.jar", Size: 3}}, nil)
+	mockArtifactsService.On("DownloadAndUnzipArtifacts", 11, "Backend_Api", filepath.Join(artifactsPath, "Backend_Api")).Return(nil, nil)
+
+	results, err := teamcity.ConstituentResults(client, 10, artifactsPath, true, teamcity.ArtifactOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, []types.BuildResult{
+		{
+			BuildName:           "Api",
+			WebURL:              "https://teamcity/build/11",
+			BuildStatus:         types.BuildStatusSuccess,
+			Duration:            15*time.Minute + 30*time.Second,
+			DownloadedArtifacts: true,
+		},
+		{BuildName: "Backend_Worker", BuildStatus: types.BuildStatusFailure},
+		{BuildName: "Web", BuildStatus: types.BuildStatusCanceled},
+	}, results)
+
+	mockBuildService.AssertExpectations(t)
+	mockArtifactsService.AssertExpectations(t)
+}
+
+func TestConstituentResultsDownloadError(t *testing.T) {
+	mockBuildService := new(testutils.MockBuildService)
+	mockArtifactsService := new(testutils.MockArtifactsService)
+	client := &teamcity.Client{
+		Build:     mockBuildService,
+		Artifacts: mockArtifactsService,
+	}
+
+	constituent := types.BuildStatusResponse{ID: 11, BuildTypeID: "Backend_Api", Status: types.BuildStatusSuccess, State: types.BuildStateFinished}
+
+	mockBuildService.On("GetConstituentBuilds", 10).Return([]types.BuildStatusResponse{constituent}, nil)
+	mockArtifactsService.On("GetArtifactChildren", 11).Return(types.ArtifactChildren{Count: 1}, nil)
+	mockArtifactsService.On("BuildHasArtifact", 11).Return(true)
+	mockArtifactsService.On("GetAllBuildTypeArtifacts", 11, "Backend_Api").Return([]byte("zip"), nil)
+	mockArtifactsService.On("ListArtifacts", 11).Return([]types.ArtifactFile{{Name: "api.jar", FullName: "api.jar", Size: 3}}, nil)
+	mockArtifactsService.On("DownloadAndUnzipArtifacts", 11, "Backend_Api", filepath.Join("out", "Backend_Api")).Return(nil, errors.New("disk full"))
+
+	results, err := teamcity.ConstituentResults(client, 10, "out", true, teamcity.ArtifactOptions{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Backend_Api: error downloading artifacts: disk full")
+
+	require.Len(t, results, 1)
+	assert.False(t, results[0].DownloadedArtifacts)
+	assert.Error(t, results[0].Error)
+}
+
+func TestConstituentResultsNoMatch(t *testing.T) {
+	mockBuildService := new(testutils.MockBuildService)
+	mockArtifactsService := new(testutils.MockArtifactsService)
+	client := &teamcity.Client{
+		Build:     mockBuildService,
+		Artifacts: mockArtifactsService,
+	}
+
+	constituent := types.BuildStatusResponse{ID: 11, BuildTypeID: "Backend_Api", Status: types.BuildStatusSuccess, State: types.BuildStateFinished}
+
+	mockBuildService.On("GetConstituentBuilds", 10).Return([]types.BuildStatusResponse{constituent}, nil)
+	mockArtifactsService.On("GetArtifactChildren", 11).Return(types.ArtifactChildren{Count: 1}, nil)
+	mockArtifactsService.On("BuildHasArtifact", 11).Return(true)
+	mockArtifactsService.On("ListArtifacts", 11).Return([]types.ArtifactFile{{Name: "api.zip", FullName: "api.zip", Size: 3}}, nil)
+
+	filter, err := teamcity.NewArtifactFilter([]string{"**/*.jar"}, nil)
+	require.NoError(t, err)
+
+	results, err := teamcity.ConstituentResults(client, 10, t.TempDir(), true, teamcity.ArtifactOptions{Filter: filter})
+	require.NoError(t, err, "a constituent build without matching artifacts is not an error")
+
+	require.Len(t, results, 1)
+	assert.False(t, results[0].DownloadedArtifacts)
+	assert.NoError(t, results[0].Error)
+}
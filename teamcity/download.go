@@ -0,0 +1,337 @@
+package teamcity
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"bbox/pkg/utils"
+
+	"github.com/avast/retry-go/v4"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// downloadProgressInterval is how often the progress of a download is logged.
+	downloadProgressInterval = 5 * time.Second
+	// downloadRetryDelay is the delay before the first retry of a failed download, doubled on every further retry
+	downloadRetryDelay = time.Second
+	// downloadMaxRetryDelay is the maximum delay between retries of a failed download
+	downloadMaxRetryDelay = 30 * time.Second
+)
+
+// downloadStatusError is returned for downloads answered with an unexpected status code.
+type downloadStatusError struct {
+	name string
+	code int
+}
+
+func (e *downloadStatusError) Error() string {
+	return fmt.Sprintf("failed to download %s, status code: %d", e.name, e.code)
+}
+
+// isRetryableDownloadError returns false for downloads rejected by the server, which fail the same way when retried.
+func isRetryableDownloadError(err error) bool {
+	var statusErr *downloadStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.code >= http.StatusInternalServerError || statusErr.code == http.StatusRequestTimeout || statusErr.code == http.StatusTooManyRequests
+	}
+
+	return true
+}
+
+// stream copies the response to getURL to w as it is downloaded, logging the progress of the download.
+// The number of bytes copied is verified against the Content-Length of the response, if it has one.
+func (as *ArtifactsService) stream(getURL, name string, w io.Writer) (int64, error) {
+	req, err := as.client.NewRequestWrapper("GET", getURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error creating request: %w", err)
+	}
+
+	resp, err := as.client.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("error downloading %s: %w", name, err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			log.Errorf("error closing response body: %s", err)
+		}
+	}(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, &downloadStatusError{name: name, code: resp.StatusCode}
+	}
+
+	progress := newDownloadProgress(name, resp.ContentLength)
+
+	written, err := io.Copy(io.MultiWriter(w, progress), resp.Body)
+	if err != nil {
+		return written, fmt.Errorf("error downloading %s: %w", name, err)
+	}
+
+	if resp.ContentLength >= 0 && written != resp.ContentLength {
+		return written, fmt.Errorf("incomplete download of %s: got %d of %d bytes", name, written, resp.ContentLength)
+	}
+
+	progress.finish()
+
+	return written, nil
+}
+
+// downloadToFile streams the response to getURL to destFile, creating its directory.
+// The download is written to a temporary file next to destFile, which is renamed once complete, so a failed download does not leave a partial file behind.
+func (as *ArtifactsService) downloadToFile(getURL, name, destFile string) (int64, error) {
+	err := utils.CreateDir(destFile)
+	if err != nil {
+		return 0, fmt.Errorf("error creating dir: %w", err)
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(destFile), "."+filepath.Base(destFile)+".*.part")
+	if err != nil {
+		return 0, fmt.Errorf("error creating temporary file: %w", err)
+	}
+
+	written, err := as.stream(getURL, name, tmpFile)
+
+	// Close the file without defer to handle the error
+	closeErr := tmpFile.Close()
+	if err == nil && closeErr != nil {
+		err = fmt.Errorf("error writing %s: %w", name, closeErr)
+	}
+
+	if err == nil {
+		err = os.Rename(tmpFile.Name(), destFile)
+	}
+
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return written, err
+	}
+
+	return written, nil
+}
+
+// downloadToFileResumable streams the response to getURL to destFile like downloadToFile, retrying failed downloads up to retries times.
+// A retry resumes the partial download with an HTTP Range request, and starts over if the server does not support ranges.
+// The size of the download is verified against size, unless it is negative. Negative retries are not retried, like 0.
+func (as *ArtifactsService) downloadToFileResumable(getURL, name, destFile string, size int64, retries int) (int64, error) {
+	// retry-go retries forever with 0 attempts, which a negative retries would wrap around to
+	if retries < 0 {
+		retries = 0
+	}
+
+	err := utils.CreateDir(destFile)
+	if err != nil {
+		return 0, fmt.Errorf("error creating dir: %w", err)
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(destFile), "."+filepath.Base(destFile)+".*.part")
+	if err != nil {
+		return 0, fmt.Errorf("error creating temporary file: %w", err)
+	}
+
+	var written int64
+
+	err = retry.Do(
+		func() error {
+			written, err = as.streamRange(getURL, name, tmpFile, written)
+			if err != nil {
+				return err
+			}
+
+			if size >= 0 && written != size {
+				return fmt.Errorf("incomplete download of %s: got %d of %d bytes", name, written, size)
+			}
+
+			return nil
+		},
+		retry.Attempts(uint(retries)+1),
+		retry.Delay(downloadRetryDelay),
+		retry.MaxDelay(downloadMaxRetryDelay),
+		retry.DelayType(retry.BackOffDelay),
+		retry.LastErrorOnly(true),
+		retry.RetryIf(isRetryableDownloadError),
+		retry.OnRetry(func(n uint, err error) {
+			log.WithField("downloaded", utils.FormatBytes(written)).Warnf("retrying download of %s (%d/%d): %s", name, n+1, retries, err)
+		}),
+	)
+
+	// Close the file without defer to handle the error
+	closeErr := tmpFile.Close()
+	if err == nil && closeErr != nil {
+		err = fmt.Errorf("error writing %s: %w", name, closeErr)
+	}
+
+	if err == nil {
+		err = os.Rename(tmpFile.Name(), destFile)
+	}
+
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return written, err
+	}
+
+	return written, nil
+}
+
+// streamRange writes the response to getURL to f from offset on, requesting only the missing bytes if offset is positive.
+// It returns the number of bytes in f, which is truncated if the server sends the whole content instead of the range.
+func (as *ArtifactsService) streamRange(getURL, name string, f *os.File, offset int64) (int64, error) {
+	var opts []RequestOption
+	if offset > 0 {
+		opts = append(opts, func(req *http.Request) {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		})
+	}
+
+	req, err := as.client.NewRequestWrapper("GET", getURL, nil, opts...)
+	if err != nil {
+		return offset, fmt.Errorf("error creating request: %w", err)
+	}
+
+	resp, err := as.client.client.Do(req)
+	if err != nil {
+		return offset, fmt.Errorf("error downloading %s: %w", name, err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			log.Errorf("error closing response body: %s", err)
+		}
+	}(resp.Body)
+
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		start, ok := contentRangeStart(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			// start over rather than appending a range we did not ask for
+			return 0, fmt.Errorf("unexpected range %q resuming %s at %d", resp.Header.Get("Content-Range"), name, offset)
+		}
+
+		log.Debugf("resuming download of %s at %s", name, utils.FormatBytes(offset))
+	case resp.StatusCode == http.StatusOK:
+		if offset > 0 {
+			log.Debugf("server does not support resuming %s, downloading it again", name)
+		}
+
+		offset = 0
+	default:
+		return offset, &downloadStatusError{name: name, code: resp.StatusCode}
+	}
+
+	err = f.Truncate(offset)
+	if err == nil {
+		_, err = f.Seek(offset, io.SeekStart)
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("error writing %s: %w", name, err)
+	}
+
+	total := int64(-1)
+	if resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
+	}
+
+	progress := newDownloadProgress(name, total)
+	progress.resumeAt(offset)
+
+	written, err := io.Copy(io.MultiWriter(f, progress), resp.Body)
+	if err != nil {
+		return offset + written, fmt.Errorf("error downloading %s: %w", name, err)
+	}
+
+	if resp.ContentLength >= 0 && written != resp.ContentLength {
+		return offset + written, fmt.Errorf("incomplete download of %s: got %d of %d bytes", name, offset+written, total)
+	}
+
+	progress.finish()
+
+	return offset + written, nil
+}
+
+// contentRangeStart returns the first byte of a Content-Range header, e.g. 100 for "bytes 100-199/200".
+func contentRangeStart(contentRange string) (int64, bool) {
+	rest, ok := strings.CutPrefix(contentRange, "bytes ")
+	if !ok {
+		return 0, false
+	}
+
+	start, _, ok := strings.Cut(rest, "-")
+	if !ok {
+		return 0, false
+	}
+
+	n, err := strconv.ParseInt(start, 10, 64)
+
+	return n, err == nil
+}
+
+// downloadProgress is an io.Writer counting the bytes of a download, which periodically logs its progress and speed.
+type downloadProgress struct {
+	name    string
+	total   int64
+	written int64
+	// resumed is the number of bytes downloaded before a resumed download, which does not count towards its speed
+	resumed    int64
+	started    time.Time
+	lastLogged time.Time
+}
+
+func newDownloadProgress(name string, total int64) *downloadProgress {
+	now := time.Now()
+
+	return &downloadProgress{name: name, total: total, started: now, lastLogged: now}
+}
+
+// resumeAt counts the bytes downloaded before a resumed download.
+func (p *downloadProgress) resumeAt(offset int64) {
+	p.written = offset
+	p.resumed = offset
+}
+
+func (p *downloadProgress) Write(b []byte) (int, error) {
+	p.written += int64(len(b))
+
+	if time.Since(p.lastLogged) >= downloadProgressInterval {
+		p.lastLogged = time.Now()
+		p.log().Infof("downloading %s", p.name)
+	}
+
+	return len(b), nil
+}
+
+// finish logs the size and average speed of the completed download.
+func (p *downloadProgress) finish() {
+	p.log().Debugf("downloaded %s", p.name)
+}
+
+func (p *downloadProgress) log() *log.Entry {
+	fields := log.Fields{
+		"downloaded": utils.FormatBytes(p.written),
+		"speed":      utils.FormatBytes(p.bytesPerSecond()) + "/s",
+	}
+
+	if p.total > 0 {
+		fields["total"] = utils.FormatBytes(p.total)
+		fields["percentage"] = p.written * 100 / p.total
+	}
+
+	return log.WithFields(fields)
+}
+
+func (p *downloadProgress) bytesPerSecond() int64 {
+	elapsed := time.Since(p.started).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+
+	return int64(float64(p.written-p.resumed) / elapsed)
+}
@@ -0,0 +1,286 @@
+package teamcity
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"bbox/pkg/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestArtifactsService(t *testing.T, handler http.HandlerFunc) *ArtifactsService {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	baseURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	client, err := NewTeamCityClient(baseURL, "user", "password")
+	require.NoError(t, err)
+
+	return client.Artifacts.(*ArtifactsService)
+}
+
+func TestGetArtifactContentByPath(t *testing.T) {
+	t.Parallel()
+
+	as := newTestArtifactsService(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/app/rest/builds/id:1/artifacts/content/report.txt", r.URL.Path)
+		_, _ = w.Write([]byte("all green"))
+	})
+
+	content, err := as.GetArtifactContentByPath("app/rest/builds/id:1/artifacts/content/report.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "all green", string(content))
+}
+
+func TestDownloadArtifactContentByPath(t *testing.T) {
+	t.Parallel()
+
+	payload := bytes.Repeat([]byte("0123456789"), 100_000)
+
+	as := newTestArtifactsService(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(payload)))
+		_, _ = w.Write(payload)
+	})
+
+	destFile := filepath.Join(t.TempDir(), "dist", "app.bin")
+
+	size, err := as.DownloadArtifactContentByPath("app.bin", destFile)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(payload)), size)
+
+	content, err := os.ReadFile(destFile)
+	require.NoError(t, err)
+	assert.Equal(t, payload, content)
+
+	entries, err := os.ReadDir(filepath.Dir(destFile))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no temporary files are left behind")
+}
+
+func TestDownloadArtifactContentByPathIncomplete(t *testing.T) {
+	t.Parallel()
+
+	as := newTestArtifactsService(t, func(w http.ResponseWriter, r *http.Request) {
+		// the connection is closed before the announced length is sent
+		w.Header().Set("Content-Length", "1000")
+		_, _ = w.Write([]byte("truncated"))
+	})
+
+	destDir := t.TempDir()
+
+	_, err := as.DownloadArtifactContentByPath("app.bin", filepath.Join(destDir, "app.bin"))
+	require.Error(t, err)
+
+	entries, err := os.ReadDir(destDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "a failed download does not leave a partial file behind")
+}
+
+func TestDownloadArtifactContentByPathStatusCode(t *testing.T) {
+	t.Parallel()
+
+	as := newTestArtifactsService(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	_, err := as.DownloadArtifactContentByPath("missing.bin", filepath.Join(t.TempDir(), "missing.bin"))
+	assert.ErrorContains(t, err, "status code: 404")
+}
+
+func TestDownloadAndUnzipArtifacts(t *testing.T) {
+	t.Parallel()
+
+	var archive bytes.Buffer
+	zipWriter := zip.NewWriter(&archive)
+	file, err := zipWriter.Create("dist/app.txt")
+	require.NoError(t, err)
+	_, err = file.Write([]byte("app"))
+	require.NoError(t, err)
+	require.NoError(t, zipWriter.Close())
+
+	as := newTestArtifactsService(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/downloadArtifacts.html", r.URL.Path)
+		assert.Equal(t, "12", r.URL.Query().Get("buildId"))
+		_, _ = w.Write(archive.Bytes())
+	})
+
+	destPath := filepath.Join(t.TempDir(), "artifacts")
+
+	files, err := as.DownloadAndUnzipArtifacts(12, "Backend_Build", destPath)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dist/app.txt"}, files)
+
+	content, err := os.ReadFile(filepath.Join(destPath, "dist", "app.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "app", string(content))
+
+	entries, err := os.ReadDir(destPath)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "the artifacts zip is removed")
+}
+
+func TestDownloadAndUnzipArtifactsInvalidZip(t *testing.T) {
+	t.Parallel()
+
+	as := newTestArtifactsService(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("not a zip"))
+	})
+
+	destPath := t.TempDir()
+
+	_, err := as.DownloadAndUnzipArtifacts(12, "Backend_Build", destPath)
+	assert.ErrorContains(t, err, "error unzipping artifacts")
+
+	entries, err := os.ReadDir(destPath)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "the artifacts zip is removed")
+}
+
+func TestDownloadArtifactResume(t *testing.T) {
+	t.Parallel()
+
+	payload := bytes.Repeat([]byte("0123456789"), 10_000)
+	half := len(payload) / 2
+
+	testCases := []struct {
+		name string
+		// supportsRange is false for servers ignoring the Range header
+		supportsRange bool
+		wantRanges    []string
+	}{
+		{
+			name:          "resumes with a range request",
+			supportsRange: true,
+			wantRanges:    []string{"", "bytes=" + strconv.Itoa(half) + "-"},
+		},
+		{
+			name:          "starts over without range support",
+			supportsRange: false,
+			wantRanges:    []string{"", "bytes=" + strconv.Itoa(half) + "-"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var ranges []string
+
+			as := newTestArtifactsService(t, func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/app/rest/builds/id:1/artifacts/content/app.bin", r.URL.Path)
+				ranges = append(ranges, r.Header.Get("Range"))
+
+				if len(ranges) == 1 {
+					// the connection is closed halfway through the first download
+					w.Header().Set("Content-Length", strconv.Itoa(len(payload)))
+					_, _ = w.Write(payload[:half])
+					return
+				}
+
+				if tc.supportsRange {
+					w.Header().Set("Content-Range", "bytes "+strconv.Itoa(half)+"-"+strconv.Itoa(len(payload)-1)+"/"+strconv.Itoa(len(payload)))
+					w.WriteHeader(http.StatusPartialContent)
+					_, _ = w.Write(payload[half:])
+					return
+				}
+
+				_, _ = w.Write(payload)
+			})
+
+			file := types.ArtifactFile{FullName: "app.bin", Size: int64(len(payload))}
+			file.Content.Href = "/app/rest/builds/id:1/artifacts/content/app.bin"
+
+			destFile := filepath.Join(t.TempDir(), "app.bin")
+
+			err := as.DownloadArtifact(file, destFile, 2)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantRanges, ranges)
+
+			content, err := os.ReadFile(destFile)
+			require.NoError(t, err)
+			assert.Equal(t, payload, content)
+
+			entries, err := os.ReadDir(filepath.Dir(destFile))
+			require.NoError(t, err)
+			assert.Len(t, entries, 1, "no temporary files are left behind")
+		})
+	}
+}
+
+func TestDownloadArtifactRetries(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		status       int
+		retries      int
+		wantRequests int
+	}{
+		{name: "server errors are retried", status: http.StatusBadGateway, retries: 1, wantRequests: 2},
+		{name: "client errors are not retried", status: http.StatusNotFound, retries: 3, wantRequests: 1},
+		{name: "no retries", status: http.StatusServiceUnavailable, retries: 0, wantRequests: 1},
+		{name: "negative retries", status: http.StatusServiceUnavailable, retries: -1, wantRequests: 1},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			requests := 0
+
+			as := newTestArtifactsService(t, func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				w.WriteHeader(tc.status)
+			})
+
+			file := types.ArtifactFile{FullName: "app.bin", Size: 10}
+			file.Content.Href = "/app/rest/builds/id:1/artifacts/content/app.bin"
+
+			destDir := t.TempDir()
+
+			err := as.DownloadArtifact(file, filepath.Join(destDir, "app.bin"), tc.retries)
+			assert.ErrorContains(t, err, "status code: "+strconv.Itoa(tc.status))
+			assert.Equal(t, tc.wantRequests, requests)
+
+			entries, err := os.ReadDir(destDir)
+			require.NoError(t, err)
+			assert.Empty(t, entries, "a failed download does not leave a partial file behind")
+		})
+	}
+}
+
+func TestContentRangeStart(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		contentRange string
+		want         int64
+		wantOK       bool
+	}{
+		{contentRange: "bytes 100-199/200", want: 100, wantOK: true},
+		{contentRange: "bytes 0-9/*", want: 0, wantOK: true},
+		{contentRange: "bytes */200"},
+		{contentRange: ""},
+	}
+
+	for _, tc := range testCases {
+		got, ok := contentRangeStart(tc.contentRange)
+		assert.Equal(t, tc.wantOK, ok, tc.contentRange)
+		assert.Equal(t, tc.want, got, tc.contentRange)
+	}
+}
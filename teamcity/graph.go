@@ -0,0 +1,117 @@
+package teamcity
+
+import (
+	"fmt"
+	"strconv"
+
+	"bbox/pkg/types"
+)
+
+// BuildGraph returns the dependency graph of a build, walking its snapshot and artifact dependencies down to the builds they resolved to.
+func BuildGraph(c *Client, buildID int) (types.DependencyGraph, error) {
+	graph := types.DependencyGraph{Nodes: []types.GraphNode{}, Edges: []types.GraphEdge{}}
+	visited := map[int]bool{buildID: true}
+	pending := []int{buildID}
+
+	for len(pending) > 0 {
+		id := pending[0]
+		pending = pending[1:]
+
+		build, err := c.Build.GetBuildDependencies(id)
+		if err != nil {
+			return graph, fmt.Errorf("error getting dependencies of build %d: %w", id, err)
+		}
+
+		graph.Nodes = append(graph.Nodes, buildNode(build))
+
+		for _, dependencies := range []struct {
+			kind string
+			refs types.Dependencies
+		}{
+			{types.DependencySnapshot, build.SnapshotDependencies},
+			{types.DependencyArtifact, build.ArtifactDependencies},
+		} {
+			for _, dependency := range dependencies.refs.Build {
+				graph.Edges = append(graph.Edges, types.GraphEdge{
+					From: strconv.Itoa(id),
+					To:   strconv.Itoa(dependency.ID),
+					Type: dependencies.kind,
+				})
+
+				if !visited[dependency.ID] {
+					visited[dependency.ID] = true
+					pending = append(pending, dependency.ID)
+				}
+			}
+		}
+	}
+
+	return graph, nil
+}
+
+// BuildTypeGraph returns the dependency graph of a build type, walking the build types it has snapshot and artifact dependencies on.
+func BuildTypeGraph(c *Client, buildTypeID string) (types.DependencyGraph, error) {
+	graph := types.DependencyGraph{Nodes: []types.GraphNode{}, Edges: []types.GraphEdge{}}
+	visited := map[string]bool{buildTypeID: true}
+	pending := []string{buildTypeID}
+
+	for len(pending) > 0 {
+		id := pending[0]
+		pending = pending[1:]
+
+		buildType, err := c.BuildType.GetBuildTypeDependencies(id)
+		if err != nil {
+			return graph, fmt.Errorf("error getting dependencies of %s: %w", id, err)
+		}
+
+		graph.Nodes = append(graph.Nodes, types.GraphNode{
+			ID:          buildType.ID,
+			Label:       buildType.FullName(),
+			BuildTypeID: buildType.ID,
+			WebURL:      buildType.WebURL,
+		})
+
+		for _, dependencies := range []struct {
+			kind    string
+			sources []types.BuildType
+		}{
+			{types.DependencySnapshot, buildType.SnapshotDependencies.SourceBuildTypes()},
+			{types.DependencyArtifact, buildType.ArtifactDependencies.SourceBuildTypes()},
+		} {
+			for _, sourceBuildType := range dependencies.sources {
+				source := sourceBuildType.ID
+
+				graph.Edges = append(graph.Edges, types.GraphEdge{From: id, To: source, Type: dependencies.kind})
+
+				if !visited[source] {
+					visited[source] = true
+					pending = append(pending, source)
+				}
+			}
+		}
+	}
+
+	return graph, nil
+}
+
+func buildNode(build types.BuildStatusResponse) types.GraphNode {
+	label := build.BuildType.FullName()
+	if label == "" {
+		label = build.BuildTypeID
+	}
+
+	if build.Number != "" {
+		label += " #" + build.Number
+	}
+
+	return types.GraphNode{
+		ID:          strconv.Itoa(build.ID),
+		Label:       label,
+		BuildTypeID: build.BuildTypeID,
+		BuildID:     build.ID,
+		Number:      build.Number,
+		Status:      build.Result(),
+		State:       build.State,
+		WebURL:      build.WebURL,
+	}
+}
@@ -0,0 +1,116 @@
+package teamcity_test
+
+import (
+	"testing"
+
+	"bbox/pkg/types"
+	"bbox/pkg/utils/testutils"
+	"bbox/teamcity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func buildDependencies(id int, buildTypeID string, status types.BuildStatus, snapshot, artifact []int) types.BuildStatusResponse {
+	build := types.BuildStatusResponse{
+		ID:          id,
+		BuildTypeID: buildTypeID,
+		Number:      "7",
+		Status:      status,
+		State:       types.BuildStateFinished,
+	}
+
+	for _, dependencyID := range snapshot {
+		build.SnapshotDependencies.Build = append(build.SnapshotDependencies.Build, types.DependencyBuild{ID: dependencyID})
+	}
+
+	for _, dependencyID := range artifact {
+		build.ArtifactDependencies.Build = append(build.ArtifactDependencies.Build, types.DependencyBuild{ID: dependencyID})
+	}
+
+	return build
+}
+
+func buildTypeDependencies(id string, snapshot, artifact []string) types.BuildType {
+	buildType := types.BuildType{ID: id, Name: id, ProjectName: "Shop"}
+
+	if len(snapshot) > 0 {
+		buildType.SnapshotDependencies = &types.BuildTypeDependencies{Count: len(snapshot)}
+		for _, dependencyID := range snapshot {
+			buildType.SnapshotDependencies.SnapshotDependency = append(buildType.SnapshotDependencies.SnapshotDependency, types.BuildTypeDependency{SourceBuildType: types.BuildType{ID: dependencyID}})
+		}
+	}
+
+	if len(artifact) > 0 {
+		buildType.ArtifactDependencies = &types.BuildTypeDependencies{Count: len(artifact)}
+		for _, dependencyID := range artifact {
+			buildType.ArtifactDependencies.ArtifactDependency = append(buildType.ArtifactDependencies.ArtifactDependency, types.BuildTypeDependency{SourceBuildType: types.BuildType{ID: dependencyID}})
+		}
+	}
+
+	return buildType
+}
+
+func TestBuildGraph(t *testing.T) {
+	mockBuildService := new(testutils.MockBuildService)
+	client := &teamcity.Client{Build: mockBuildService}
+
+	// release depends on api and web, which both depend on the same lib build
+	mockBuildService.On("GetBuildDependencies", 1).Return(buildDependencies(1, "Release", types.BuildStatusSuccess, []int{2, 3}, []int{3}), nil)
+	mockBuildService.On("GetBuildDependencies", 2).Return(buildDependencies(2, "Api", types.BuildStatusFailure, []int{4}, nil), nil)
+	mockBuildService.On("GetBuildDependencies", 3).Return(buildDependencies(3, "Web", types.BuildStatusSuccess, []int{4}, nil), nil)
+	mockBuildService.On("GetBuildDependencies", 4).Return(buildDependencies(4, "Lib", types.BuildStatusSuccess, nil, nil), nil)
+
+	graph, err := teamcity.BuildGraph(client, 1)
+	require.NoError(t, err)
+
+	ids := make([]string, 0, len(graph.Nodes))
+	for _, node := range graph.Nodes {
+		ids = append(ids, node.ID)
+	}
+
+	assert.Equal(t, []string{"1", "2", "3", "4"}, ids)
+	assert.Equal(t, "Api #7", graph.Nodes[1].Label)
+	assert.Equal(t, types.BuildStatusFailure, graph.Nodes[1].Status)
+	assert.Equal(t, []types.GraphEdge{
+		{From: "1", To: "2", Type: types.DependencySnapshot},
+		{From: "1", To: "3", Type: types.DependencySnapshot},
+		{From: "1", To: "3", Type: types.DependencyArtifact},
+		{From: "2", To: "4", Type: types.DependencySnapshot},
+		{From: "3", To: "4", Type: types.DependencySnapshot},
+	}, graph.Edges)
+
+	// every build is fetched once, even when several builds depend on it
+	mockBuildService.AssertNumberOfCalls(t, "GetBuildDependencies", 4)
+}
+
+func TestBuildTypeGraph(t *testing.T) {
+	mockBuildTypeService := new(testutils.MockBuildTypeService)
+	client := &teamcity.Client{BuildType: mockBuildTypeService}
+
+	// Release depends on Api and Web, which both depend on Lib, and Release takes the artifacts of Web
+	mockBuildTypeService.On("GetBuildTypeDependencies", "Release").Return(buildTypeDependencies("Release", []string{"Api", "Web"}, []string{"Web"}), nil)
+	mockBuildTypeService.On("GetBuildTypeDependencies", "Api").Return(buildTypeDependencies("Api", []string{"Lib"}, nil), nil)
+	mockBuildTypeService.On("GetBuildTypeDependencies", "Web").Return(buildTypeDependencies("Web", []string{"Lib"}, nil), nil)
+	mockBuildTypeService.On("GetBuildTypeDependencies", "Lib").Return(buildTypeDependencies("Lib", nil, nil), nil)
+
+	graph, err := teamcity.BuildTypeGraph(client, "Release")
+	require.NoError(t, err)
+
+	assert.Equal(t, []types.GraphNode{
+		{ID: "Release", Label: "Shop / Release", BuildTypeID: "Release"},
+		{ID: "Api", Label: "Shop / Api", BuildTypeID: "Api"},
+		{ID: "Web", Label: "Shop / Web", BuildTypeID: "Web"},
+		{ID: "Lib", Label: "Shop / Lib", BuildTypeID: "Lib"},
+	}, graph.Nodes)
+	assert.Equal(t, []types.GraphEdge{
+		{From: "Release", To: "Api", Type: types.DependencySnapshot},
+		{From: "Release", To: "Web", Type: types.DependencySnapshot},
+		{From: "Release", To: "Web", Type: types.DependencyArtifact},
+		{From: "Api", To: "Lib", Type: types.DependencySnapshot},
+		{From: "Web", To: "Lib", Type: types.DependencySnapshot},
+	}, graph.Edges)
+
+	// every build type is fetched once, even when several build types depend on it
+	mockBuildTypeService.AssertNumberOfCalls(t, "GetBuildTypeDependencies", 4)
+}
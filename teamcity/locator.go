@@ -0,0 +1,119 @@
+package teamcity
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"bbox/pkg/types"
+)
+
+// locatorSpecialChars are the characters that end a locator value, so values containing them have to be escaped.
+const locatorSpecialChars = "(),:"
+
+// Locator builds a TeamCity REST API locator, e.g. buildType:(id:Backend_Build),branch:(name:main),count:10.
+// Values are escaped with $base64: when they contain characters that would break the locator.
+type Locator struct {
+	dimensions []locatorDimension
+}
+
+type locatorDimension struct {
+	name   string
+	value  string
+	nested *Locator
+}
+
+// NewLocator returns an empty locator.
+func NewLocator() *Locator {
+	return &Locator{}
+}
+
+// IDLocator returns the locator of a single entity by ID, e.g. id:Backend_Build.
+func IDLocator(id string) *Locator {
+	return NewLocator().Add("id", id)
+}
+
+// Add adds a dimension with a value, which is escaped if needed.
+func (l *Locator) Add(name, value string) *Locator {
+	l.dimensions = append(l.dimensions, locatorDimension{name: name, value: value})
+	return l
+}
+
+// AddInt adds a dimension with an integer value.
+func (l *Locator) AddInt(name string, value int) *Locator {
+	return l.Add(name, strconv.Itoa(value))
+}
+
+// AddBool adds a dimension with a boolean value.
+func (l *Locator) AddBool(name string, value bool) *Locator {
+	return l.Add(name, strconv.FormatBool(value))
+}
+
+// AddTime adds a dimension with a date value in the TeamCity date format.
+func (l *Locator) AddTime(name string, value time.Time) *Locator {
+	return l.Add(name, value.Format(types.TeamCityTimeLayout))
+}
+
+// AddLocator adds a dimension whose value is a nested locator, e.g. buildType:(id:Backend_Build).
+func (l *Locator) AddLocator(name string, nested *Locator) *Locator {
+	l.dimensions = append(l.dimensions, locatorDimension{name: name, nested: nested})
+	return l
+}
+
+// IsEmpty returns true if the locator has no dimensions.
+func (l *Locator) IsEmpty() bool {
+	return l == nil || len(l.dimensions) == 0
+}
+
+// String returns the locator in the TeamCity locator syntax.
+func (l *Locator) String() string {
+	if l.IsEmpty() {
+		return ""
+	}
+
+	dimensions := make([]string, 0, len(l.dimensions))
+	for _, dimension := range l.dimensions {
+		if dimension.nested != nil {
+			dimensions = append(dimensions, dimension.name+":("+dimension.nested.String()+")")
+			continue
+		}
+
+		dimensions = append(dimensions, dimension.name+":"+escapeLocatorValue(dimension.value))
+	}
+
+	return strings.Join(dimensions, ",")
+}
+
+// PathSegment returns the locator escaped to be used as a URL path segment, e.g. app/rest/builds/<locator>.
+func (l *Locator) PathSegment() string {
+	return url.PathEscape(l.String())
+}
+
+// escapeLocatorValue encodes values containing locator special characters, or starting with $, as $base64:<value>.
+func escapeLocatorValue(value string) string {
+	if !strings.ContainsAny(value, locatorSpecialChars) && !strings.HasPrefix(value, "$") {
+		return value
+	}
+
+	return "$base64:" + base64.RawURLEncoding.EncodeToString([]byte(value))
+}
+
+// withQuery appends the locator and the fields= projection to a REST API path, skipping the empty ones.
+func withQuery(path string, locator *Locator, fields string) string {
+	query := url.Values{}
+	if !locator.IsEmpty() {
+		query.Set("locator", locator.String())
+	}
+
+	if fields != "" {
+		query.Set("fields", fields)
+	}
+
+	if len(query) == 0 {
+		return path
+	}
+
+	return path + "?" + query.Encode()
+}
@@ -0,0 +1,110 @@
+package teamcity
+
+import (
+	"testing"
+	"time"
+
+	"bbox/pkg/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocator(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		locator  *Locator
+		expected string
+	}{
+		{
+			name:     "empty",
+			locator:  NewLocator(),
+			expected: "",
+		},
+		{
+			name:     "plain values",
+			locator:  IDLocator("Backend_Build").AddInt("count", 10).AddBool("running", false),
+			expected: "id:Backend_Build,count:10,running:false",
+		},
+		{
+			name:     "nested locators",
+			locator:  NewLocator().AddLocator("buildType", IDLocator("Backend_Build")).AddLocator("branch", NewLocator().Add("default", "any")),
+			expected: "buildType:(id:Backend_Build),branch:(default:any)",
+		},
+		{
+			name:     "pull request branch is kept as is",
+			locator:  NewLocator().AddLocator("branch", NewLocator().Add("name", "refs/pull/12/merge")),
+			expected: "branch:(name:refs/pull/12/merge)",
+		},
+		{
+			name:     "branch with a comma is base64 encoded",
+			locator:  NewLocator().AddLocator("branch", NewLocator().Add("name", "feature/a,b")),
+			expected: "branch:(name:$base64:ZmVhdHVyZS9hLGI)",
+		},
+		{
+			name:     "values with parentheses, colons and a leading $ are base64 encoded",
+			locator:  NewLocator().Add("name", "Build (nightly)").Add("tag", "release:1").Add("user", "$me"),
+			expected: "name:$base64:QnVpbGQgKG5pZ2h0bHkp,tag:$base64:cmVsZWFzZTox,user:$base64:JG1l",
+		},
+		{
+			name:     "dates use the TeamCity format",
+			locator:  NewLocator().AddTime("sinceDate", time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC)),
+			expected: "sinceDate:20240501T083000+0000",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, tc.locator.String())
+		})
+	}
+}
+
+func TestLocatorPathSegment(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "id:Backend_Build", IDLocator("Backend_Build").PathSegment())
+	assert.Equal(t, "id:$base64:YSxi", IDLocator("a,b").PathSegment())
+}
+
+func TestWithQuery(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "app/rest/buildTypes", withQuery("app/rest/buildTypes", nil, ""))
+	assert.Equal(t, "app/rest/buildTypes?fields=buildType%28id%29", withQuery("app/rest/buildTypes", NewLocator(), "buildType(id)"))
+	assert.Equal(t,
+		"app/rest/vcs-root-instances?locator=vcsRoot%3A%28id%3ARoot_1%29",
+		withQuery("app/rest/vcs-root-instances", NewLocator().AddLocator("vcsRoot", IDLocator("Root_1")), ""),
+	)
+}
+
+func TestBuildsLocator(t *testing.T) {
+	t.Parallel()
+
+	running := true
+
+	locator := buildsLocator(types.BuildLocator{
+		BuildTypeID:  "Backend_Build",
+		Branch:       "feature/a,b",
+		Revision:     "0a1b2c3",
+		Status:       "FAILURE",
+		User:         "jdoe",
+		Tags:         []string{"nightly", "release"},
+		SinceDate:    time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		SinceBuildID: 100,
+		Running:      &running,
+		Pinned:       &running,
+	}, 50)
+
+	assert.Equal(t,
+		"buildType:(id:Backend_Build),branch:(name:$base64:ZmVhdHVyZS9hLGI),revision:(version:0a1b2c3),status:FAILURE,user:(username:jdoe),"+
+			"tag:nightly,tag:release,sinceDate:20240501T000000+0000,sinceBuild:(id:100),running:true,pinned:true,count:50",
+		locator.String(),
+	)
+
+	assert.Equal(t, "branch:(default:any),count:100", buildsLocator(types.BuildLocator{}, 100).String())
+}
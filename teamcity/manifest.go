@@ -0,0 +1,231 @@
+package teamcity
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"bbox/pkg/types"
+	"bbox/pkg/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrChecksumMismatch is returned when a downloaded artifact does not match the checksum published by its build.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// ArtifactsManifestName returns the file name of the manifest of the artifacts downloaded from a build.
+func ArtifactsManifestName(buildID int) string {
+	return fmt.Sprintf("bbox-manifest-%d.json", buildID)
+}
+
+// ArtifactURL returns the URL an artifact of a build can be downloaded from, or an empty string if the client has no base URL.
+func (c *Client) ArtifactURL(buildTypeID string, buildID int, artifactPath string) string {
+	if c.baseURL == nil {
+		return ""
+	}
+
+	u := *c.baseURL
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/repository/download/" + buildTypeID + "/" + strconv.Itoa(buildID) + ":id/" + artifactPath
+	u.RawPath = ""
+
+	return u.String()
+}
+
+// ArtifactsArchiveURL returns the URL the archive of all artifacts of a build can be downloaded from, or an empty string if the client has no base URL.
+func (c *Client) ArtifactsArchiveURL(buildTypeID string, buildID int) string {
+	if c.baseURL == nil {
+		return ""
+	}
+
+	u := *c.baseURL
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/repository/downloadAll/" + buildTypeID + "/" + strconv.Itoa(buildID) + ":id"
+	u.RawPath = ""
+
+	return u.String()
+}
+
+// artifactURL returns the URL of a downloaded artifact, which is the archive of all artifacts if it has no artifact path.
+func artifactURL(c *Client, buildTypeID string, buildID int, artifactPath string) string {
+	if artifactPath == "" {
+		return c.ArtifactsArchiveURL(buildTypeID, buildID)
+	}
+
+	return c.ArtifactURL(buildTypeID, buildID, artifactPath)
+}
+
+// WriteArtifactsManifest hashes the files downloaded from a build to destPath, and records them in a manifest written next to them.
+// Nothing is written if no files were downloaded.
+func WriteArtifactsManifest(c *Client, buildID int, buildTypeID, destPath string, downloaded []downloadedArtifact) (types.ArtifactsManifest, error) {
+	manifest := types.ArtifactsManifest{
+		BuildID:      buildID,
+		BuildTypeID:  buildTypeID,
+		DownloadedAt: time.Now().UTC(),
+		Files:        make([]types.ManifestFile, 0, len(downloaded)),
+	}
+
+	if len(downloaded) == 0 {
+		return manifest, nil
+	}
+
+	for _, file := range downloaded {
+		sum, size, err := utils.FileSHA256(filepath.Join(destPath, filepath.FromSlash(file.path)))
+		if err != nil {
+			return manifest, fmt.Errorf("error hashing %s: %w", file.path, err)
+		}
+
+		manifest.Files = append(manifest.Files, types.ManifestFile{
+			Path:         file.path,
+			ArtifactPath: file.artifactPath,
+			Size:         size,
+			SHA256:       sum,
+			URL:          artifactURL(c, buildTypeID, buildID, file.artifactPath),
+		})
+	}
+
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest, fmt.Errorf("error encoding artifacts manifest: %w", err)
+	}
+
+	manifestFile := filepath.Join(destPath, ArtifactsManifestName(buildID))
+
+	err = utils.WriteFileAtomic(manifestFile, bytes.NewReader(append(content, '\n')), 0o644)
+	if err != nil {
+		return manifest, fmt.Errorf("error writing artifacts manifest: %w", err)
+	}
+
+	log.Debugf("wrote manifest of %d artifacts to %s", len(manifest.Files), manifestFile)
+
+	return manifest, nil
+}
+
+// VerifyChecksums verifies the files of a manifest against a checksum file in the artifacts of the build, in the format of sha256sum.
+// Files not listed in the checksum file are skipped with a warning. Mismatching files, and listed files selected by the filter
+// that were not downloaded, fail with ErrChecksumMismatch.
+func VerifyChecksums(c *Client, buildID int, checksumFile string, filter ArtifactFilter, manifest types.ArtifactsManifest) error {
+	getURL := "app/rest/builds/" + NewLocator().AddInt("id", buildID).PathSegment() + "/artifacts/content/" + escapeArtifactPath(checksumFile)
+
+	content, err := c.Artifacts.GetArtifactContentByPath(getURL)
+	if err != nil {
+		return fmt.Errorf("error getting checksum file %s: %w", checksumFile, err)
+	}
+
+	checksums, err := ParseChecksums(content, path.Dir(checksumFile))
+	if err != nil {
+		return fmt.Errorf("error parsing checksum file %s: %w", checksumFile, err)
+	}
+
+	verified := 0
+	var unlisted []string
+	var errs []error
+	downloaded := make(map[string]bool, len(manifest.Files))
+
+	for _, file := range manifest.Files {
+		downloaded[file.ArtifactPath] = true
+
+		if file.ArtifactPath == checksumFile {
+			continue
+		}
+
+		expected, ok := checksums[file.ArtifactPath]
+		if !ok {
+			unlisted = append(unlisted, file.ArtifactPath)
+			continue
+		}
+
+		if !strings.EqualFold(expected, file.SHA256) {
+			errs = append(errs, fmt.Errorf("%w: %s is %s, expected %s", ErrChecksumMismatch, file.Path, file.SHA256, expected))
+			continue
+		}
+
+		verified++
+	}
+
+	var missing []string
+	for artifactPath := range checksums {
+		if downloaded[artifactPath] {
+			continue
+		}
+
+		if _, selected := filter.Destination(artifactPath); selected || filter.IsEmpty() {
+			missing = append(missing, artifactPath)
+		}
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		errs = append(errs, fmt.Errorf("%w: %d artifacts listed in %s were not downloaded: %s", ErrChecksumMismatch, len(missing), checksumFile, strings.Join(missing, ", ")))
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	if verified == 0 {
+		return fmt.Errorf("none of the %d downloaded artifacts are listed in %s", len(manifest.Files), checksumFile)
+	}
+
+	if len(unlisted) > 0 {
+		log.Warnf("%d downloaded artifacts are not listed in %s: %s", len(unlisted), checksumFile, strings.Join(unlisted, ", "))
+	}
+
+	log.Infof("verified the checksums of %d artifacts against %s", verified, checksumFile)
+
+	return nil
+}
+
+// ParseChecksums parses a checksum file in the format of sha256sum, "<hex digest>  <file>" or "<hex digest> *<file>" per line,
+// and returns the digests by artifact path. File names are relative to dir, the directory of the checksum file in the artifacts.
+func ParseChecksums(content []byte, dir string) (map[string]string, error) {
+	checksums := map[string]string{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	line := 0
+
+	for scanner.Scan() {
+		line++
+
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		sum, name, ok := strings.Cut(text, " ")
+		name = strings.TrimPrefix(strings.TrimPrefix(name, " "), "*")
+
+		if !ok || name == "" || !isSHA256(sum) {
+			return nil, fmt.Errorf("invalid checksum on line %d: %q", line, text)
+		}
+
+		checksums[path.Join(dir, strings.TrimPrefix(name, "./"))] = strings.ToLower(sum)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return checksums, nil
+}
+
+func isSHA256(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+
+	return true
+}
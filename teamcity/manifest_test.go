@@ -0,0 +1,83 @@
+package teamcity_test
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"bbox/teamcity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChecksums(t *testing.T) {
+	sum := strings.Repeat("ab", 32)
+	upper := strings.Repeat("CD", 32)
+
+	testCases := []struct {
+		name    string
+		content string
+		dir     string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "text and binary mode",
+			content: sum + "  app.jar\n" + upper + " *lib/core.jar\n",
+			dir:     ".",
+			want:    map[string]string{"app.jar": sum, "lib/core.jar": strings.ToLower(upper)},
+		},
+		{
+			name:    "relative to the checksum file",
+			content: "# release checksums\n\n" + sum + "  ./app.jar\n",
+			dir:     "dist",
+			want:    map[string]string{"dist/app.jar": sum},
+		},
+		{
+			name:    "file name with spaces",
+			content: sum + "  my app.jar\n",
+			dir:     ".",
+			want:    map[string]string{"my app.jar": sum},
+		},
+		{
+			name:    "invalid digest",
+			content: "abc  app.jar\n",
+			dir:     ".",
+			wantErr: true,
+		},
+		{
+			name:    "missing file name",
+			content: sum + "\n",
+			dir:     ".",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := teamcity.ParseChecksums([]byte(tc.content), tc.dir)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestArtifactURL(t *testing.T) {
+	baseURL, err := url.Parse("https://teamcity.example.com/tc")
+	require.NoError(t, err)
+
+	client, err := teamcity.NewTeamCityClient(baseURL, "user", "pass")
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://teamcity.example.com/tc/repository/download/Backend_Build/10:id/dist/my%20app.jar", client.ArtifactURL("Backend_Build", 10, "dist/my app.jar"))
+	assert.Empty(t, (&teamcity.Client{}).ArtifactURL("Backend_Build", 10, "app.jar"))
+}
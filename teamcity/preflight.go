@@ -0,0 +1,60 @@
+package teamcity
+
+import (
+	"errors"
+	"fmt"
+
+	"bbox/pkg/types"
+	"bbox/pkg/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrArtifactsTooLarge is returned when the selected artifacts of a build exceed ArtifactOptions.MaxSize.
+	ErrArtifactsTooLarge = errors.New("artifacts are too large")
+	// ErrInsufficientDiskSpace is returned when the artifacts of a build do not fit the free space of the download directory.
+	ErrInsufficientDiskSpace = errors.New("not enough disk space")
+)
+
+// artifactsSize returns the total size of artifact files.
+func artifactsSize(files []types.ArtifactFile) int64 {
+	var size int64
+	for _, file := range files {
+		size += file.Size
+	}
+
+	return size
+}
+
+// checkArtifactsSize fails with ErrArtifactsTooLarge if the total size of the artifacts selected from a build exceeds maxSize, unless it is 0 or less.
+func checkArtifactsSize(buildID int, files []types.ArtifactFile, maxSize int64) error {
+	size := artifactsSize(files)
+	if maxSize > 0 && size > maxSize {
+		return fmt.Errorf("%w: the %d artifacts of build %d are %s, the limit is %s", ErrArtifactsTooLarge, len(files), buildID, utils.FormatBytes(size), utils.FormatBytes(maxSize))
+	}
+
+	return nil
+}
+
+// checkDiskSpace fails with ErrInsufficientDiskSpace if needed bytes do not fit the free space of the file system of destPath.
+// The check is skipped with a warning if the free space cannot be determined.
+func checkDiskSpace(buildID int, destPath string, needed int64) error {
+	if needed <= 0 {
+		return nil
+	}
+
+	free, err := utils.FreeDiskSpace(destPath)
+	if err != nil {
+		log.Warnf("skipping the disk space check of the artifacts of build %d: %s", buildID, err)
+		return nil
+	}
+
+	if uint64(needed) > free {
+		return fmt.Errorf("%w: downloading the artifacts of build %d to %s needs %s, but only %s is free", ErrInsufficientDiskSpace, buildID, destPath, utils.FormatBytes(needed), utils.FormatBytes(int64(free)))
+	}
+
+	log.Debugf("downloading %s of artifacts of build %d, %s is free", utils.FormatBytes(needed), buildID, utils.FormatBytes(int64(free)))
+
+	return nil
+}
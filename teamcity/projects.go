@@ -59,7 +59,7 @@ func (project *ProjectService) GetAllProjects() ([]string, error) {
 // GetProjectTemplates retrieves all template IDs associated with a given project ID.
 func (project *ProjectService) GetProjectTemplates(projectID string) ([]string, error) {
 
-	templatesURL := fmt.Sprintf("app/rest/projects/id:%s/templates", projectID)
+	templatesURL := "app/rest/projects/" + IDLocator(projectID).PathSegment() + "/templates"
 	req, err := project.client.NewRequestWrapper("GET", templatesURL, nil)
 	if err != nil {
 		return []string{}, fmt.Errorf("error creating request: %w", err)
@@ -1,6 +1,7 @@
 package teamcity
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -36,3 +37,52 @@ func (qs *QueueService) ClearQueue() error {
 
 	return nil
 }
+
+// queuePositionLimit is the number of queued builds GetQueuePosition looks at, so polling a long queue stays cheap.
+const queuePositionLimit = 100
+
+// GetQueuePosition returns the 1-based position of a build in the build queue,
+// or 0 if it is not queued or not among the first queuePositionLimit queued builds.
+func (qs *QueueService) GetQueuePosition(buildID int) (int, error) {
+	getURL := withQuery("app/rest/buildQueue", NewLocator().AddInt("count", queuePositionLimit), "build(id)")
+
+	req, err := qs.client.NewRequestWrapper("GET", getURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error creating request: %w", err)
+	}
+
+	response, err := qs.client.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("error executing request to get the queue: %w", err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			log.Errorf("error closing response body: %s", err)
+		}
+	}(response.Body)
+
+	if response.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("failed to get the queue, status code: %d", response.StatusCode)
+	}
+
+	var queueResponse struct {
+		Build []struct {
+			ID int `json:"id"`
+		} `json:"build"`
+	}
+
+	err = json.NewDecoder(response.Body).Decode(&queueResponse)
+	if err != nil {
+		return 0, fmt.Errorf("error decoding response body: %w", err)
+	}
+
+	// the queue is returned in order, first to start first
+	for i, build := range queueResponse.Build {
+		if build.ID == buildID {
+			return i + 1, nil
+		}
+	}
+
+	return 0, nil
+}
@@ -0,0 +1,39 @@
+package teamcity
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetQueuePosition(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/app/rest/buildQueue", r.URL.Path)
+		assert.Equal(t, fmt.Sprintf("count:%d", queuePositionLimit), r.URL.Query().Get("locator"))
+		assert.Equal(t, "build(id)", r.URL.Query().Get("fields"))
+
+		_, _ = w.Write([]byte(`{"build":[{"id":7},{"id":42},{"id":9}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	baseURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	client, err := NewTeamCityClient(baseURL, "user", "password")
+	require.NoError(t, err)
+
+	position, err := client.Queue.GetQueuePosition(42)
+	require.NoError(t, err)
+	assert.Equal(t, 2, position)
+
+	position, err = client.Queue.GetQueuePosition(1)
+	require.NoError(t, err)
+	assert.Equal(t, 0, position)
+}
@@ -21,6 +21,8 @@ var (
 	_ IVcsRootsService  = &VcsRootsService{}
 	_ IProjectService   = &ProjectService{}
 	_ ITemplateService  = &TemplateService{}
+	_ IBuildTypeService = &BuildTypeService{}
+	_ IChangeService    = &ChangeService{}
 )
 
 type Client struct {
@@ -36,12 +38,19 @@ type Client struct {
 	VcsRoots  IVcsRootsService
 	Project   IProjectService
 	Template  ITemplateService
+	BuildType IBuildTypeService
+	Change    IChangeService
 }
 
 type IBuildService interface {
 	GetBuildStatus(buildID int) (types.BuildStatusResponse, error)
 	TriggerBuild(buildTypeID, branchName string, params map[string]string) (types.TriggerBuildWithParametersResponse, error)
-	WaitForBuild(buildName string, buildNumber int, timeout time.Duration) (types.BuildStatusResponse, error)
+	WaitForBuild(buildName string, buildNumber int, timeout time.Duration, opts ...WaitOption) (types.BuildStatusResponse, error)
+	GetResultingProperties(buildID int, filters ...string) (map[string]string, error)
+	ListBuilds(locator types.BuildLocator) ([]types.Build, error)
+	GetConstituentBuilds(buildID int) ([]types.BuildStatusResponse, error)
+	GetBuildDependencies(buildID int) (types.BuildStatusResponse, error)
+	CancelBuild(buildID int, comment string) (bool, error)
 }
 
 type IArtifactsService interface {
@@ -49,11 +58,16 @@ type IArtifactsService interface {
 	GetArtifactChildren(buildID int) (types.ArtifactChildren, error)
 	GetArtifactContentByPath(path string) ([]byte, error)
 	GetAllBuildTypeArtifacts(buildID int, buildTypeID string) ([]byte, error)
-	DownloadAndUnzipArtifacts(buildID int, buildTypeID, destPath string) error
+	DownloadArtifactContentByPath(path, destFile string) (int64, error)
+	DownloadAllBuildTypeArtifacts(buildID int, buildTypeID, destFile string) (int64, error)
+	DownloadAndUnzipArtifacts(buildID int, buildTypeID, destPath string) ([]string, error)
+	ListArtifacts(buildID int) ([]types.ArtifactFile, error)
+	DownloadArtifact(file types.ArtifactFile, destFile string, retries int) error
 }
 
 type IQueueService interface {
 	ClearQueue() error
+	GetQueuePosition(buildID int) (int, error)
 }
 
 type IVcsRootsService interface {
@@ -74,6 +88,20 @@ type ITemplateService interface {
 	GetVcsRootsIDsFromTemplates(templateIDs []string) ([]string, error)
 }
 
+type IChangeService interface {
+	GetChanges(locator *Locator) ([]types.Change, error)
+}
+
+type IBuildTypeService interface {
+	GetBuildTypes(locator *Locator) ([]types.BuildType, error)
+	GetBranches(buildTypeID string) ([]types.Branch, error)
+	GetParameters(buildTypeID string) ([]types.Parameter, error)
+	ValidateProperties(buildTypeID string, properties map[string]string, strict bool) error
+	FindBuildTypes(query string) ([]types.BuildType, error)
+	ResolveBuildTypeID(query string) (string, error)
+	GetBuildTypeDependencies(buildTypeID string) (types.BuildType, error)
+}
+
 type BasicAuth struct {
 	username string
 	password string
@@ -110,6 +138,8 @@ func (c *Client) initializeServices() {
 	c.VcsRoots = &VcsRootsService{client: c}
 	c.Project = &ProjectService{client: c}
 	c.Template = &TemplateService{client: c}
+	c.BuildType = &BuildTypeService{client: c}
+	c.Change = &ChangeService{client: c}
 }
 
 // RequestOption represents an option that can modify an http.Request.
@@ -19,7 +19,7 @@ func (template *TemplateService) GetVcsRootsIDsFromTemplates(templateIDs []strin
 	vcsRootsIDs := []string{}
 
 	for _, templateID := range templateIDs {
-		vcsRootURL := fmt.Sprintf("app/rest/buildTypes/id:%s/vcs-root-entries?fields=vcs-root-entry", templateID)
+		vcsRootURL := withQuery("app/rest/buildTypes/"+IDLocator(templateID).PathSegment()+"/vcs-root-entries", nil, "vcs-root-entry")
 		req, err := template.client.NewRequestWrapper("GET", vcsRootURL, nil)
 		if err != nil {
 			return []string{}, fmt.Errorf("error creating request: %w", err)
@@ -133,7 +133,7 @@ func (vcs *VcsRootsService) DeleteUnusedVcsRoots(allUnusedVcsRoots []string) (in
 func (vcs *VcsRootsService) DoesVcsRootHaveInstance(vcsRootID string) (bool, error) {
 	var instancesResponse VcsRootInstanceResponse
 	// Get VCS Root instances
-	instancesURL := fmt.Sprintf("app/rest/vcs-root-instances?locator=vcsRoot:(id:%s)", vcsRootID)
+	instancesURL := withQuery("app/rest/vcs-root-instances", NewLocator().AddLocator("vcsRoot", IDLocator(vcsRootID)), "")
 	req, err := vcs.client.NewRequestWrapper("GET", instancesURL, nil)
 	if err != nil {
 		return false, fmt.Errorf("error creating request: %w", err)
@@ -159,7 +159,7 @@ func (vcs *VcsRootsService) DoesVcsRootHaveInstance(vcsRootID string) (bool, err
 
 // DeleteVcsRoot removes a VCS Root by its ID.
 func (vcs *VcsRootsService) DeleteVcsRoot(vcsRootID string) (bool, error) {
-	vcsRootURL := fmt.Sprintf("app/rest/vcs-roots/%s", vcsRootID)
+	vcsRootURL := "app/rest/vcs-roots/" + IDLocator(vcsRootID).PathSegment()
 	req, err := vcs.client.NewRequestWrapper("DELETE", vcsRootURL, nil)
 	if err != nil {
 		return false, fmt.Errorf("error creating request for %v: %v", vcsRootID, err)